@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/internal/auth"
+	"github.com/oguzhan/e-commerce/internal/cart"
+	"github.com/oguzhan/e-commerce/internal/clients"
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/internal/middleware"
+	"github.com/oguzhan/e-commerce/internal/notification"
+	"github.com/oguzhan/e-commerce/internal/order"
+	"github.com/oguzhan/e-commerce/internal/payment"
+	"github.com/oguzhan/e-commerce/internal/product"
+	"github.com/oguzhan/e-commerce/internal/review"
+	"github.com/oguzhan/e-commerce/internal/user"
+	"github.com/oguzhan/e-commerce/internal/webhook"
+	"github.com/oguzhan/e-commerce/internal/wishlist"
+	"github.com/oguzhan/e-commerce/pkg/config"
+	"github.com/oguzhan/e-commerce/pkg/database"
+	"github.com/oguzhan/e-commerce/pkg/health"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	sharedmiddleware "github.com/oguzhan/e-commerce/pkg/middleware"
+	"github.com/oguzhan/e-commerce/pkg/server"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+	"github.com/redis/go-redis/v9"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// app is the API as served by main and by the integration tests.
+type app struct {
+	router      *gin.Engine
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// newApp connects to the database, applies migrations, wires the services
+// and their routes, and registers the background workers with runner.
+func newApp(cfg *config.Config, logger *zap.Logger, tracer *tracing.Tracer, runner *server.Runner) (*app, error) {
+	// Initialize database
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to instrument database: %w", err)
+	}
+	if err := db.Use(middleware.DBMetrics{
+		Logger:        logger,
+		SlowThreshold: cfg.DBSlowQueryThreshold,
+		LogValues:     cfg.DBLogQueryValues,
+		DBName:        cfg.DBName,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to instrument database: %w", err)
+	}
+	if replicas := database.ReplicasOf(db); replicas != nil {
+		runner.Go("db_replicas", func(ctx context.Context) {
+			replicas.RunHealthChecks(ctx, cfg.DBReplicaCheckInterval)
+		})
+	}
+
+	// Run migrations
+	migrator, err := database.Migrate(context.Background(), cfg, db, migrationModels()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	redisClient := newRedisClient(cfg)
+
+	// Initialize services
+	tokens := auth.NewTokens(cfg.JWTSecret, cfg.JWTExpiration)
+	authService := auth.NewService(db)
+	userService := user.NewService(db)
+	productService := product.NewServiceWithCache(db, newProductCache(cfg, redisClient))
+	orderService := order.NewServiceWithCatalog(db, newCatalog(cfg, productService))
+	paymentService := payment.NewServiceWithOrders(db, orderService)
+	reviewService := review.NewServiceWithProductCache(db, productService)
+	webhookService := webhook.NewServiceWithOptions(db, webhook.Options{
+		Timeout:      cfg.WebhookTimeout,
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		DisableAfter: cfg.WebhookDisableAfter,
+	})
+	cartService := cart.NewServiceWithOptions(db, cart.Options{
+		TokenSecret:  cfg.CartTokenSecret,
+		GuestTTL:     cfg.CartGuestTTL,
+		MergePolicy:  cart.MergePolicy(cfg.CartMergePolicy),
+		SecureCookie: cfg.CartSecureCookie,
+	})
+	runner.Go("guest_cart_janitor", func(ctx context.Context) {
+		cartService.RunGuestCartJanitor(ctx, time.Hour, logger)
+	})
+	wishlistService := wishlist.NewService(db, cartService)
+	notificationService, err := newNotificationService(db, cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize notifications: %w", err)
+	}
+
+	// Deliver domain events from the outbox
+	dispatcher := events.NewDispatcher(db, events.DispatcherOptions{
+		MaxAttempts: cfg.EventsMaxAttempts,
+	}, logger)
+	subscribeEvents(dispatcher, orderService, webhookService, notificationService, logger)
+	runner.Go("events", func(ctx context.Context) {
+		dispatcher.Run(ctx, cfg.EventsPollInterval)
+	})
+	webhookWorker := webhook.NewWorker(webhookService, logger)
+	runner.Go("webhooks", func(ctx context.Context) {
+		webhookWorker.Run(ctx, cfg.WebhookPollInterval)
+	})
+
+	// Start background workers
+	if cfg.CartRemindersEnabled {
+		reminderWorker := cart.NewAbandonedCartWorker(db, notificationService, cart.AbandonedCartOptions{
+			Stages: cfg.CartReminderStages,
+		}, logger)
+		runner.Go("cart_reminders", func(ctx context.Context) {
+			reminderWorker.Run(ctx, cfg.CartReminderInterval)
+		})
+	}
+
+	// Initialize handlers
+	authHandler := auth.NewHandler(authService, tokens)
+	userHandler := user.NewHandler(userService)
+	productHandler := product.NewHandler(productService)
+	orderHandler := order.NewHandler(orderService)
+	paymentHandler := payment.NewHandler(paymentService)
+	reviewHandler := review.NewHandler(reviewService)
+	webhookHandler := webhook.NewHandler(webhookService)
+	cartHandler := cart.NewHandler(cartService)
+	checkoutHandler := cart.NewCheckoutHandler(cartService, orderService, paymentService)
+	wishlistHandler := wishlist.NewHandler(wishlistService)
+	notificationHandler := notification.NewHandler(notificationService)
+
+	// Merge anonymous carts into the user's cart on login and registration
+	authHandler.OnLogin(cartHandler.MergeGuestCart)
+
+	// Initialize router
+	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	// Apply middlewares
+	rateLimitConfig, err := newRateLimitConfig(cfg, redisClient, tokens, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize rate limiting: %w", err)
+	}
+	router.Use(middleware.Tracing(tracer))
+	router.Use(middleware.StickToPrimary())
+	router.Use(clients.Propagate())
+	router.Use(sharedmiddleware.ErrorHandler())
+	router.Use(middleware.RateLimit(rateLimitConfig))
+	router.Use(middleware.Logger(logger))
+	router.Use(metrics.HTTPMetricsMiddleware())
+	router.Use(sharedmiddleware.CORS(corsConfig(cfg)))
+	router.Use(sharedmiddleware.SecurityHeaders(securityHeaders(cfg)))
+
+	// Swagger documentation; the UI needs its own scripts and styles
+	swaggerHeaders := securityHeaders(cfg)
+	swaggerHeaders.ContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:"
+	swaggerHeaders.FrameOptions = "SAMEORIGIN"
+	router.GET("/swagger/*any", sharedmiddleware.SecurityHeaders(swaggerHeaders), ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// Metrics and health endpoints
+	serveMetrics(runner, router, cfg)
+	checks := newHealth(cfg, db, migrator, redisClient)
+	sharedmiddleware.RegisterHealth(router, checks)
+	runner.OnDrain(checks.Drain)
+
+	// API routes
+	api := router.Group("/api/v1")
+	if cfg.APIMode == "gateway" {
+		gw, err := newGateway(cfg, tokens, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize gateway: %w", err)
+		}
+		runner.Go("gateway_health", func(ctx context.Context) {
+			gw.RunHealthChecks(ctx, cfg.GatewayHealthInterval)
+		})
+		checks.Add("gateway", health.CheckerFunc(gw.Check))
+		router.NoRoute(gw.Handler())
+	} else {
+		registerServiceRoutes(api, authHandler, userHandler, productHandler, orderHandler, paymentHandler)
+	}
+	{
+		// Auth routes
+		api.POST("/auth/register", authHandler.Register)
+		api.POST("/auth/login", authHandler.Login)
+		api.GET("/me", authHandler.AuthMiddleware(), authHandler.GetUserFromToken)
+
+		// Notification inbox and preference routes
+		meGroup := api.Group("/me")
+		meGroup.Use(authHandler.AuthMiddleware())
+		{
+			meGroup.GET("/notifications", notificationHandler.ListNotifications)
+			meGroup.POST("/notifications/read-all", notificationHandler.MarkAllRead)
+			meGroup.POST("/notifications/:id/read", notificationHandler.MarkRead)
+			meGroup.POST("/notifications/:id/unread", notificationHandler.MarkUnread)
+			meGroup.GET("/notification-preferences", notificationHandler.GetPreferences)
+			meGroup.PUT("/notification-preferences", notificationHandler.UpdatePreferences)
+		}
+
+		// Product reviews are served here in both modes
+		api.GET("/products/:id/reviews", reviewHandler.ListProductReviews)
+		api.POST("/products/:id/reviews", authHandler.AuthMiddleware(), reviewHandler.CreateReview)
+
+		// Review routes
+		reviewGroup := api.Group("/reviews")
+		reviewGroup.Use(authHandler.AuthMiddleware())
+		{
+			reviewGroup.PUT("/:id", reviewHandler.UpdateReview)
+			reviewGroup.DELETE("/:id", reviewHandler.DeleteReview)
+			reviewGroup.POST("/:id/helpful", reviewHandler.VoteHelpful)
+			reviewGroup.DELETE("/:id/helpful", reviewHandler.RemoveHelpfulVote)
+
+			// Moderation and merchant replies (admin only)
+			reviewGroup.GET("", authHandler.RequireAdmin(), reviewHandler.ListReviews)
+			reviewGroup.PUT("/:id/status", authHandler.RequireAdmin(), reviewHandler.ModerateReview)
+			reviewGroup.PUT("/:id/reply", authHandler.RequireAdmin(), reviewHandler.ReplyToReview)
+		}
+
+		// Cart routes
+		cartGroup := api.Group("/cart")
+		cartGroup.Use(authHandler.OptionalAuthMiddleware())
+		{
+			cartGroup.GET("", cartHandler.GetCart)
+			cartGroup.GET("/items", cartHandler.GetItems)
+			cartGroup.POST("/items", cartHandler.AddItem)
+			cartGroup.PUT("/items/:id", cartHandler.UpdateItem)
+			cartGroup.DELETE("/items/:id", cartHandler.RemoveItem)
+			cartGroup.DELETE("", cartHandler.ClearCart)
+			cartGroup.POST("/items/:id/save-for-later", authHandler.AuthMiddleware(), wishlistHandler.SaveForLater)
+		}
+
+		// Guest checkout orders the guest cart, so it is served here in both modes
+		api.POST("/orders/guest", checkoutHandler.CheckoutGuest)
+
+		// Webhook subscription routes (admin only)
+		webhookGroup := api.Group("/webhooks")
+		webhookGroup.Use(authHandler.AuthMiddleware(), authHandler.RequireAdmin())
+		{
+			webhookGroup.POST("", webhookHandler.CreateSubscription)
+			webhookGroup.GET("", webhookHandler.ListSubscriptions)
+			webhookGroup.GET("/:id", webhookHandler.GetSubscription)
+			webhookGroup.PUT("/:id", webhookHandler.UpdateSubscription)
+			webhookGroup.DELETE("/:id", webhookHandler.DeleteSubscription)
+			webhookGroup.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+			webhookGroup.GET("/:id/deliveries/:deliveryId", webhookHandler.GetDelivery)
+			webhookGroup.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+		}
+
+		// Wishlist routes
+		api.GET("/wishlists/shared/:token", wishlistHandler.GetSharedWishlist)
+
+		wishlistGroup := api.Group("/wishlists")
+		wishlistGroup.Use(authHandler.AuthMiddleware())
+		{
+			wishlistGroup.GET("", wishlistHandler.ListWishlists)
+			wishlistGroup.POST("", wishlistHandler.CreateWishlist)
+			wishlistGroup.GET("/:id", wishlistHandler.GetWishlist)
+			wishlistGroup.PUT("/:id", wishlistHandler.UpdateWishlist)
+			wishlistGroup.DELETE("/:id", wishlistHandler.DeleteWishlist)
+			wishlistGroup.POST("/:id/items", wishlistHandler.AddItem)
+			wishlistGroup.PUT("/:id/items/:itemId", wishlistHandler.UpdateItem)
+			wishlistGroup.DELETE("/:id/items/:itemId", wishlistHandler.RemoveItem)
+			wishlistGroup.POST("/:id/items/:itemId/move-to-cart", wishlistHandler.MoveToCart)
+		}
+	}
+
+	return &app{router: router, db: db, redisClient: redisClient}, nil
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/oguzhan/e-commerce/internal/cart"
+	"github.com/oguzhan/e-commerce/pkg/config"
+	"github.com/oguzhan/e-commerce/pkg/database"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/oguzhan/e-commerce/pkg/server"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// testAPI boots the whole API, workers included, on an in-memory SQLite
+// database.
+type testAPI struct {
+	t      *testing.T
+	router *gin.Engine
+	db     *gorm.DB
+}
+
+// newTestAPI starts the API; configure may adjust the test configuration.
+func newTestAPI(t *testing.T, configure ...func(*config.Config)) *testAPI {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.DBDriver = database.SQLite
+	cfg.DBPath = ":memory:"
+	cfg.JWTSecret = "integration-secret"
+	cfg.MetricsPort = 0
+	cfg.EventsPollInterval = 10 * time.Millisecond
+	cfg.CartRemindersEnabled = false
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	runner := server.New(server.Options{ShutdownTimeout: 5 * time.Second}, nil)
+	a, err := newApp(cfg, zap.NewNop(), tracing.NewTracer("test", nil), runner)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	runner.OnClose("database", func(context.Context) error { return database.Close(a.db) })
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.NoError(t, runner.Run(ctx))
+	})
+	return &testAPI{t: t, router: a.router, db: a.db}
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given.
+func (api *testAPI) do(method, path, token string, body, out interface{}) int {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return api.send(method, path, header, body, out).Code
+}
+
+// doAsGuest is do for a visitor holding the guest cart token cartToken. It
+// also returns the cart token sent back.
+func (api *testAPI) doAsGuest(method, path, cartToken string, body, out interface{}) (int, string) {
+	header := http.Header{}
+	if cartToken != "" {
+		header.Set(cart.TokenHeader, cartToken)
+	}
+	w := api.send(method, path, header, body, out)
+	return w.Code, w.Header().Get(cart.TokenHeader)
+}
+
+func (api *testAPI) send(method, path string, header http.Header, body, out interface{}) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		assert.NoError(api.t, json.NewEncoder(&reader).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, req)
+	if out != nil && w.Body.Len() > 0 {
+		assert.NoError(api.t, json.Unmarshal(w.Body.Bytes(), out), w.Body.String())
+	}
+	return w
+}
+
+// signUp registers a user and returns a token for them.
+func (api *testAPI) signUp(email string) string {
+	credentials := map[string]string{"email": email, "password": "secret123"}
+	assert.Equal(api.t, http.StatusCreated, api.do(http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"email": email, "password": "secret123", "first_name": "Test", "last_name": "User",
+	}, nil))
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	assert.Equal(api.t, http.StatusOK, api.do(http.MethodPost, "/api/v1/auth/login", "", credentials, &login))
+	assert.NotEmpty(api.t, login.Token)
+	return login.Token
+}
+
+func (api *testAPI) createProduct(token, name, sku string, price float64, stock int) uint {
+	var product struct {
+		ID uint `json:"id"`
+	}
+	assert.Equal(api.t, http.StatusCreated, api.do(http.MethodPost, "/api/v1/products", token, map[string]interface{}{
+		"name": name, "sku": sku, "price": price, "stock": stock, "category": "tools",
+	}, &product))
+	return product.ID
+}
+
+var testAddress = map[string]string{
+	"address_line": "1 Main St", "city": "Istanbul", "country": "TR", "postal_code": "34000",
+}
+
+func TestCheckoutFlow(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.signUp("buyer@example.com")
+	productID := api.createProduct(token, "Hammer", "HAM-1", 25, 10)
+
+	// Fill the cart
+	assert.Equal(t, http.StatusCreated, api.do(http.MethodPost, "/api/v1/cart/items", token,
+		map[string]interface{}{"product_id": productID, "quantity": 2}, nil))
+	var cart struct {
+		ItemCount int     `json:"item_count"`
+		Subtotal  float64 `json:"subtotal"`
+		Items     []struct {
+			ProductID uint `json:"product_id"`
+			Quantity  int  `json:"quantity"`
+		} `json:"items"`
+	}
+	assert.Equal(t, http.StatusOK, api.do(http.MethodGet, "/api/v1/cart", token, nil, &cart))
+	if !assert.Len(t, cart.Items, 1) {
+		return
+	}
+	assert.Equal(t, 2, cart.ItemCount)
+	assert.Equal(t, 50.0, cart.Subtotal)
+
+	// Order what is in the cart
+	var order struct {
+		ID          uint    `json:"id"`
+		Status      string  `json:"status"`
+		TotalAmount float64 `json:"total_amount"`
+	}
+	assert.Equal(t, http.StatusCreated, api.do(http.MethodPost, "/api/v1/orders", token, map[string]interface{}{
+		"payment_method":   "card",
+		"total_amount":     cart.Subtotal,
+		"shipping_address": testAddress,
+		"billing_address":  testAddress,
+		"order_items": []map[string]interface{}{
+			{"product_id": cart.Items[0].ProductID, "quantity": cart.Items[0].Quantity, "price": 25},
+		},
+	}, &order))
+	assert.Equal(t, "pending", order.Status)
+
+	// Pay for it
+	var payment struct {
+		ID     uint   `json:"id"`
+		Status string `json:"status"`
+	}
+	assert.Equal(t, http.StatusCreated, api.do(http.MethodPost, "/api/v1/payments", token, map[string]interface{}{
+		"order_id": order.ID, "amount": order.TotalAmount, "payment_method": "card",
+	}, &payment))
+	assert.Equal(t, http.StatusOK, api.do(http.MethodPost, fmt.Sprintf("/api/v1/payments/%d/process", payment.ID), token, nil, nil))
+	assert.Equal(t, http.StatusOK, api.do(http.MethodGet, fmt.Sprintf("/api/v1/payments/%d", payment.ID), token, nil, &payment))
+	assert.Equal(t, "completed", payment.Status)
+
+	// The outbox dispatcher moves the paid order into fulfilment
+	assert.Eventually(t, func() bool {
+		api.do(http.MethodGet, fmt.Sprintf("/api/v1/orders/%d", order.ID), token, nil, &order)
+		return order.Status == "processing"
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestOrdersAndPaymentsBelongToTheirUser(t *testing.T) {
+	api := newTestAPI(t)
+	owner := api.signUp("owner@example.com")
+	other := api.signUp("other@example.com")
+	productID := api.createProduct(owner, "Saw", "SAW-1", 40, 5)
+
+	var order struct {
+		ID uint `json:"id"`
+	}
+	assert.Equal(t, http.StatusCreated, api.do(http.MethodPost, "/api/v1/orders", owner, map[string]interface{}{
+		"payment_method":   "card",
+		"total_amount":     40,
+		"shipping_address": testAddress,
+		"billing_address":  testAddress,
+		"order_items":      []map[string]interface{}{{"product_id": productID, "quantity": 1, "price": 40}},
+	}, &order))
+
+	var payment struct {
+		ID uint `json:"id"`
+	}
+	assert.Equal(t, http.StatusCreated, api.do(http.MethodPost, "/api/v1/payments", owner, map[string]interface{}{
+		"order_id": order.ID, "amount": 40, "payment_method": "card",
+	}, &payment))
+
+	var problem struct {
+		Code string `json:"code"`
+	}
+	assert.Equal(t, http.StatusForbidden, api.do(http.MethodPost, fmt.Sprintf("/api/v1/payments/%d/process", payment.ID), other, nil, &problem))
+	assert.Equal(t, "payment_forbidden", problem.Code)
+
+	// Paying for someone else's order, or for a different amount, is refused
+	assert.Equal(t, http.StatusNotFound, api.do(http.MethodPost, "/api/v1/payments", other, map[string]interface{}{
+		"order_id": order.ID, "amount": 40, "payment_method": "card",
+	}, &problem))
+	assert.Equal(t, "order_not_found", problem.Code)
+	assert.Equal(t, http.StatusNotFound, api.do(http.MethodPost, "/api/v1/payments", other, map[string]interface{}{
+		"order_id": order.ID + 100, "amount": 40, "payment_method": "card",
+	}, &problem))
+	assert.Equal(t, http.StatusBadRequest, api.do(http.MethodPost, "/api/v1/payments", owner, map[string]interface{}{
+		"order_id": order.ID, "amount": 1, "payment_method": "card",
+	}, &problem))
+	assert.Equal(t, "amount_mismatch", problem.Code)
+	assert.Equal(t, http.StatusUnauthorized, api.do(http.MethodGet, "/api/v1/orders", "", nil, nil))
+}
+
+func TestGuestCheckoutOrdersTheGuestCart(t *testing.T) {
+	api := newTestAPI(t)
+	merchant := api.signUp("merchant@example.com")
+	productID := api.createProduct(merchant, "Drill", "DRL-1", 80, 3)
+
+	code, cartToken := api.doAsGuest(http.MethodPost, "/api/v1/cart/items", "", map[string]interface{}{"product_id": productID, "quantity": 2}, nil)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NotEmpty(t, cartToken)
+
+	// Items and prices sent by the client are ignored
+	checkout := map[string]interface{}{
+		"email":            "guest@example.com",
+		"payment_method":   "card",
+		"shipping_address": testAddress,
+		"billing_address":  testAddress,
+		"total_amount":     1,
+		"order_items":      []map[string]interface{}{{"product_id": productID, "quantity": 3, "price": 0.5}},
+	}
+	code, _ = api.doAsGuest(http.MethodPost, "/api/v1/orders/guest", "", checkout, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = api.doAsGuest(http.MethodPost, "/api/v1/orders/guest", "forged.token", checkout, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	var order struct {
+		ID          uint    `json:"id"`
+		UserID      uint    `json:"user_id"`
+		GuestEmail  string  `json:"guest_email"`
+		TotalAmount float64 `json:"total_amount"`
+		OrderItems  []struct {
+			Quantity int     `json:"quantity"`
+			Price    float64 `json:"price"`
+		} `json:"order_items"`
+	}
+	code, _ = api.doAsGuest(http.MethodPost, "/api/v1/orders/guest", cartToken, checkout, &order)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "guest@example.com", order.GuestEmail)
+	assert.Zero(t, order.UserID)
+	assert.Equal(t, 160.0, order.TotalAmount)
+	if assert.Len(t, order.OrderItems, 1) {
+		assert.Equal(t, 2, order.OrderItems[0].Quantity)
+		assert.Equal(t, 80.0, order.OrderItems[0].Price)
+	}
+
+	// Stock is reserved and the cart emptied
+	var product struct {
+		Stock int `json:"stock"`
+	}
+	assert.Equal(t, http.StatusOK, api.do(http.MethodGet, fmt.Sprintf("/api/v1/products/%d", productID), "", nil, &product))
+	assert.Equal(t, 1, product.Stock)
+	var guestCart struct {
+		Items []interface{} `json:"items"`
+	}
+	code, _ = api.doAsGuest(http.MethodGet, "/api/v1/cart", cartToken, nil, &guestCart)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, guestCart.Items)
+
+	// The order is paid at checkout and moves into fulfilment
+	var payment models.Payment
+	assert.NoError(t, api.db.Where("order_id = ?", order.ID).First(&payment).Error)
+	assert.Equal(t, models.PaymentStatusCompleted, payment.Status)
+	assert.Equal(t, 160.0, payment.Amount)
+	assert.Eventually(t, func() bool {
+		var stored models.Order
+		api.db.First(&stored, order.ID)
+		return stored.Status == models.OrderStatusProcessing
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// An emptied cart has nothing to order, and stock sold elsewhere is not
+	// promised twice
+	var problem struct {
+		Code string `json:"code"`
+	}
+	code, _ = api.doAsGuest(http.MethodPost, "/api/v1/orders/guest", cartToken, checkout, &problem)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "no_items", problem.Code)
+	code, _ = api.doAsGuest(http.MethodPost, "/api/v1/cart/items", cartToken, map[string]interface{}{"product_id": productID, "quantity": 1}, nil)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NoError(t, api.db.Model(&models.Product{}).Where("id = ?", productID).Update("stock", 0).Error)
+	code, _ = api.doAsGuest(http.MethodPost, "/api/v1/orders/guest", cartToken, checkout, &problem)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "out_of_stock", problem.Code)
+}
+
+func TestCancellingThroughStatusEndpointsReleasesStock(t *testing.T) {
+	api := newTestAPI(t)
+	owner := api.signUp("owner@example.com")
+	admin := api.signUp("admin@example.com")
+	assert.NoError(t, api.db.Model(&models.User{}).Where("email = ?", "admin@example.com").Update("role", "admin").Error)
+	productID := api.createProduct(admin, "Plane", "PLN-1", 30, 4)
+
+	stock := func() int {
+		var product struct {
+			Stock int `json:"stock"`
+		}
+		assert.Equal(t, http.StatusOK, api.do(http.MethodGet, fmt.Sprintf("/api/v1/products/%d", productID), "", nil, &product))
+		return product.Stock
+	}
+	placeOrder := func() uint {
+		var order struct {
+			ID uint `json:"id"`
+		}
+		assert.Equal(t, http.StatusCreated, api.do(http.MethodPost, "/api/v1/orders", owner, map[string]interface{}{
+			"payment_method":   "card",
+			"shipping_address": testAddress,
+			"billing_address":  testAddress,
+			"order_items":      []map[string]interface{}{{"product_id": productID, "quantity": 3}},
+		}, &order))
+		return order.ID
+	}
+	cancelled := map[string]string{"status": "cancelled"}
+
+	orderID := placeOrder()
+	assert.Equal(t, 1, stock())
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, api.do(http.MethodPut, fmt.Sprintf("/api/v1/orders/%d/status", orderID), owner, cancelled, nil))
+	}
+	assert.Equal(t, 4, stock())
+
+	orderID = placeOrder()
+	assert.Equal(t, 1, stock())
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, api.do(http.MethodPut, fmt.Sprintf("/api/v1/orders/%d/fulfilment-status", orderID), admin, cancelled, nil))
+	}
+	assert.Equal(t, 4, stock())
+	assert.Equal(t, http.StatusConflict, api.do(http.MethodPut, fmt.Sprintf("/api/v1/orders/%d/fulfilment-status", orderID), admin,
+		map[string]string{"status": "processing"}, nil))
+}
+
+func TestUsersCannotGrantThemselvesAdmin(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.signUp("mallory@example.com")
+	var me struct {
+		ID   uint   `json:"id"`
+		Role string `json:"role"`
+	}
+	assert.Equal(t, http.StatusOK, api.do(http.MethodGet, "/api/v1/me", token, nil, &me))
+
+	var problem struct {
+		Code string `json:"code"`
+	}
+	assert.Equal(t, http.StatusForbidden, api.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/role", me.ID), token,
+		map[string]string{"role": "admin"}, &problem))
+	assert.Equal(t, "admin_required", problem.Code)
+	assert.Equal(t, http.StatusForbidden, api.do(http.MethodPost, fmt.Sprintf("/api/v1/users/%d/reset-password", me.ID), token, nil, nil))
+	api.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d", me.ID), token, map[string]string{"role": "admin"}, nil)
+
+	assert.Equal(t, http.StatusOK, api.do(http.MethodGet, "/api/v1/me", token, nil, &me))
+	assert.Equal(t, "user", me.Role)
+	assert.Equal(t, http.StatusForbidden, api.do(http.MethodGet, "/api/v1/reviews", token, nil, nil))
+}
+
+func TestTokensUseTheConfiguredSecret(t *testing.T) {
+	// Load the secret the way the binary does: from JWT_SECRET_FILE only
+	secretFile := filepath.Join(t.TempDir(), "jwt_secret")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("file-secret\n"), 0o600))
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("JWT_SECRET", "")
+	os.Unsetenv("JWT_SECRET")
+	t.Setenv("JWT_SECRET_FILE", secretFile)
+	t.Setenv("JWT_EXPIRATION", "90m")
+	args := os.Args
+	os.Args = []string{"api"}
+	loaded, err := config.LoadConfig()
+	os.Args = args
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	api := newTestAPI(t, func(cfg *config.Config) {
+		cfg.JWTSecret = loaded.JWTSecret
+		cfg.JWTExpiration = loaded.JWTExpiration
+	})
+	token := api.signUp("keys@example.com")
+
+	claims := &models.Claims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte("file-secret"), nil
+	})
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(90*time.Minute), claims.ExpiresAt.Time, time.Minute)
+
+	_, err = jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return []byte(""), nil })
+	assert.Error(t, err)
+}
+
+func TestSearchIgnoresCase(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.signUp("merchant@example.com")
+	api.createProduct(token, "Claw Hammer", "HAM-2", 30, 3)
+	api.createProduct(token, "Wrench", "WRE-1", 15, 3)
+
+	var results []struct {
+		Name string `json:"name"`
+	}
+	assert.Equal(t, http.StatusOK, api.do(http.MethodGet, "/api/v1/products/search?q=hammer", "", nil, &results))
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "Claw Hammer", results[0].Name)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	api := newTestAPI(t)
+	var report struct {
+		Status string `json:"status"`
+	}
+	assert.Equal(t, http.StatusOK, api.do(http.MethodGet, "/readyz", "", nil, &report))
+	assert.Equal(t, "up", report.Status)
+}
@@ -1,22 +1,38 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oguzhan/e-commerce/internal/auth"
 	"github.com/oguzhan/e-commerce/internal/cart"
+	"github.com/oguzhan/e-commerce/internal/clients"
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/internal/gateway"
 	"github.com/oguzhan/e-commerce/internal/middleware"
+	"github.com/oguzhan/e-commerce/internal/notification"
 	"github.com/oguzhan/e-commerce/internal/order"
 	"github.com/oguzhan/e-commerce/internal/payment"
 	"github.com/oguzhan/e-commerce/internal/product"
+	"github.com/oguzhan/e-commerce/internal/review"
 	"github.com/oguzhan/e-commerce/internal/user"
+	"github.com/oguzhan/e-commerce/internal/webhook"
+	"github.com/oguzhan/e-commerce/internal/wishlist"
+	"github.com/oguzhan/e-commerce/pkg/cache"
 	"github.com/oguzhan/e-commerce/pkg/config"
 	"github.com/oguzhan/e-commerce/pkg/database"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/oguzhan/e-commerce/pkg/health"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	sharedmiddleware "github.com/oguzhan/e-commerce/pkg/middleware"
+	"github.com/oguzhan/e-commerce/pkg/ratelimit"
+	"github.com/oguzhan/e-commerce/pkg/server"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // @title           E-Commerce API
@@ -44,7 +60,6 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
-	defer logger.Sync()
 
 	// Load configuration
 	cfg, err := config.LoadConfig()
@@ -52,140 +67,328 @@ func main() {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
 
-	// Initialize database
-	db, err := database.InitDB(cfg)
+	// The runner stops servers and workers on SIGTERM before the resources
+	// registered with OnClose are closed
+	runner := server.New(server.FromConfig(cfg), logger)
+
+	// Initialize tracing
+	exporter, err := tracing.NewExporter(cfg.TracingExporter, cfg.TracingFile)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	tracer := tracing.NewTracer(cfg.TracingServiceName, exporter)
+	tracing.SetDefault(tracer)
+
+	a, err := newApp(cfg, logger, tracer, runner)
 	if err != nil {
-		logger.Fatal("Failed to initialize database", zap.Error(err))
+		logger.Fatal("Failed to initialize API", zap.Error(err))
 	}
 
-	// Run migrations
-	if err := database.AutoMigrate(db); err != nil {
-		logger.Fatal("Failed to run migrations", zap.Error(err))
+	// Start server and shut down on SIGTERM, closing what the workers and
+	// handlers use only after they have stopped
+	runner.Serve("api", ":"+cfg.ServerPort, a.router)
+	runner.OnClose("database", func(context.Context) error { return database.Close(a.db) })
+	if a.redisClient != nil {
+		runner.OnClose("redis", func(context.Context) error { return a.redisClient.Close() })
 	}
+	runner.OnClose("tracing", tracer.Shutdown)
+	runner.OnClose("logger", func(context.Context) error {
+		logger.Sync()
+		return nil
+	})
+	if err := runner.Run(context.Background()); err != nil {
+		logger.Fatal("Server stopped with errors", zap.Error(err))
+	}
+}
 
-	// Initialize services
-	authService := auth.NewService(db)
-	userService := user.NewService(db)
-	productService := product.NewService(db)
-	orderService := order.NewService(db)
-	paymentService := payment.NewService(db)
-	cartService := cart.NewService(db)
+// newHealth checks the database, its schema version and, when it is used
+// for caching or rate limiting, Redis.
+func newHealth(cfg *config.Config, db *gorm.DB, migrations health.Checker, redisClient *redis.Client) *health.Health {
+	checks := health.New()
+	checks.Timeout = cfg.HealthTimeout
+	checks.CacheTTL = cfg.HealthCacheTTL
+	checks.Add("database", health.Database(db))
+	checks.Add("migrations", migrations)
+	if redisClient != nil {
+		checks.Add("redis", health.Redis(redisClient))
+	}
+	return checks
+}
 
-	// Initialize handlers
-	authHandler := auth.NewHandler(authService)
-	userHandler := user.NewHandler(userService)
-	productHandler := product.NewHandler(productService)
-	orderHandler := order.NewHandler(orderService)
-	paymentHandler := payment.NewHandler(paymentService)
-	cartHandler := cart.NewHandler(cartService)
+// serveMetrics exposes /metrics on its own port, or on the router when
+// metrics.port is 0.
+func serveMetrics(runner *server.Runner, router *gin.Engine, cfg *config.Config) {
+	if !cfg.EnableMetrics {
+		return
+	}
+	if cfg.MetricsPort == 0 {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+		return
+	}
+	runner.Serve("metrics", fmt.Sprintf(":%d", cfg.MetricsPort), metrics.NewServeMux())
+}
 
-	// Initialize router
-	router := gin.Default()
+// registerServiceRoutes serves the user, product, order and payment routes
+// from this process. In gateway mode they are proxied to their services
+// instead.
+func registerServiceRoutes(api *gin.RouterGroup, authHandler *auth.Handler, userHandler *user.Handler, productHandler *product.Handler, orderHandler *order.Handler, paymentHandler *payment.Handler) {
+	// User routes
+	userGroup := api.Group("/users")
+	userGroup.Use(authHandler.AuthMiddleware())
+	{
+		userGroup.GET("/:id", userHandler.GetUser)
+		userGroup.PUT("/:id", userHandler.UpdateUser)
+		userGroup.DELETE("/:id", userHandler.DeleteUser)
+		userGroup.GET("", userHandler.ListUsers)
+		userGroup.POST("/:id/change-password", userHandler.ChangePassword)
+		userGroup.PUT("/:id/preferences", userHandler.UpdatePreferences)
 
-	// Apply middlewares
-	rateLimitConfig := middleware.DefaultConfig()
-	router.Use(middleware.RateLimit(rateLimitConfig))
-	router.Use(middleware.Logger(logger))
-	router.Use(middleware.Metrics())
+		// Admin only routes
+		userGroup.POST("/:id/deactivate", authHandler.RequireAdmin(), userHandler.DeactivateUser)
+		userGroup.POST("/:id/activate", authHandler.RequireAdmin(), userHandler.ActivateUser)
+		userGroup.PUT("/:id/role", authHandler.RequireAdmin(), userHandler.UpdateUserRole)
+		userGroup.POST("/:id/reset-password", authHandler.RequireAdmin(), userHandler.ResetPassword)
 
-	// Swagger documentation
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+		// Address routes
+		userGroup.POST("/addresses", userHandler.CreateAddress)
+		userGroup.GET("/addresses", userHandler.GetAddresses)
+		userGroup.PUT("/addresses/:id", userHandler.UpdateAddress)
+		userGroup.DELETE("/addresses/:id", userHandler.DeleteAddress)
 
-	// Metrics endpoint
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+		// Contact routes
+		userGroup.POST("/contacts", userHandler.CreateContact)
+		userGroup.GET("/contacts", userHandler.GetContacts)
+		userGroup.PUT("/contacts/:id", userHandler.UpdateContact)
+		userGroup.DELETE("/contacts/:id", userHandler.DeleteContact)
+	}
 
-	// API routes
-	api := router.Group("/api/v1")
+	// Product routes
+	productGroup := api.Group("/products")
 	{
-		// Auth routes
-		api.POST("/auth/register", authHandler.Register)
-		api.POST("/auth/login", authHandler.Login)
-		api.GET("/me", authHandler.AuthMiddleware(), authHandler.GetUserFromToken)
-
-		// User routes
-		userGroup := api.Group("/users")
-		userGroup.Use(authHandler.AuthMiddleware())
-		{
-			userGroup.GET("/:id", userHandler.GetUser)
-			userGroup.PUT("/:id", userHandler.UpdateUser)
-			userGroup.DELETE("/:id", userHandler.DeleteUser)
-			userGroup.GET("", userHandler.ListUsers)
-			userGroup.POST("/:id/change-password", userHandler.ChangePassword)
-
-			// Admin only routes
-			userGroup.POST("/:id/deactivate", userHandler.DeactivateUser)
-			userGroup.POST("/:id/activate", userHandler.ActivateUser)
-			userGroup.PUT("/:id/role", userHandler.UpdateUserRole)
-			userGroup.POST("/:id/reset-password", userHandler.ResetPassword)
-
-			// Address routes
-			userGroup.POST("/addresses", userHandler.CreateAddress)
-			userGroup.GET("/addresses", userHandler.GetAddresses)
-			userGroup.PUT("/addresses/:id", userHandler.UpdateAddress)
-			userGroup.DELETE("/addresses/:id", userHandler.DeleteAddress)
-
-			// Contact routes
-			userGroup.POST("/contacts", userHandler.CreateContact)
-			userGroup.GET("/contacts", userHandler.GetContacts)
-			userGroup.PUT("/contacts/:id", userHandler.UpdateContact)
-			userGroup.DELETE("/contacts/:id", userHandler.DeleteContact)
-		}
+		productGroup.GET("", productHandler.ListProducts)
+		productGroup.GET("/:id", productHandler.GetProduct)
+		productGroup.GET("/search", productHandler.SearchProducts)
+	}
 
-		// Product routes
-		productGroup := api.Group("/products")
-		{
-			productGroup.GET("", productHandler.ListProducts)
-			productGroup.GET("/:id", productHandler.GetProduct)
-			productGroup.GET("/search", productHandler.SearchProducts)
-		}
+	// Protected product routes
+	protectedProductGroup := api.Group("/products")
+	protectedProductGroup.Use(authHandler.AuthMiddleware())
+	{
+		protectedProductGroup.POST("", productHandler.CreateProduct)
+		protectedProductGroup.PUT("/:id", productHandler.UpdateProduct)
+		protectedProductGroup.DELETE("/:id", productHandler.DeleteProduct)
+	}
 
-		// Protected product routes
-		protectedProductGroup := api.Group("/products")
-		protectedProductGroup.Use(authHandler.AuthMiddleware())
-		{
-			protectedProductGroup.POST("", productHandler.CreateProduct)
-			protectedProductGroup.PUT("/:id", productHandler.UpdateProduct)
-			protectedProductGroup.DELETE("/:id", productHandler.DeleteProduct)
-		}
+	// Order routes
+	orderGroup := api.Group("/orders")
+	orderGroup.Use(authHandler.AuthMiddleware())
+	{
+		orderGroup.POST("", orderHandler.CreateOrder)
+		orderGroup.GET("/:id", orderHandler.GetOrder)
+		orderGroup.GET("", orderHandler.ListOrders)
+		orderGroup.PUT("/:id", orderHandler.UpdateOrder)
+		orderGroup.PUT("/:id/status", orderHandler.UpdateOrderStatus)
+		orderGroup.PUT("/:id/fulfilment-status", authHandler.RequireAdmin(), orderHandler.SetOrderStatus)
+		orderGroup.DELETE("/:id", orderHandler.CancelOrder)
+	}
 
-		// Order routes
-		orderGroup := api.Group("/orders")
-		orderGroup.Use(authHandler.AuthMiddleware())
-		{
-			orderGroup.POST("", orderHandler.CreateOrder)
-			orderGroup.GET("/:id", orderHandler.GetOrder)
-			orderGroup.GET("", orderHandler.ListOrders)
-			orderGroup.PUT("/:id", orderHandler.UpdateOrder)
-			orderGroup.DELETE("/:id", orderHandler.CancelOrder)
-		}
+	// Payment routes
+	paymentGroup := api.Group("/payments")
+	paymentGroup.Use(authHandler.AuthMiddleware())
+	{
+		paymentGroup.POST("", paymentHandler.CreatePayment)
+		paymentGroup.GET("/:id", paymentHandler.GetPayment)
+		paymentGroup.GET("", paymentHandler.ListPayments)
+		paymentGroup.POST("/:id/process", paymentHandler.ProcessPayment)
+		paymentGroup.POST("/:id/refund", paymentHandler.RefundPayment)
+	}
+}
 
-		// Payment routes
-		paymentGroup := api.Group("/payments")
-		paymentGroup.Use(authHandler.AuthMiddleware())
-		{
-			paymentGroup.POST("", paymentHandler.CreatePayment)
-			paymentGroup.GET("/:id", paymentHandler.GetPayment)
-			paymentGroup.GET("", paymentHandler.ListPayments)
-			paymentGroup.POST("/:id/process", paymentHandler.ProcessPayment)
-			paymentGroup.POST("/:id/refund", paymentHandler.RefundPayment)
-		}
+// migrationModels lists the tables owned by feature packages, migrated
+// alongside the core models.
+func migrationModels() []interface{} {
+	var tables []interface{}
+	tables = append(tables, events.Models()...)
+	tables = append(tables, cart.Models()...)
+	tables = append(tables, wishlist.Models()...)
+	tables = append(tables, review.Models()...)
+	tables = append(tables, webhook.Models()...)
+	tables = append(tables, notification.Models()...)
+	return tables
+}
+
+// subscribeEvents registers the in-process reactions to domain events.
+func subscribeEvents(dispatcher *events.Dispatcher, orderService *order.Service, webhookService *webhook.Service, notificationService *notification.Service, logger *zap.Logger) {
+	events.On(dispatcher, func(ctx context.Context, e events.PaymentCaptured) error {
+		return orderService.StartFulfilment(e.OrderID)
+	})
+	events.On(dispatcher, func(ctx context.Context, e events.StockLow) error {
+		logger.Warn("product stock is low",
+			zap.Uint("product_id", e.ProductID), zap.String("sku", e.SKU), zap.Int("stock", e.Stock))
+		return nil
+	})
+
+	// Queue merchant webhooks for order and payment events
+	for _, name := range []string{
+		events.NameOrderPlaced,
+		events.NameOrderStatusChanged,
+		events.NamePaymentCaptured,
+		events.NamePaymentRefunded,
+	} {
+		dispatcher.Subscribe(name, webhookService.HandleEvent)
+	}
+
+	// Notify customers about their orders and refunds
+	for _, name := range []string{
+		events.NameOrderPlaced,
+		events.NameOrderStatusChanged,
+		events.NamePaymentRefunded,
+	} {
+		dispatcher.Subscribe(name, notificationService.HandleEvent)
+	}
+}
+
+// newNotificationService registers the in-app inbox and whichever of the
+// email and SMS channels are configured.
+func newNotificationService(db *gorm.DB, cfg *config.Config, logger *zap.Logger) (*notification.Service, error) {
+	renderer, err := notification.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	channels := []notification.Channel{notification.NewInAppChannel(db)}
+	if cfg.SMTPHost != "" {
+		channels = append(channels, notification.NewSMTPChannel(notification.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}))
+	}
+	if cfg.SMSProviderURL != "" {
+		channels = append(channels, notification.NewHTTPSMSChannel(notification.SMSConfig{
+			URL:    cfg.SMSProviderURL,
+			APIKey: cfg.SMSAPIKey,
+			From:   cfg.SMSFrom,
+		}))
+	}
+	return notification.NewService(db, renderer, logger, channels...), nil
+}
+
+// newProductCache keeps catalog reads in Redis when it is the configured
+// store, or in an in-process LRU otherwise.
+func newProductCache(cfg *config.Config, redisClient *redis.Client) *cache.ReadThrough {
+	var store cache.Cache = cache.NewLRUCache(cfg.CacheLRUSize)
+	if cfg.CacheStore == "redis" {
+		store = cache.NewRedisCacheWithClient(redisClient, cfg.CacheNamespace)
+	}
+	return cache.NewReadThrough("products", store, cfg.CacheTTL, cfg.CacheJitter)
+}
+
+// newRedisClient returns the client shared by the cache and the rate
+// limiter, or nil when neither is stored in Redis.
+func newRedisClient(cfg *config.Config) *redis.Client {
+	if cfg.CacheStore != "redis" && cfg.RateLimitStore != "redis" {
+		return nil
+	}
+	return redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.RedisHost, cfg.RedisPort),
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+}
+
+func newRateLimitConfig(cfg *config.Config, redisClient *redis.Client, tokens *auth.Tokens, logger *zap.Logger) (*middleware.RateLimitConfig, error) {
+	defaultPolicy, err := ratelimit.ParsePolicy("default", cfg.RateLimitDefault)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := middleware.ParseRoutePolicies(cfg.RateLimitRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "redis" {
+		store = ratelimit.NewRedisStore(redisClient, "")
+	}
+
+	return &middleware.RateLimitConfig{
+		Default:   defaultPolicy,
+		Routes:    routes,
+		Store:     store,
+		AllowList: cfg.RateLimitAllowList,
+		UserID: func(c *gin.Context) (uint, bool) {
+			header := c.GetHeader("Authorization")
+			if header == "" {
+				return 0, false
+			}
+			userID, err := tokens.Authenticate(header)
+			return userID, err == nil
+		},
+		Logger: logger,
+	}, nil
+}
+
+func corsConfig(cfg *config.Config) sharedmiddleware.CORSConfig {
+	return sharedmiddleware.CORSConfig{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}
+}
+
+func securityHeaders(cfg *config.Config) sharedmiddleware.SecurityHeadersConfig {
+	return sharedmiddleware.SecurityHeadersConfig{
+		HSTSMaxAge:            cfg.SecurityHSTSMaxAge,
+		HSTSIncludeSubdomains: cfg.SecurityHSTSIncludeSubdomains,
+		ContentSecurityPolicy: cfg.SecurityContentSecurityPolicy,
+		FrameOptions:          cfg.SecurityFrameOptions,
+		ReferrerPolicy:        cfg.SecurityReferrerPolicy,
+	}
+}
+
+// newCatalog returns what orders placed by this process are priced and
+// reserve stock with: the product service in gateway mode, the local
+// product service otherwise.
+func newCatalog(cfg *config.Config, products *product.Service) order.Catalog {
+	if cfg.APIMode == "gateway" {
+		return clients.NewProductClient(cfg.ProductServiceURL, clients.Options{
+			Timeout:    cfg.ServiceTimeout,
+			MaxRetries: cfg.ServiceMaxRetries,
+			APIKey:     cfg.ProductAPIKey,
+		})
+	}
+	return product.NewCatalog(products)
+}
 
-		// Cart routes
-		cartGroup := api.Group("/cart")
-		cartGroup.Use(authHandler.AuthMiddleware())
-		{
-			cartGroup.GET("", cartHandler.GetCart)
-			cartGroup.GET("/items", cartHandler.GetItems)
-			cartGroup.POST("/items", cartHandler.AddItem)
-			cartGroup.PUT("/items/:id", cartHandler.UpdateItem)
-			cartGroup.DELETE("/items/:id", cartHandler.RemoveItem)
-			cartGroup.DELETE("", cartHandler.ClearCart)
+// newGateway builds the proxy routes for gateway mode. Each service URL may
+// list several instances separated by commas.
+func newGateway(cfg *config.Config, tokens *auth.Tokens, logger *zap.Logger) (*gateway.Gateway, error) {
+	pools := make(map[string]*gateway.Pool)
+	for name, urls := range map[string]string{
+		"users":    cfg.UserServiceURL,
+		"products": cfg.ProductServiceURL,
+		"orders":   cfg.OrderServiceURL,
+		"payments": cfg.PaymentServiceURL,
+	} {
+		pool, err := gateway.NewPool(name, urls, "/readyz")
+		if err != nil {
+			return nil, err
 		}
+		pools[name] = pool
 	}
 
-	// Start server
-	logger.Info("Starting server", zap.String("port", cfg.ServerPort))
-	if err := router.Run(":" + cfg.ServerPort); err != nil {
-		logger.Fatal("Failed to start server", zap.Error(err))
+	timeouts := cfg.GatewayRouteTimeouts
+	routes := []gateway.Route{
+		{Prefix: "/api/v1/users", Target: "/users", Pool: pools["users"], Timeout: timeouts["users"]},
+		{Prefix: "/api/v1/products", Target: "/products", Pool: pools["products"], Timeout: timeouts["products"],
+			PublicMethods: []string{http.MethodGet}, Internal: []string{"/reserve", "/release"}},
+		{Prefix: "/api/v1/orders", Target: "/orders/", Pool: pools["orders"], Timeout: timeouts["orders"]},
+		{Prefix: "/api/v1/payments", Target: "/payments/", Pool: pools["payments"], Timeout: timeouts["payments"]},
 	}
+	return gateway.New(routes, tokens.Authenticate, logger), nil
 }
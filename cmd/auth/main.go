@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oguzhan/e-commerce/internal/auth"
 	"github.com/oguzhan/e-commerce/pkg/config"
 	"github.com/oguzhan/e-commerce/pkg/database"
+	"github.com/oguzhan/e-commerce/pkg/health"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+	"github.com/oguzhan/e-commerce/pkg/server"
 )
 
 func main() {
@@ -24,25 +27,40 @@ func main() {
 	}
 
 	// Run migrations
-	if err := database.AutoMigrate(db); err != nil {
+	migrator, err := database.Migrate(context.Background(), cfg, db)
+	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
 	// Initialize service
 	authService := auth.NewService(db)
-	authHandler := auth.NewHandler(authService)
+	authHandler := auth.NewHandler(authService, auth.NewTokens(cfg.JWTSecret, cfg.JWTExpiration))
 
 	// Initialize router
 	router := gin.Default()
 
+	// Health checks
+	checks := health.New()
+	checks.Timeout = cfg.HealthTimeout
+	checks.CacheTTL = cfg.HealthCacheTTL
+	checks.Add("database", health.Database(db))
+	checks.Add("migrations", migrator)
+	middleware.RegisterHealth(router, checks)
+
+	// The auth service has no workers; the runner only drains and closes
+	runner := server.New(server.FromConfig(cfg), nil)
+	runner.OnDrain(checks.Drain)
+
 	// Register routes
 	router.POST("/register", authHandler.Register)
 	router.POST("/login", authHandler.Login)
 	router.GET("/me", authHandler.AuthMiddleware(), authHandler.GetUserFromToken)
 
-	// Start server
+	// Start server and shut down gracefully on SIGTERM
 	log.Printf("Auth service starting on port %s", cfg.ServerPort)
-	if err := http.ListenAndServe(":"+cfg.ServerPort, router); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	runner.Serve("auth", ":"+cfg.ServerPort, router)
+	runner.OnClose("database", func(context.Context) error { return database.Close(db) })
+	if err := runner.Run(context.Background()); err != nil {
+		log.Fatalf("Server stopped with errors: %v", err)
 	}
 }
@@ -0,0 +1,122 @@
+// Command migrate applies and inspects the SQL migrations in migrations/.
+//
+//	go run ./cmd/migrate [config flags] up
+//	go run ./cmd/migrate [config flags] down [N]
+//	go run ./cmd/migrate [config flags] status
+//	go run ./cmd/migrate create <name>
+//
+// The database is configured like the services: config.yaml, environment
+// variables or flags such as --database.host, given before the command.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/config"
+	"github.com/oguzhan/e-commerce/pkg/database"
+	"github.com/oguzhan/e-commerce/pkg/migrate"
+)
+
+// dir is where create writes new migrations; run it from the repository root.
+const dir = "migrations"
+
+const usage = `usage: migrate [config flags] <command>
+
+commands:
+  up           apply every pending migration
+  down [N]     revert the N most recent migrations (default 1)
+  status       list migrations and whether they are applied
+  create NAME  write empty up and down files for a new migration`
+
+func main() {
+	cfg, args, err := config.LoadConfigArgs()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			log.Fatal("usage: migrate create NAME")
+		}
+		up, down, err := migrate.Create(dir, args[1])
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return
+	}
+
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close(db)
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		printMigrations("Applied", applied)
+		if err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				log.Fatalf("down: %q is not a positive number", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, n)
+		printMigrations("Reverted", reverted)
+		if err != nil {
+			log.Fatalf("Failed to revert migrations: %v", err)
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := ""
+			if !s.AppliedAt.IsZero() {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, strings.ToUpper(s.State), appliedAt)
+		}
+		w.Flush()
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func printMigrations(verb string, migrations []migrate.Migration) {
+	for _, m := range migrations {
+		fmt.Printf("%s %03d_%s\n", verb, m.Version, m.Name)
+	}
+}
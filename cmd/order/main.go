@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oguzhan/e-commerce/internal/auth"
+	"github.com/oguzhan/e-commerce/internal/clients"
+	internalmiddleware "github.com/oguzhan/e-commerce/internal/middleware"
 	"github.com/oguzhan/e-commerce/internal/order"
 	"github.com/oguzhan/e-commerce/pkg/config"
 	"github.com/oguzhan/e-commerce/pkg/database"
+	"github.com/oguzhan/e-commerce/pkg/health"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+	"github.com/oguzhan/e-commerce/pkg/server"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
 )
 
 func main() {
@@ -24,32 +33,96 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Initialize tracing
+	exporter, err := tracing.NewExporter(cfg.TracingExporter, cfg.TracingFile)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	tracer := tracing.NewTracer("order", exporter)
+	tracing.SetDefault(tracer)
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+	logger, err := internalmiddleware.NewLogger()
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	runner := server.New(server.FromConfig(cfg), logger)
+	if err := db.Use(internalmiddleware.DBMetrics{
+		Logger:        logger,
+		SlowThreshold: cfg.DBSlowQueryThreshold,
+		LogValues:     cfg.DBLogQueryValues,
+		DBName:        cfg.DBName,
+	}); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+	if replicas := database.ReplicasOf(db); replicas != nil {
+		runner.Go("db_replicas", func(ctx context.Context) {
+			replicas.RunHealthChecks(ctx, cfg.DBReplicaCheckInterval)
+		})
+	}
+
 	// Run migrations
-	if err := database.AutoMigrate(db); err != nil {
+	migrator, err := database.Migrate(context.Background(), cfg, db)
+	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
 	// Initialize services
-	orderService := order.NewService(db)
+	catalog := clients.NewProductClient(cfg.ProductServiceURL, clients.Options{
+		Timeout:    cfg.ServiceTimeout,
+		MaxRetries: cfg.ServiceMaxRetries,
+		APIKey:     cfg.ProductAPIKey,
+	})
+	orderService := order.NewServiceWithCatalog(db, catalog)
 	orderHandler := order.NewHandler(orderService)
 
 	// Initialize router
 	router := gin.Default()
+	router.Use(internalmiddleware.Tracing(tracer))
+	router.Use(internalmiddleware.StickToPrimary())
+	router.Use(clients.Propagate())
+	router.Use(metrics.HTTPMetricsMiddleware())
+
+	// Health checks; the product service is checked for liveness only
+	checks := health.New()
+	checks.Timeout = cfg.HealthTimeout
+	checks.CacheTTL = cfg.HealthCacheTTL
+	checks.Add("database", health.Database(db))
+	checks.Add("migrations", migrator)
+	checks.Add("product_service", health.HTTP(nil, strings.TrimSuffix(cfg.ProductServiceURL, "/")+"/healthz"))
+	middleware.RegisterHealth(router, checks)
+	runner.OnDrain(checks.Drain)
+
+	// Metrics are served on metrics.port, or on the router when it is 0
+	if cfg.EnableMetrics && cfg.MetricsPort == 0 {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	} else if cfg.EnableMetrics {
+		runner.Serve("metrics", fmt.Sprintf(":%d", cfg.MetricsPort), metrics.NewServeMux())
+	}
 
 	// Register routes
+	authHandler := auth.NewHandler(auth.NewService(db), auth.NewTokens(cfg.JWTSecret, cfg.JWTExpiration))
 	orderGroup := router.Group("/orders")
-	orderGroup.Use(auth.NewHandler(nil).AuthMiddleware())
+	orderGroup.Use(authHandler.AuthMiddleware())
 	{
 		orderGroup.POST("/", orderHandler.CreateOrder)
 		orderGroup.GET("/:id", orderHandler.GetOrder)
 		orderGroup.GET("/user/:userID", orderHandler.GetUserOrders)
 		orderGroup.PUT("/:id/status", orderHandler.UpdateOrderStatus)
+		orderGroup.PUT("/:id/fulfilment-status", authHandler.RequireAdmin(), orderHandler.SetOrderStatus)
 		orderGroup.DELETE("/:id", orderHandler.CancelOrder)
 	}
 
-	// Start server
-	log.Printf("Order service starting on port %s", cfg.ServerPort)
-	if err := http.ListenAndServe(":"+cfg.ServerPort, router); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Start server and shut down gracefully on SIGTERM
+	runner.Serve("order", ":"+cfg.ServerPort, router)
+	runner.OnClose("database", func(context.Context) error { return database.Close(db) })
+	runner.OnClose("tracing", tracer.Shutdown)
+	runner.OnClose("logger", func(context.Context) error {
+		logger.Sync()
+		return nil
+	})
+	if err := runner.Run(context.Background()); err != nil {
+		log.Fatalf("Server stopped with errors: %v", err)
 	}
 }
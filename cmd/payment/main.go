@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oguzhan/e-commerce/internal/auth"
+	"github.com/oguzhan/e-commerce/internal/clients"
+	internalmiddleware "github.com/oguzhan/e-commerce/internal/middleware"
 	"github.com/oguzhan/e-commerce/internal/payment"
 	"github.com/oguzhan/e-commerce/pkg/config"
 	"github.com/oguzhan/e-commerce/pkg/database"
+	"github.com/oguzhan/e-commerce/pkg/health"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+	"github.com/oguzhan/e-commerce/pkg/server"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
 )
 
 func main() {
@@ -24,21 +33,70 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Initialize tracing
+	exporter, err := tracing.NewExporter(cfg.TracingExporter, cfg.TracingFile)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	tracer := tracing.NewTracer("payment", exporter)
+	tracing.SetDefault(tracer)
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+	logger, err := internalmiddleware.NewLogger()
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	runner := server.New(server.FromConfig(cfg), logger)
+	if err := db.Use(internalmiddleware.DBMetrics{
+		Logger:        logger,
+		SlowThreshold: cfg.DBSlowQueryThreshold,
+		LogValues:     cfg.DBLogQueryValues,
+		DBName:        cfg.DBName,
+	}); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+
 	// Run migrations
-	if err := database.AutoMigrate(db); err != nil {
+	migrator, err := database.Migrate(context.Background(), cfg, db)
+	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
 	// Initialize services
-	paymentService := payment.NewService(db)
+	orders := clients.NewOrderClient(cfg.OrderServiceURL, clients.Options{
+		Timeout:    cfg.ServiceTimeout,
+		MaxRetries: cfg.ServiceMaxRetries,
+	})
+	paymentService := payment.NewServiceWithOrders(db, orders)
 	paymentHandler := payment.NewHandler(paymentService)
 
 	// Initialize router
 	router := gin.Default()
+	router.Use(internalmiddleware.Tracing(tracer))
+	router.Use(clients.Propagate())
+	router.Use(metrics.HTTPMetricsMiddleware())
+
+	// Health checks; the order service is checked for liveness only
+	checks := health.New()
+	checks.Timeout = cfg.HealthTimeout
+	checks.CacheTTL = cfg.HealthCacheTTL
+	checks.Add("database", health.Database(db))
+	checks.Add("migrations", migrator)
+	checks.Add("order_service", health.HTTP(nil, strings.TrimSuffix(cfg.OrderServiceURL, "/")+"/healthz"))
+	middleware.RegisterHealth(router, checks)
+	runner.OnDrain(checks.Drain)
+
+	// Metrics are served on metrics.port, or on the router when it is 0
+	if cfg.EnableMetrics && cfg.MetricsPort == 0 {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	} else if cfg.EnableMetrics {
+		runner.Serve("metrics", fmt.Sprintf(":%d", cfg.MetricsPort), metrics.NewServeMux())
+	}
 
 	// Register routes
 	paymentGroup := router.Group("/payments")
-	paymentGroup.Use(auth.NewHandler(nil).AuthMiddleware())
+	paymentGroup.Use(auth.NewHandler(nil, auth.NewTokens(cfg.JWTSecret, cfg.JWTExpiration)).AuthMiddleware())
 	{
 		paymentGroup.POST("/", paymentHandler.CreatePayment)
 		paymentGroup.POST("/:id/process", paymentHandler.ProcessPayment)
@@ -49,9 +107,15 @@ func main() {
 		paymentGroup.GET("/", paymentHandler.ListPayments)
 	}
 
-	// Start server
-	log.Printf("Payment service starting on port %s", cfg.ServerPort)
-	if err := http.ListenAndServe(":"+cfg.ServerPort, router); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Start server and shut down gracefully on SIGTERM
+	runner.Serve("payment", ":"+cfg.ServerPort, router)
+	runner.OnClose("database", func(context.Context) error { return database.Close(db) })
+	runner.OnClose("tracing", tracer.Shutdown)
+	runner.OnClose("logger", func(context.Context) error {
+		logger.Sync()
+		return nil
+	})
+	if err := runner.Run(context.Background()); err != nil {
+		log.Fatalf("Server stopped with errors: %v", err)
 	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
@@ -10,9 +11,17 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	_ "github.com/lib/pq"
+	internalmiddleware "github.com/oguzhan/e-commerce/internal/middleware"
 	"github.com/oguzhan/e-commerce/internal/product/handler"
 	"github.com/oguzhan/e-commerce/internal/product/repository"
 	"github.com/oguzhan/e-commerce/internal/product/service"
+	"github.com/oguzhan/e-commerce/migrations"
+	"github.com/oguzhan/e-commerce/pkg/health"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/oguzhan/e-commerce/pkg/migrate"
+	"github.com/oguzhan/e-commerce/pkg/server"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
@@ -26,34 +35,91 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
+
+	// Other services authenticate with PRODUCT_API_KEY
+	apiKey := os.Getenv("PRODUCT_API_KEY")
+	if apiKey == "" {
+		log.Println("PRODUCT_API_KEY is not set; stock reservations will be refused")
+	}
 
 	// Initialize dependencies
 	productRepo := repository.NewProductRepository(db)
 	productService := service.NewProductService(productRepo)
 	productHandler := handler.NewProductHandler(productService)
 
+	// Tracing
+	exporter, err := tracing.NewExporter(os.Getenv("TRACING_EXPORTER"), os.Getenv("TRACING_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	tracer := tracing.NewTracer("product", exporter)
+	tracing.SetDefault(tracer)
+
+	// Lifecycle
+	logger, err := internalmiddleware.NewLogger()
+	if err != nil {
+		log.Fatal(err)
+	}
+	runner := server.New(server.DefaultOptions(), logger)
+
 	// Router setup
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(tracing.Middleware(tracer))
+	r.Use(metrics.Middleware(func(r *http.Request) string {
+		return chi.RouteContext(r.Context()).RoutePattern()
+	}))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	// Browsers reach this service through cmd/api, which applies the
+	// configured CORS policy. Requests here carry bearer tokens rather than
+	// cookies, so any origin is allowed and credentials never are.
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		AllowCredentials: true,
-		MaxAge:           300,
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type"},
+		MaxAge:         300,
 	}))
 
+	// Health checks
+	checks := health.New()
+	checks.Add("database", health.SQL(db))
+	// Migrations are applied by the gorm services or cmd/migrate; this
+	// service only waits for the schema to be current.
+	migrator, err := migrate.New(db, migrate.Postgres, migrations.FS)
+	if err != nil {
+		log.Fatal(err)
+	}
+	checks.Add("migrations", migrator)
+	runner.OnDrain(checks.Drain)
+
+	// Metrics are served on METRICS_PORT when it is set
+	if err := metrics.Register(collectors.NewDBStatsCollector(db, "product")); err != nil {
+		log.Fatal(err)
+	}
+	if metricsPort := os.Getenv("METRICS_PORT"); metricsPort != "" {
+		runner.Serve("metrics", ":"+metricsPort, metrics.NewServeMux())
+	} else {
+		r.Handle("/metrics", metrics.Handler())
+	}
+
 	// Routes
+	r.Method(http.MethodGet, "/healthz", health.LivenessHandler())
+	r.Method(http.MethodGet, "/readyz", checks.ReadinessHandler())
+	r.Method(http.MethodGet, "/health", checks.ReadinessHandler())
 	r.Post("/products", productHandler.CreateProduct)
 	r.Get("/products", productHandler.GetAllProducts)
 	r.Get("/products/{id}", productHandler.GetProduct)
 	r.Put("/products/{id}", productHandler.UpdateProduct)
 	r.Delete("/products/{id}", productHandler.DeleteProduct)
 	r.Patch("/products/{id}/stock", productHandler.UpdateStock)
+	r.Group(func(r chi.Router) {
+		// Stock reservations are made by the order service only
+		r.Use(handler.RequireAPIKey(apiKey))
+		r.Post("/products/{id}/reserve", productHandler.ReserveStock)
+		r.Post("/products/{id}/release", productHandler.ReleaseStock)
+	})
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -61,8 +127,14 @@ func main() {
 		port = "8081"
 	}
 
-	log.Printf("Product service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	runner.Serve("product", ":"+port, r)
+	runner.OnClose("database", func(context.Context) error { return db.Close() })
+	runner.OnClose("tracing", tracer.Shutdown)
+	runner.OnClose("logger", func(context.Context) error {
+		logger.Sync()
+		return nil
+	})
+	if err := runner.Run(context.Background()); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
@@ -10,9 +11,17 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	_ "github.com/lib/pq"
+	internalmiddleware "github.com/oguzhan/e-commerce/internal/middleware"
 	"github.com/oguzhan/e-commerce/internal/user/handler"
 	"github.com/oguzhan/e-commerce/internal/user/repository"
 	"github.com/oguzhan/e-commerce/internal/user/service"
+	"github.com/oguzhan/e-commerce/migrations"
+	"github.com/oguzhan/e-commerce/pkg/health"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/oguzhan/e-commerce/pkg/migrate"
+	"github.com/oguzhan/e-commerce/pkg/server"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
@@ -26,7 +35,6 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 
 	// Initialize dependencies
 	userRepo := repository.NewUserRepository(db)
@@ -37,21 +45,66 @@ func main() {
 	userService := service.NewUserService(userRepo, jwtSecret)
 	userHandler := handler.NewUserHandler(userService)
 
+	// Tracing
+	exporter, err := tracing.NewExporter(os.Getenv("TRACING_EXPORTER"), os.Getenv("TRACING_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	tracer := tracing.NewTracer("user", exporter)
+	tracing.SetDefault(tracer)
+
+	// Lifecycle
+	logger, err := internalmiddleware.NewLogger()
+	if err != nil {
+		log.Fatal(err)
+	}
+	runner := server.New(server.DefaultOptions(), logger)
+
 	// Router setup
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(tracing.Middleware(tracer))
+	r.Use(metrics.Middleware(func(r *http.Request) string {
+		return chi.RouteContext(r.Context()).RoutePattern()
+	}))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	// The configured CORS policy lives in cmd/api; this service only sees
+	// bearer tokens, so it never allows credentials.
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		AllowCredentials: true,
-		MaxAge:           300,
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type"},
+		MaxAge:         300,
 	}))
 
+	// Health checks
+	checks := health.New()
+	checks.Add("database", health.SQL(db))
+	// Migrations are applied by the gorm services or cmd/migrate; this
+	// service only waits for the schema to be current.
+	migrator, err := migrate.New(db, migrate.Postgres, migrations.FS)
+	if err != nil {
+		log.Fatal(err)
+	}
+	checks.Add("migrations", migrator)
+	runner.OnDrain(checks.Drain)
+
+	// Metrics are served on METRICS_PORT when it is set
+	if err := metrics.Register(collectors.NewDBStatsCollector(db, "user")); err != nil {
+		log.Fatal(err)
+	}
+	if metricsPort := os.Getenv("METRICS_PORT"); metricsPort != "" {
+		runner.Serve("metrics", ":"+metricsPort, metrics.NewServeMux())
+	} else {
+		r.Handle("/metrics", metrics.Handler())
+	}
+
 	// Routes
+	r.Method(http.MethodGet, "/healthz", health.LivenessHandler())
+	r.Method(http.MethodGet, "/readyz", checks.ReadinessHandler())
+	r.Method(http.MethodGet, "/health", checks.ReadinessHandler())
 	r.Post("/register", userHandler.Register)
 	r.Post("/login", userHandler.Login)
 	r.Get("/users/{id}", userHandler.GetUser)
@@ -64,8 +117,14 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("User service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	runner.Serve("user", ":"+port, r)
+	runner.OnClose("database", func(context.Context) error { return db.Close() })
+	runner.OnClose("tracing", tracer.Shutdown)
+	runner.OnClose("logger", func(context.Context) error {
+		logger.Sync()
+		return nil
+	})
+	if err := runner.Run(context.Background()); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -2,34 +2,52 @@ package auth
 
 import (
 	"net/http"
-	"os"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
 	"github.com/oguzhan/e-commerce/pkg/models"
 )
 
+// LoginHook runs after a user has successfully registered or logged in.
+type LoginHook func(c *gin.Context, userID uint)
+
 type Handler struct {
-	service *Service
+	service    *Service
+	tokens     *Tokens
+	loginHooks []LoginHook
+}
+
+// NewHandler returns the auth handler. Services that only authenticate
+// requests may pass a nil service, but then cannot use RequireAdmin.
+func NewHandler(service *Service, tokens *Tokens) *Handler {
+	return &Handler{service: service, tokens: tokens}
 }
 
-func NewHandler(service *Service) *Handler {
-	return &Handler{service: service}
+// OnLogin registers a hook that runs after every successful login or
+// registration, e.g. to merge a guest cart into the user's cart.
+func (h *Handler) OnLogin(hook LoginHook) {
+	h.loginHooks = append(h.loginHooks, hook)
+}
+
+func (h *Handler) runLoginHooks(c *gin.Context, userID uint) {
+	for _, hook := range h.loginHooks {
+		hook(c, userID)
+	}
 }
 
 func (h *Handler) Register(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.Register(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
+	h.runLoginHooks(c, user.ID)
 	c.JSON(http.StatusCreated, user)
 }
 
@@ -40,27 +58,23 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&credentials); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	user, err := h.service.Login(credentials.Email, credentials.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
-
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokenString, _, err := h.tokens.Issue(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
+	h.runLoginHooks(c, user.ID)
 	c.JSON(http.StatusOK, gin.H{
 		"token": tokenString,
 		"user":  user,
@@ -71,40 +85,54 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
-			c.Abort()
+			middleware.WriteError(c, ErrMissingAuthHeader)
 			return
 		}
 
-		// Check if the header starts with "Bearer "
-		if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
-			c.Abort()
+		userID, err := h.tokens.Authenticate(authHeader)
+		if err != nil {
+			middleware.WriteError(c, err)
 			return
 		}
 
-		// Extract the token without "Bearer " prefix
-		tokenString := authHeader[7:]
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+// OptionalAuthMiddleware authenticates the request when an Authorization
+// header is present and lets anonymous requests through otherwise. A
+// malformed or invalid token is still rejected.
+func (h *Handler) OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
+			return
+		}
 
+		userID, err := h.tokens.Authenticate(authHeader)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+			middleware.WriteError(c, err)
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			userID := uint(claims["user_id"].(float64))
-			c.Set("user_id", userID)
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
+
+// RequireAdmin only lets through users whose role is "admin". It must run
+// after AuthMiddleware. The role is read from the database so that demoting
+// a user takes effect immediately.
+func (h *Handler) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := h.service.GetUserByID(c.GetUint("user_id"))
+		if err != nil || user.Role != "admin" {
+			middleware.WriteError(c, ErrAdminRequired)
 			return
 		}
+		c.Next()
 	}
 }
 
@@ -112,7 +140,7 @@ func (h *Handler) GetUserFromToken(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	user, err := h.service.GetUserByID(userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -4,15 +4,26 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/oguzhan/e-commerce/internal/events"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 	"github.com/oguzhan/e-commerce/pkg/models"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrInvalidCredentials = apperrors.New(http.StatusUnauthorized, "invalid_credentials", "invalid email or password")
+	ErrEmailTaken         = apperrors.New(http.StatusConflict, "email_taken", "a user with this email already exists")
+	ErrMissingAuthHeader  = apperrors.New(http.StatusUnauthorized, "missing_authorization", "authorization header is required")
+	ErrInvalidAuthHeader  = apperrors.New(http.StatusUnauthorized, "invalid_authorization_header", "invalid authorization header format")
+	ErrInvalidToken       = apperrors.ErrInvalidToken.WithMessage("invalid token")
+	ErrAdminRequired      = apperrors.New(http.StatusForbidden, "admin_required", "admin access required")
+	ErrUserNotFound       = apperrors.New(http.StatusNotFound, "user_not_found", "user not found")
+)
+
 type Service struct {
 	db     *gorm.DB
 	logger *log.Logger
@@ -41,8 +52,26 @@ func (s *Service) Register(user *models.User) error {
 		Role:      "user",
 	}
 
+	var existing int64
+	if err := s.db.Model(&models.User{}).Where("email = ?", user.Email).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrEmailTaken
+	}
+
 	// Save the user to the database
-	if err := s.db.Create(newUser).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(newUser).Error; err != nil {
+			return err
+		}
+		return events.Publish(tx, events.UserRegistered{
+			UserID:    newUser.ID,
+			Email:     newUser.Email,
+			FirstName: newUser.FirstName,
+		})
+	})
+	if err != nil {
 		return fmt.Errorf("failed to create user: %v", err)
 	}
 
@@ -62,7 +91,7 @@ func (s *Service) Login(email, password string) (*models.User, error) {
 	var user models.User
 	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrInvalidCredentials
 		}
 		return nil, fmt.Errorf("failed to retrieve user: %v", err)
 	}
@@ -71,7 +100,7 @@ func (s *Service) Login(email, password string) (*models.User, error) {
 	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
 		s.logger.Printf("Password comparison failed: %v", err)
-		return nil, errors.New("invalid password")
+		return nil, ErrInvalidCredentials
 	}
 
 	// Update last login time
@@ -87,45 +116,10 @@ func (s *Service) Login(email, password string) (*models.User, error) {
 func (s *Service) GetUserByID(id uint) (*models.User, error) {
 	var user models.User
 	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
 		return nil, err
 	}
 	return &user, nil
 }
-
-func (s *Service) generateToken(user *models.User) (string, time.Time, error) {
-	expiresAt := time.Now().Add(24 * time.Hour)
-	claims := &models.Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-	if err != nil {
-		return "", time.Time{}, err
-	}
-
-	return tokenString, expiresAt, nil
-}
-
-func (s *Service) ValidateToken(tokenString string) (*models.Claims, error) {
-	claims := &models.Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if !token.Valid {
-		return nil, errors.New("invalid token")
-	}
-
-	return claims, nil
-}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+// Tokens issues and verifies the signed tokens handed out at login, using
+// the jwt.secret and jwt.expiration settings.
+type Tokens struct {
+	secret     []byte
+	expiration time.Duration
+}
+
+func NewTokens(secret string, expiration time.Duration) *Tokens {
+	return &Tokens{secret: []byte(secret), expiration: expiration}
+}
+
+// Issue returns a token for the user and when it expires.
+func (t *Tokens) Issue(user *models.User) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(t.expiration)
+	claims := &models.Claims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Role:   user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(t.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expiresAt, nil
+}
+
+// Validate checks the token's signature and expiry and returns its claims.
+func (t *Tokens) Validate(tokenString string) (*models.Claims, error) {
+	claims := &models.Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || claims.UserID == 0 {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// Authenticate verifies a "Bearer" Authorization header and returns the user
+// ID from its token.
+func (t *Tokens) Authenticate(authHeader string) (uint, error) {
+	tokenString, ok := strings.CutPrefix(authHeader, "Bearer ")
+	if !ok {
+		return 0, ErrInvalidAuthHeader
+	}
+	claims, err := t.Validate(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
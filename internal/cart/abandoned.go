@@ -0,0 +1,282 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CartReminder records a reminder sent for one idle period of a cart. A cart
+// gets at most one reminder per stage for each idle period, where an idle
+// period is identified by the time of the cart's last item change.
+type CartReminder struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	CartID           uint       `json:"cart_id" gorm:"uniqueIndex:idx_cart_reminders_stage"`
+	Stage            string     `json:"stage" gorm:"uniqueIndex:idx_cart_reminders_stage"`
+	IdleSince        time.Time  `json:"idle_since" gorm:"uniqueIndex:idx_cart_reminders_stage"`
+	UserID           uint       `json:"user_id" gorm:"index"`
+	SentAt           time.Time  `json:"sent_at"`
+	RecoveredOrderID *uint      `json:"recovered_order_id,omitempty"`
+	RecoveredAt      *time.Time `json:"recovered_at,omitempty"`
+}
+
+// Reminder is the payload handed to a ReminderNotifier.
+type Reminder struct {
+	CartID    uint
+	UserID    uint
+	Email     string
+	FirstName string
+	Stage     string
+	IdleSince time.Time
+	Items     []CartItem
+}
+
+// ReminderNotifier delivers abandoned cart reminders to users.
+type ReminderNotifier interface {
+	SendCartReminder(ctx context.Context, reminder Reminder) error
+}
+
+// LogNotifier is a ReminderNotifier that only logs reminders. It is used
+// until a real notification channel is configured.
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) SendCartReminder(ctx context.Context, reminder Reminder) error {
+	n.logger.Info("abandoned cart reminder",
+		zap.Uint("cart_id", reminder.CartID),
+		zap.Uint("user_id", reminder.UserID),
+		zap.String("stage", reminder.Stage),
+		zap.Time("idle_since", reminder.IdleSince),
+	)
+	return nil
+}
+
+type AbandonedCartOptions struct {
+	// Stages are the idle durations after which a reminder is sent.
+	Stages []time.Duration
+	// RecoveryWindow is how long after a reminder an order still counts as
+	// recovered by it.
+	RecoveryWindow time.Duration
+}
+
+// AbandonedCartWorker finds user carts that have been idle past the
+// configured stages and sends one reminder per stage.
+type AbandonedCartWorker struct {
+	db       *gorm.DB
+	notifier ReminderNotifier
+	stages   []time.Duration
+	window   time.Duration
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewAbandonedCartWorker(db *gorm.DB, notifier ReminderNotifier, options AbandonedCartOptions, logger *zap.Logger) *AbandonedCartWorker {
+	stages := append([]time.Duration(nil), options.Stages...)
+	sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+	if options.RecoveryWindow <= 0 {
+		options.RecoveryWindow = 7 * 24 * time.Hour
+	}
+	return &AbandonedCartWorker{
+		db:       db,
+		notifier: notifier,
+		stages:   stages,
+		window:   options.RecoveryWindow,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Run runs the worker every interval until ctx is cancelled.
+func (w *AbandonedCartWorker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.RunOnce(ctx); err != nil {
+				w.logger.Error("abandoned cart run failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RunOnce sends due reminders and attributes new orders to earlier ones.
+func (w *AbandonedCartWorker) RunOnce(ctx context.Context) error {
+	if len(w.stages) == 0 {
+		return nil
+	}
+	if err := w.sendReminders(ctx); err != nil {
+		return err
+	}
+	return w.detectRecoveries()
+}
+
+func (w *AbandonedCartWorker) sendReminders(ctx context.Context) error {
+	now := w.now()
+	idleBefore := now.Add(-w.stages[0])
+
+	var carts []Cart
+	err := w.db.Preload("Items").
+		Where("user_id <> 0").
+		Where("EXISTS (SELECT 1 FROM cart_items WHERE cart_items.cart_id = carts.id)").
+		Where("NOT EXISTS (SELECT 1 FROM cart_items WHERE cart_items.cart_id = carts.id AND cart_items.updated_at > ?)", idleBefore).
+		Find(&carts).Error
+	if err != nil || len(carts) == 0 {
+		return err
+	}
+
+	userIDs := make([]uint, 0, len(carts))
+	for _, cart := range carts {
+		userIDs = append(userIDs, cart.UserID)
+	}
+	var users []models.User
+	if err := w.db.Where("id IN ? AND is_active = ? AND cart_reminders_opt_out = ?", userIDs, true, false).
+		Find(&users).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	for _, cart := range carts {
+		user, ok := byID[cart.UserID]
+		if !ok {
+			continue
+		}
+
+		idleSince := lastActivity(cart)
+		stage, ok := w.dueStage(now.Sub(idleSince))
+		if !ok {
+			continue
+		}
+
+		record := CartReminder{
+			CartID:    cart.ID,
+			Stage:     stage,
+			IdleSince: idleSince,
+			UserID:    cart.UserID,
+			SentAt:    now,
+		}
+		result := w.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		err := w.notifier.SendCartReminder(ctx, Reminder{
+			CartID:    cart.ID,
+			UserID:    user.ID,
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			Stage:     stage,
+			IdleSince: idleSince,
+			Items:     cart.Items,
+		})
+		if err != nil {
+			// Forget the reminder so the next run retries it.
+			w.db.Delete(&record)
+			w.logger.Warn("failed to send cart reminder",
+				zap.Uint("cart_id", cart.ID), zap.String("stage", stage), zap.Error(err))
+			continue
+		}
+		metrics.RecordCartAbandoned(stage)
+	}
+	return nil
+}
+
+// dueStage returns the name of the longest stage that idle has reached.
+func (w *AbandonedCartWorker) dueStage(idle time.Duration) (string, bool) {
+	for i := len(w.stages) - 1; i >= 0; i-- {
+		if idle >= w.stages[i] {
+			return w.stages[i].String(), true
+		}
+	}
+	return "", false
+}
+
+func lastActivity(cart Cart) time.Time {
+	var last time.Time
+	for _, item := range cart.Items {
+		if item.UpdatedAt.After(last) {
+			last = item.UpdatedAt
+		}
+	}
+	return last
+}
+
+// detectRecoveries marks reminders whose user placed an order after the
+// reminder was sent, counting each idle period of a cart once.
+func (w *AbandonedCartWorker) detectRecoveries() error {
+	now := w.now()
+
+	var pending []CartReminder
+	if err := w.db.Where("recovered_order_id IS NULL AND sent_at >= ?", now.Add(-w.window)).
+		Order("sent_at").
+		Find(&pending).Error; err != nil {
+		return err
+	}
+
+	type period struct {
+		cartID    uint
+		idleSince time.Time
+	}
+	groups := make(map[period][]CartReminder)
+	var order []period
+	for _, reminder := range pending {
+		key := period{reminder.CartID, reminder.IdleSince.UTC()}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], reminder)
+	}
+
+	for _, key := range order {
+		reminders := groups[key]
+		first := reminders[0]
+
+		var placed models.Order
+		err := w.db.Where("user_id = ? AND created_at >= ?", first.UserID, first.SentAt).
+			Order("created_at").
+			First(&placed).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		ids := make([]uint, 0, len(reminders))
+		stage := first.Stage
+		for _, reminder := range reminders {
+			ids = append(ids, reminder.ID)
+			if !reminder.SentAt.After(placed.CreatedAt) {
+				stage = reminder.Stage
+			}
+		}
+		if err := w.db.Model(&CartReminder{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"recovered_order_id": placed.ID,
+			"recovered_at":       now,
+		}).Error; err != nil {
+			return err
+		}
+		metrics.RecordCartRecovered(stage)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type recordingNotifier struct {
+	sent []Reminder
+	err  error
+}
+
+func (n *recordingNotifier) SendCartReminder(ctx context.Context, reminder Reminder) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, reminder)
+	return nil
+}
+
+func setupReminderTest(t *testing.T) (*Service, *gorm.DB, *recordingNotifier, *AbandonedCartWorker) {
+	service, db := setupTestService(t, Options{})
+	if err := db.AutoMigrate(&models.User{}, &models.Order{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, user := range []models.User{
+		{Model: gorm.Model{ID: 10}, Email: "buyer@example.com", Password: "x"},
+		{Model: gorm.Model{ID: 11}, Email: "optout@example.com", Password: "x", CartRemindersOptOut: true},
+	} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("Failed to create test user: %v", err)
+		}
+	}
+
+	notifier := &recordingNotifier{}
+	worker := NewAbandonedCartWorker(db, notifier, AbandonedCartOptions{
+		Stages: []time.Duration{24 * time.Hour, time.Hour, 72 * time.Hour},
+	}, zap.NewNop())
+	return service, db, notifier, worker
+}
+
+func TestAbandonedCartWorker_SendsEachStageOnce(t *testing.T) {
+	service, db, notifier, worker := setupReminderTest(t)
+	assert.NoError(t, service.AddItem(10, 1, 1))
+	assert.NoError(t, service.AddItem(11, 1, 1))
+	assert.NoError(t, db.Model(&CartItem{}).Where("1 = 1").
+		Update("updated_at", time.Now().Add(-2*time.Hour)).Error)
+
+	assert.NoError(t, worker.RunOnce(context.Background()))
+	assert.NoError(t, worker.RunOnce(context.Background()))
+	assert.Len(t, notifier.sent, 1)
+	assert.Equal(t, uint(10), notifier.sent[0].UserID)
+	assert.Equal(t, "1h0m0s", notifier.sent[0].Stage)
+
+	worker.now = func() time.Time { return time.Now().Add(30 * time.Hour) }
+	assert.NoError(t, worker.RunOnce(context.Background()))
+	assert.Len(t, notifier.sent, 2)
+	assert.Equal(t, "24h0m0s", notifier.sent[1].Stage)
+
+	var reminders int64
+	db.Model(&CartReminder{}).Count(&reminders)
+	assert.Equal(t, int64(2), reminders)
+}
+
+func TestAbandonedCartWorker_RetriesFailedSends(t *testing.T) {
+	service, db, notifier, worker := setupReminderTest(t)
+	assert.NoError(t, service.AddItem(10, 1, 1))
+	assert.NoError(t, db.Model(&CartItem{}).Where("1 = 1").
+		Update("updated_at", time.Now().Add(-2*time.Hour)).Error)
+
+	notifier.err = errors.New("smtp down")
+	assert.NoError(t, worker.RunOnce(context.Background()))
+
+	notifier.err = nil
+	assert.NoError(t, worker.RunOnce(context.Background()))
+	assert.Len(t, notifier.sent, 1)
+}
+
+func TestAbandonedCartWorker_DetectsRecoveredOrders(t *testing.T) {
+	service, db, _, worker := setupReminderTest(t)
+	assert.NoError(t, service.AddItem(10, 1, 1))
+	assert.NoError(t, db.Model(&CartItem{}).Where("1 = 1").
+		Update("updated_at", time.Now().Add(-2*time.Hour)).Error)
+	assert.NoError(t, worker.RunOnce(context.Background()))
+
+	order := models.Order{UserID: 10, TotalAmount: 50, PaymentMethod: "credit_card"}
+	assert.NoError(t, db.Create(&order).Error)
+	assert.NoError(t, worker.RunOnce(context.Background()))
+
+	var reminder CartReminder
+	assert.NoError(t, db.First(&reminder).Error)
+	if assert.NotNil(t, reminder.RecoveredOrderID) {
+		assert.Equal(t, order.ID, *reminder.RecoveredOrderID)
+	}
+}
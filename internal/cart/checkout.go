@@ -0,0 +1,77 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/internal/order"
+	"github.com/oguzhan/e-commerce/internal/payment"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+// CheckoutHandler serves guest checkout. The order is built from the
+// request's signed guest cart and priced through the order service's
+// catalog; the request only adds the email, addresses and payment method.
+type CheckoutHandler struct {
+	carts    *Service
+	orders   *order.Service
+	payments *payment.Service
+}
+
+func NewCheckoutHandler(carts *Service, orders *order.Service, payments *payment.Service) *CheckoutHandler {
+	return &CheckoutHandler{carts: carts, orders: orders, payments: payments}
+}
+
+// CheckoutGuest places and pays an order for the contents of the guest
+// cart, then empties the cart.
+func (h *CheckoutHandler) CheckoutGuest(c *gin.Context) {
+	var request models.GuestOrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+
+	placed, err := h.checkout(c.Request.Context(), guestToken(c), &request)
+	if placed == nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	if err != nil {
+		// The order is placed and paid; a cart left behind does not undo that
+		_ = c.Error(err)
+	}
+	c.JSON(http.StatusCreated, placed)
+}
+
+// checkout places the order and pays it. An order whose payment fails is
+// cancelled again, which releases its stock. Once paid, the order is
+// returned even if the cart could not be emptied.
+func (h *CheckoutHandler) checkout(ctx context.Context, token string, request *models.GuestOrderRequest) (*models.Order, error) {
+	if token == "" {
+		return nil, ErrCartNotFound
+	}
+	cart, err := h.carts.GetGuestCart(token)
+	if errors.Is(err, ErrInvalidCartToken) {
+		return nil, ErrCartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.OrderItem, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		items = append(items, models.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	placed, err := h.orders.PlaceGuestOrder(ctx, request, items)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := h.payments.PayGuestOrder(ctx, placed.ID, request.PaymentMethod); err != nil {
+		return nil, errors.Join(err, h.orders.CancelOrder(ctx, placed.ID, 0))
+	}
+	return placed, h.carts.ClearCartItems(cart)
+}
@@ -1,10 +1,19 @@
 package cart
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+)
+
+const (
+	// TokenCookie and TokenHeader carry the signed guest cart token. The
+	// header takes precedence over the cookie.
+	TokenCookie = "cart_token"
+	TokenHeader = "X-Cart-Token"
 )
 
 type Handler struct {
@@ -15,82 +24,171 @@ func NewHandler(service *Service) *Handler {
 	return &Handler{service: service}
 }
 
+// resolveCart returns the authenticated user's cart, or the guest cart named
+// by the request token. When create is set and the visitor has no valid guest
+// cart, a new one is started and its token is returned to the client.
+func (h *Handler) resolveCart(c *gin.Context, create bool) (*Cart, error) {
+	if userID := c.GetUint("user_id"); userID != 0 {
+		return h.service.GetCartByUserID(userID)
+	}
+
+	if token := guestToken(c); token != "" {
+		cart, err := h.service.GetGuestCart(token)
+		if err == nil {
+			h.setGuestToken(c, cart)
+			return cart, nil
+		}
+		if !errors.Is(err, ErrCartNotFound) && !errors.Is(err, ErrInvalidCartToken) {
+			return nil, err
+		}
+	}
+
+	if !create {
+		return &Cart{Items: []CartItem{}}, nil
+	}
+
+	cart, _, err := h.service.CreateGuestCart()
+	if err != nil {
+		return nil, err
+	}
+	h.setGuestToken(c, cart)
+	return cart, nil
+}
+
+func guestToken(c *gin.Context) string {
+	if token := c.GetHeader(TokenHeader); token != "" {
+		return token
+	}
+	token, _ := c.Cookie(TokenCookie)
+	return token
+}
+
+func (h *Handler) setGuestToken(c *gin.Context, cart *Cart) {
+	token := h.service.GuestToken(cart)
+	c.Header(TokenHeader, token)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(TokenCookie, token, int(h.service.options.GuestTTL.Seconds()), "/", "", h.secureCookie(c), true)
+}
+
+func (h *Handler) secureCookie(c *gin.Context) bool {
+	return h.service.options.SecureCookie || c.Request.TLS != nil
+}
+
+// MergeGuestCart folds the request's guest cart into the user's cart. It is
+// meant to run as an auth login hook; failures are recorded on the context
+// but never fail the login itself.
+func (h *Handler) MergeGuestCart(c *gin.Context, userID uint) {
+	token := guestToken(c)
+	if token == "" {
+		return
+	}
+	if err := h.service.MergeGuestCart(userID, token); err != nil {
+		if !errors.Is(err, ErrCartNotFound) && !errors.Is(err, ErrInvalidCartToken) {
+			_ = c.Error(err)
+			return
+		}
+	}
+	c.SetCookie(TokenCookie, "", -1, "/", "", h.secureCookie(c), true)
+}
+
 func (h *Handler) GetCart(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	cart, err := h.service.GetCartByUserID(userID)
+	view, err := h.view(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, cart)
+	c.JSON(http.StatusOK, view)
 }
 
 func (h *Handler) GetItems(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	cart, err := h.service.GetCartByUserID(userID)
+	view, err := h.view(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, cart.Items)
+	c.JSON(http.StatusOK, view.Items)
+}
+
+func (h *Handler) view(c *gin.Context) (*View, error) {
+	cart, err := h.resolveCart(c, false)
+	if err != nil {
+		return nil, err
+	}
+	return h.service.View(cart)
 }
 
 func (h *Handler) AddItem(c *gin.Context) {
-	userID := c.GetUint("user_id")
 	var req struct {
 		ProductID uint `json:"product_id" binding:"required"`
 		Quantity  int  `json:"quantity" binding:"required,min=1"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
+		return
+	}
+	cart, err := h.resolveCart(c, true)
+	if err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
-	if err := h.service.AddItem(userID, req.ProductID, req.Quantity); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.AddCartItem(cart, req.ProductID, req.Quantity); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 	c.Status(http.StatusCreated)
 }
 
 func (h *Handler) UpdateItem(c *gin.Context) {
-	userID := c.GetUint("user_id")
 	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item ID"})
+		middleware.WriteError(c, ErrInvalidItemID)
 		return
 	}
 	var req struct {
 		Quantity int `json:"quantity" binding:"required,min=1"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
-	if err := h.service.UpdateItem(userID, uint(itemID), req.Quantity); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	cart, err := h.resolveCart(c, false)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	if err := h.service.UpdateCartItem(cart, uint(itemID), req.Quantity); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
 }
 
 func (h *Handler) RemoveItem(c *gin.Context) {
-	userID := c.GetUint("user_id")
 	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item ID"})
+		middleware.WriteError(c, ErrInvalidItemID)
+		return
+	}
+	cart, err := h.resolveCart(c, false)
+	if err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
-	if err := h.service.RemoveItem(userID, uint(itemID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.RemoveCartItem(cart, uint(itemID)); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
 func (h *Handler) ClearCart(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	if err := h.service.ClearCart(userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	cart, err := h.resolveCart(c, false)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	if err := h.service.ClearCartItems(cart); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
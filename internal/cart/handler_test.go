@@ -0,0 +1,41 @@
+package cart
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetGuestToken_SecureCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		option bool
+		tls    bool
+		want   bool
+	}{
+		{"plain http", false, false, false},
+		{"tls request", false, true, true},
+		{"configured", true, false, true},
+	}
+	for _, tt := range tests {
+		service, _ := setupTestService(t, Options{SecureCookie: tt.option})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/cart/items", nil)
+		if tt.tls {
+			c.Request.TLS = &tls.ConnectionState{}
+		}
+
+		NewHandler(service).setGuestToken(c, &Cart{ID: 1})
+		cookies := w.Result().Cookies()
+		if assert.Len(t, cookies, 1, tt.name) {
+			assert.Equal(t, TokenCookie, cookies[0].Name, tt.name)
+			assert.Equal(t, tt.want, cookies[0].Secure, tt.name)
+		}
+	}
+}
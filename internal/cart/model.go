@@ -6,17 +6,30 @@ import (
 
 type Cart struct {
 	ID        uint       `json:"id" gorm:"primaryKey"`
-	UserID    uint       `json:"user_id" gorm:"uniqueIndex"`
+	UserID    uint       `json:"user_id" gorm:"uniqueIndex:idx_carts_user,where:user_id <> 0"`
+	GuestID   string     `json:"-" gorm:"uniqueIndex:idx_carts_guest,where:guest_id <> ''"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 	Items     []CartItem `json:"items" gorm:"foreignKey:CartID"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 }
 
+// IsGuest reports whether the cart belongs to an anonymous visitor.
+func (c *Cart) IsGuest() bool {
+	return c.UserID == 0
+}
+
 type CartItem struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	CartID    uint      `json:"cart_id"`
 	ProductID uint      `json:"product_id"`
 	Quantity  int       `json:"quantity"`
+	UnitPrice float64   `json:"unit_price"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// Models returns the cart tables for schema migration.
+func Models() []interface{} {
+	return []interface{}{&Cart{}, &CartItem{}, &CartReminder{}}
+}
@@ -1,17 +1,78 @@
 package cart
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"time"
 
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrCartNotFound       = apperrors.New(http.StatusNotFound, "cart_not_found", "cart not found")
+	ErrItemNotFound       = apperrors.New(http.StatusNotFound, "cart_item_not_found", "cart item not found")
+	ErrInvalidItemID      = apperrors.New(http.StatusBadRequest, "invalid_item_id", "invalid item ID")
+	ErrInvalidQuantity    = apperrors.New(http.StatusBadRequest, "invalid_quantity", "quantity must be greater than zero")
+	ErrProductNotFound    = apperrors.New(http.StatusNotFound, "product_not_found", "product not found")
+	ErrProductUnavailable = apperrors.New(http.StatusConflict, "product_unavailable", "product is not available")
+	ErrInsufficientStock  = apperrors.New(http.StatusConflict, "insufficient_stock", "not enough stock for the requested quantity")
+)
+
+// MergePolicy decides how a guest cart line is combined with a line for the
+// same product already in the user's cart.
+type MergePolicy string
+
+const (
+	// MergeSumQuantities adds the guest quantity to the user's quantity.
+	MergeSumQuantities MergePolicy = "sum"
+	// MergeKeepNewest keeps the quantity of whichever line was updated last.
+	MergeKeepNewest MergePolicy = "newest"
+)
+
+type Options struct {
+	TokenSecret string
+	GuestTTL    time.Duration
+	MergePolicy MergePolicy
+	// SecureCookie marks the guest cart cookie Secure on every request,
+	// not only on those that arrive over TLS.
+	SecureCookie bool
+}
+
+func DefaultOptions() Options {
+	return Options{
+		TokenSecret: "your-secret-key",
+		GuestTTL:    7 * 24 * time.Hour,
+		MergePolicy: MergeSumQuantities,
+	}
+}
+
 type Service struct {
-	db *gorm.DB
+	db      *gorm.DB
+	signer  *TokenSigner
+	options Options
 }
 
 func NewService(db *gorm.DB) *Service {
-	return &Service{db: db}
+	return NewServiceWithOptions(db, DefaultOptions())
+}
+
+func NewServiceWithOptions(db *gorm.DB, options Options) *Service {
+	defaults := DefaultOptions()
+	if options.TokenSecret == "" {
+		options.TokenSecret = defaults.TokenSecret
+	}
+	if options.GuestTTL <= 0 {
+		options.GuestTTL = defaults.GuestTTL
+	}
+	if options.MergePolicy == "" {
+		options.MergePolicy = defaults.MergePolicy
+	}
+	return &Service{db: db, signer: NewTokenSigner(options.TokenSecret), options: options}
 }
 
 func (s *Service) GetCartByUserID(userID uint) (*Cart, error) {
@@ -25,19 +86,90 @@ func (s *Service) GetCartByUserID(userID uint) (*Cart, error) {
 	return &cart, err
 }
 
+// CreateGuestCart starts an empty cart for an anonymous visitor and returns
+// it together with the signed token that identifies it.
+func (s *Service) CreateGuestCart() (*Cart, string, error) {
+	guestID, err := NewGuestID()
+	if err != nil {
+		return nil, "", err
+	}
+	expiresAt := time.Now().Add(s.options.GuestTTL)
+	cart := Cart{GuestID: guestID, ExpiresAt: &expiresAt}
+	if err := s.db.Create(&cart).Error; err != nil {
+		return nil, "", err
+	}
+	return &cart, s.signer.Sign(guestID), nil
+}
+
+// GetGuestCart loads the unexpired guest cart identified by token and extends
+// its expiry by the configured TTL.
+func (s *Service) GetGuestCart(token string) (*Cart, error) {
+	guestID, err := s.signer.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var cart Cart
+	err = s.db.Preload("Items").
+		Where("guest_id = ? AND expires_at > ?", guestID, time.Now()).
+		First(&cart).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.options.GuestTTL)
+	if err := s.db.Model(&cart).Update("expires_at", expiresAt).Error; err != nil {
+		return nil, err
+	}
+	cart.ExpiresAt = &expiresAt
+	return &cart, nil
+}
+
+// GuestToken returns the signed token for a guest cart.
+func (s *Service) GuestToken(cart *Cart) string {
+	return s.signer.Sign(cart.GuestID)
+}
+
 func (s *Service) AddItem(userID, productID uint, quantity int) error {
 	cart, err := s.GetCartByUserID(userID)
 	if err != nil {
 		return err
 	}
+	return s.AddCartItem(cart, productID, quantity)
+}
+
+func (s *Service) AddCartItem(cart *Cart, productID uint, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+
 	var item CartItem
-	err = s.db.Where("cart_id = ? AND product_id = ?", cart.ID, productID).First(&item).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		item = CartItem{CartID: cart.ID, ProductID: productID, Quantity: quantity}
-		return s.db.Create(&item).Error
+	err := s.db.Where("cart_id = ? AND product_id = ?", cart.ID, productID).First(&item).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
 	}
-	item.Quantity += quantity
-	return s.db.Save(&item).Error
+
+	product, err := s.purchasableProduct(productID, item.Quantity+quantity)
+	if err != nil {
+		return err
+	}
+
+	if item.ID == 0 {
+		item = CartItem{CartID: cart.ID, ProductID: productID, Quantity: quantity, UnitPrice: product.Price}
+		err = s.db.Create(&item).Error
+	} else {
+		// UnitPrice stays the price the item was first added at
+		item.Quantity += quantity
+		err = s.db.Save(&item).Error
+	}
+	if err != nil {
+		return err
+	}
+	metrics.RecordCartAdd()
+	return nil
 }
 
 func (s *Service) UpdateItem(userID, itemID uint, quantity int) error {
@@ -45,20 +177,60 @@ func (s *Service) UpdateItem(userID, itemID uint, quantity int) error {
 	if err != nil {
 		return err
 	}
+	return s.UpdateCartItem(cart, itemID, quantity)
+}
+
+func (s *Service) UpdateCartItem(cart *Cart, itemID uint, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+
 	var item CartItem
-	err = s.db.Where("id = ? AND cart_id = ?", itemID, cart.ID).First(&item).Error
+	err := s.db.Where("id = ? AND cart_id = ?", itemID, cart.ID).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrItemNotFound
+	}
 	if err != nil {
 		return err
 	}
+
+	if _, err := s.purchasableProduct(item.ProductID, quantity); err != nil {
+		return err
+	}
+
 	item.Quantity = quantity
 	return s.db.Save(&item).Error
 }
 
+// purchasableProduct loads the product and checks that quantity units of it
+// can be bought right now.
+func (s *Service) purchasableProduct(productID uint, quantity int) (*models.Product, error) {
+	var product models.Product
+	err := s.db.First(&product, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsActive {
+		return nil, ErrProductUnavailable
+	}
+	if product.Stock < quantity {
+		return nil, ErrInsufficientStock
+	}
+	return &product, nil
+}
+
 func (s *Service) RemoveItem(userID, itemID uint) error {
 	cart, err := s.GetCartByUserID(userID)
 	if err != nil {
 		return err
 	}
+	return s.RemoveCartItem(cart, itemID)
+}
+
+func (s *Service) RemoveCartItem(cart *Cart, itemID uint) error {
 	return s.db.Where("id = ? AND cart_id = ?", itemID, cart.ID).Delete(&CartItem{}).Error
 }
 
@@ -67,5 +239,128 @@ func (s *Service) ClearCart(userID uint) error {
 	if err != nil {
 		return err
 	}
+	return s.ClearCartItems(cart)
+}
+
+func (s *Service) ClearCartItems(cart *Cart) error {
 	return s.db.Where("cart_id = ?", cart.ID).Delete(&CartItem{}).Error
 }
+
+// MergeGuestCart moves the items of the guest cart identified by token into
+// the user's cart, resolving duplicate products with the configured merge
+// policy, and deletes the guest cart.
+func (s *Service) MergeGuestCart(userID uint, token string) error {
+	guest, err := s.GetGuestCart(token)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.GetCartByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[uint]CartItem, len(user.Items))
+	for _, item := range user.Items {
+		existing[item.ProductID] = item
+	}
+
+	merged := make(map[uint]int, len(guest.Items))
+	for _, item := range guest.Items {
+		current, ok := existing[item.ProductID]
+		if !ok {
+			continue
+		}
+		quantity := current.Quantity
+		switch s.options.MergePolicy {
+		case MergeKeepNewest:
+			if item.UpdatedAt.After(current.UpdatedAt) {
+				quantity = item.Quantity
+			}
+		default:
+			quantity += item.Quantity
+		}
+		if merged[item.ProductID], err = s.mergedQuantity(item.ProductID, current.Quantity, quantity); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range guest.Items {
+			current, ok := existing[item.ProductID]
+			if !ok {
+				if err := tx.Model(&CartItem{}).Where("id = ?", item.ID).
+					Update("cart_id", user.ID).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := tx.Model(&CartItem{}).Where("id = ?", current.ID).
+				Update("quantity", merged[item.ProductID]).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&CartItem{}, item.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&Cart{}, guest.ID).Error
+	})
+}
+
+// mergedQuantity runs the quantity a merge would give a product through
+// the checks AddItem makes. A quantity above the stock on hand is clamped
+// to the stock, but never below what the user's cart already held, and a
+// product that can no longer be bought keeps the user's quantity. A failed
+// merge would strand the guest cart, so these outcomes are not errors.
+func (s *Service) mergedQuantity(productID uint, current, quantity int) (int, error) {
+	_, err := s.purchasableProduct(productID, quantity)
+	switch {
+	case err == nil:
+		return quantity, nil
+	case errors.Is(err, ErrInsufficientStock):
+		product, err := s.purchasableProduct(productID, 0)
+		if err != nil {
+			return 0, err
+		}
+		return max(product.Stock, min(current, quantity)), nil
+	case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrProductUnavailable):
+		return current, nil
+	default:
+		return 0, err
+	}
+}
+
+// PurgeExpiredGuestCarts deletes guest carts whose TTL has elapsed.
+func (s *Service) PurgeExpiredGuestCarts() (int64, error) {
+	expired := s.db.Model(&Cart{}).Select("id").
+		Where("user_id = 0 AND expires_at <= ?", time.Now())
+
+	var purged int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cart_id IN (?)", expired).Delete(&CartItem{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("user_id = 0 AND expires_at <= ?", time.Now()).Delete(&Cart{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// RunGuestCartJanitor purges expired guest carts every interval until ctx
+// is cancelled.
+func (s *Service) RunGuestCartJanitor(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.PurgeExpiredGuestCarts(); err != nil {
+				logger.Error("guest cart purge failed", zap.Error(err))
+			}
+		}
+	}
+}
@@ -2,6 +2,12 @@ package cart
 
 import (
 	"testing"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 type mockCartDB struct {
@@ -32,3 +38,208 @@ func TestFindByUserID_NotFound(t *testing.T) {
 		t.Fatal("expected nil, got cart")
 	}
 }
+
+func setupTestService(t *testing.T, options Options) (*Service, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(append(Models(), &models.Product{})...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, product := range []models.Product{
+		{Name: "Keyboard", Price: 50, Stock: 10, SKU: "KB-1", IsActive: true},
+		{Name: "Mouse", Price: 20, Stock: 10, SKU: "MS-1", IsActive: true},
+	} {
+		if err := db.Create(&product).Error; err != nil {
+			t.Fatalf("Failed to create test product: %v", err)
+		}
+	}
+	return NewServiceWithOptions(db, options), db
+}
+
+func TestTokenSigner_RejectsTamperedToken(t *testing.T) {
+	signer := NewTokenSigner("secret")
+	token := signer.Sign("guest-1")
+
+	guestID, err := signer.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "guest-1", guestID)
+
+	_, err = signer.Verify("guest-2" + token[len("guest-1"):])
+	assert.ErrorIs(t, err, ErrInvalidCartToken)
+
+	_, err = NewTokenSigner("other").Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidCartToken)
+}
+
+func TestGuestCart_Expires(t *testing.T) {
+	service, db := setupTestService(t, Options{GuestTTL: time.Hour})
+	cart, token, err := service.CreateGuestCart()
+	assert.NoError(t, err)
+	assert.NoError(t, service.AddCartItem(cart, 1, 2))
+
+	loaded, err := service.GetGuestCart(token)
+	assert.NoError(t, err)
+	assert.Len(t, loaded.Items, 1)
+
+	assert.NoError(t, db.Model(&Cart{}).Where("id = ?", cart.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = service.GetGuestCart(token)
+	assert.ErrorIs(t, err, ErrCartNotFound)
+
+	purged, err := service.PurgeExpiredGuestCarts()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var items int64
+	db.Model(&CartItem{}).Count(&items)
+	assert.Zero(t, items)
+}
+
+func TestMergeGuestCart_SumQuantities(t *testing.T) {
+	service, db := setupTestService(t, Options{MergePolicy: MergeSumQuantities})
+	assert.NoError(t, service.AddItem(10, 1, 1))
+
+	guest, token, err := service.CreateGuestCart()
+	assert.NoError(t, err)
+	assert.NoError(t, service.AddCartItem(guest, 1, 2))
+	assert.NoError(t, service.AddCartItem(guest, 2, 4))
+
+	assert.NoError(t, service.MergeGuestCart(10, token))
+
+	cart, err := service.GetCartByUserID(10)
+	assert.NoError(t, err)
+	quantities := map[uint]int{}
+	for _, item := range cart.Items {
+		quantities[item.ProductID] = item.Quantity
+	}
+	assert.Equal(t, map[uint]int{1: 3, 2: 4}, quantities)
+
+	var carts int64
+	db.Model(&Cart{}).Count(&carts)
+	assert.Equal(t, int64(1), carts)
+}
+
+func TestMergeGuestCart_ClampsToStock(t *testing.T) {
+	service, db := setupTestService(t, Options{MergePolicy: MergeSumQuantities})
+	assert.NoError(t, service.AddItem(10, 1, 6))
+	assert.NoError(t, service.AddItem(10, 2, 3))
+
+	guest, token, err := service.CreateGuestCart()
+	assert.NoError(t, err)
+	assert.NoError(t, service.AddCartItem(guest, 1, 7))
+	assert.NoError(t, service.AddCartItem(guest, 2, 1))
+	assert.NoError(t, db.Model(&models.Product{}).Where("id = ?", 2).Update("is_active", false).Error)
+
+	assert.NoError(t, service.MergeGuestCart(10, token))
+
+	cart, err := service.GetCartByUserID(10)
+	assert.NoError(t, err)
+	quantities := map[uint]int{}
+	for _, item := range cart.Items {
+		quantities[item.ProductID] = item.Quantity
+	}
+	assert.Equal(t, map[uint]int{1: 10, 2: 3}, quantities)
+}
+
+func TestMergeGuestCart_KeepNewest(t *testing.T) {
+	service, db := setupTestService(t, Options{MergePolicy: MergeKeepNewest})
+	assert.NoError(t, service.AddItem(10, 1, 5))
+	assert.NoError(t, db.Model(&CartItem{}).Where("product_id = ?", 1).
+		Update("updated_at", time.Now().Add(-time.Hour)).Error)
+
+	guest, token, err := service.CreateGuestCart()
+	assert.NoError(t, err)
+	assert.NoError(t, service.AddCartItem(guest, 1, 2))
+
+	assert.NoError(t, service.MergeGuestCart(10, token))
+
+	cart, err := service.GetCartByUserID(10)
+	assert.NoError(t, err)
+	assert.Len(t, cart.Items, 1)
+	assert.Equal(t, 2, cart.Items[0].Quantity)
+}
+
+func TestAddCartItem_Validation(t *testing.T) {
+	service, db := setupTestService(t, Options{})
+	cart, err := service.GetCartByUserID(10)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, service.AddCartItem(cart, 1, 0), ErrInvalidQuantity)
+	assert.ErrorIs(t, service.AddCartItem(cart, 1, -3), ErrInvalidQuantity)
+	assert.ErrorIs(t, service.AddCartItem(cart, 99, 1), ErrProductNotFound)
+	assert.ErrorIs(t, service.AddCartItem(cart, 1, 11), ErrInsufficientStock)
+
+	assert.NoError(t, service.AddCartItem(cart, 1, 6))
+	assert.ErrorIs(t, service.AddCartItem(cart, 1, 5), ErrInsufficientStock)
+
+	assert.NoError(t, db.Model(&models.Product{}).Where("id = ?", 2).Update("is_active", false).Error)
+	assert.ErrorIs(t, service.AddCartItem(cart, 2, 1), ErrProductUnavailable)
+
+	cart, err = service.GetCartByUserID(10)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, service.UpdateCartItem(cart, cart.Items[0].ID, 0), ErrInvalidQuantity)
+	assert.ErrorIs(t, service.UpdateCartItem(cart, 999, 1), ErrItemNotFound)
+}
+
+func TestView_PricesAndFlagsItems(t *testing.T) {
+	service, db := setupTestService(t, Options{})
+	assert.NoError(t, service.AddItem(10, 1, 4))
+	assert.NoError(t, service.AddItem(10, 2, 2))
+
+	assert.NoError(t, db.Model(&models.Product{}).Where("id = ?", 1).
+		Updates(map[string]interface{}{"price": 45.5, "stock": 3}).Error)
+	assert.NoError(t, db.Model(&models.Product{}).Where("id = ?", 2).Update("is_active", false).Error)
+
+	cart, err := service.GetCartByUserID(10)
+	assert.NoError(t, err)
+	view, err := service.View(cart)
+	assert.NoError(t, err)
+
+	assert.True(t, view.HasIssues)
+	assert.Len(t, view.Items, 2)
+
+	keyboard := view.Items[0]
+	assert.True(t, keyboard.Available)
+	assert.Equal(t, 3, keyboard.Quantity)
+	assert.Equal(t, 4, keyboard.RequestedQuantity)
+	assert.Equal(t, 50.0, keyboard.PriceAtAdd)
+	assert.Equal(t, 45.5, keyboard.UnitPrice)
+	assert.Equal(t, 136.5, keyboard.LineTotal)
+	assert.ElementsMatch(t, []string{IssuePriceChanged, IssueQuantityAdjusted}, keyboard.Issues)
+
+	mouse := view.Items[1]
+	assert.False(t, mouse.Available)
+	assert.Equal(t, []string{IssueUnavailable}, mouse.Issues)
+
+	assert.Equal(t, 136.5, view.Subtotal)
+	assert.Equal(t, 3, view.ItemCount)
+
+	// Viewing the cart does not change it
+	var stored CartItem
+	assert.NoError(t, db.First(&stored, keyboard.ID).Error)
+	assert.Equal(t, 4, stored.Quantity)
+}
+
+func TestCartItem_KeepsPriceFromFirstAdd(t *testing.T) {
+	service, db := setupTestService(t, Options{})
+	assert.NoError(t, service.AddItem(10, 1, 1))
+	assert.NoError(t, db.Model(&models.Product{}).Where("id = ?", 1).Update("price", 55).Error)
+
+	cart, err := service.GetCartByUserID(10)
+	assert.NoError(t, err)
+	assert.NoError(t, service.AddCartItem(cart, 1, 1))
+	assert.NoError(t, service.UpdateCartItem(cart, cart.Items[0].ID, 3))
+
+	cart, err = service.GetCartByUserID(10)
+	assert.NoError(t, err)
+	view, err := service.View(cart)
+	assert.NoError(t, err)
+	if assert.Len(t, view.Items, 1) {
+		assert.Equal(t, 3, view.Items[0].Quantity)
+		assert.Equal(t, 50.0, view.Items[0].PriceAtAdd)
+		assert.Equal(t, 55.0, view.Items[0].UnitPrice)
+		assert.Equal(t, []string{IssuePriceChanged}, view.Items[0].Issues)
+	}
+}
@@ -0,0 +1,54 @@
+package cart
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCartToken = errors.New("invalid cart token")
+
+// TokenSigner issues and verifies guest cart tokens of the form
+// "<guest id>.<signature>", where the signature is an HMAC-SHA256 of the
+// guest ID.
+type TokenSigner struct {
+	secret []byte
+}
+
+func NewTokenSigner(secret string) *TokenSigner {
+	return &TokenSigner{secret: []byte(secret)}
+}
+
+// NewGuestID returns a random, URL-safe guest cart identifier.
+func NewGuestID() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *TokenSigner) Sign(guestID string) string {
+	return guestID + "." + s.signature(guestID)
+}
+
+// Verify checks the token signature and returns the guest ID it carries.
+func (s *TokenSigner) Verify(token string) (string, error) {
+	guestID, sig, ok := strings.Cut(token, ".")
+	if !ok || guestID == "" {
+		return "", ErrInvalidCartToken
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(guestID))) {
+		return "", ErrInvalidCartToken
+	}
+	return guestID, nil
+}
+
+func (s *TokenSigner) signature(guestID string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(guestID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,130 @@
+package cart
+
+import (
+	"math"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+// Item issues reported on a cart view.
+const (
+	IssueUnavailable      = "unavailable"
+	IssueOutOfStock       = "out_of_stock"
+	IssueQuantityAdjusted = "quantity_adjusted"
+	IssuePriceChanged     = "price_changed"
+)
+
+// View is a cart joined with live product data. Totals only include items
+// that can currently be purchased.
+type View struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Items     []ItemView `json:"items"`
+	ItemCount int        `json:"item_count"`
+	Subtotal  float64    `json:"subtotal"`
+	HasIssues bool       `json:"has_issues"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type ItemView struct {
+	ID        uint   `json:"id"`
+	ProductID uint   `json:"product_id"`
+	Name      string `json:"name"`
+	SKU       string `json:"sku"`
+	ImageURL  string `json:"image_url"`
+	Quantity  int    `json:"quantity"`
+	// RequestedQuantity is the stored quantity when Quantity was lowered
+	// to the available stock.
+	RequestedQuantity int      `json:"requested_quantity,omitempty"`
+	UnitPrice         float64  `json:"unit_price"`
+	PriceAtAdd        float64  `json:"price_at_add"`
+	LineTotal         float64  `json:"line_total"`
+	Available         bool     `json:"available"`
+	AvailableStock    int      `json:"available_stock"`
+	Issues            []string `json:"issues,omitempty"`
+}
+
+// View prices the cart against the current catalog. Quantities above the
+// available stock are clamped in the view only; the cart is not changed.
+func (s *Service) View(cart *Cart) (*View, error) {
+	view := &View{
+		ID:        cart.ID,
+		UserID:    cart.UserID,
+		ExpiresAt: cart.ExpiresAt,
+		Items:     make([]ItemView, 0, len(cart.Items)),
+		CreatedAt: cart.CreatedAt,
+		UpdatedAt: cart.UpdatedAt,
+	}
+	if len(cart.Items) == 0 {
+		return view, nil
+	}
+
+	productIDs := make([]uint, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	var products []models.Product
+	if err := s.db.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for _, item := range cart.Items {
+		line := ItemView{
+			ID:         item.ID,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			PriceAtAdd: item.UnitPrice,
+		}
+
+		product, ok := byID[item.ProductID]
+		switch {
+		case !ok || !product.IsActive:
+			line.Issues = append(line.Issues, IssueUnavailable)
+		case product.Stock <= 0:
+			line.Issues = append(line.Issues, IssueOutOfStock)
+		default:
+			line.Available = true
+		}
+
+		if ok {
+			line.Name = product.Name
+			line.SKU = product.SKU
+			line.ImageURL = product.ImageURL
+			line.UnitPrice = product.Price
+			line.AvailableStock = max(product.Stock, 0)
+			if item.UnitPrice != 0 && item.UnitPrice != product.Price {
+				line.Issues = append(line.Issues, IssuePriceChanged)
+			}
+		}
+
+		if line.Available && item.Quantity > product.Stock {
+			line.RequestedQuantity = item.Quantity
+			line.Quantity = product.Stock
+			line.Issues = append(line.Issues, IssueQuantityAdjusted)
+		}
+
+		if line.Available {
+			line.LineTotal = roundPrice(line.UnitPrice * float64(line.Quantity))
+			view.Subtotal += line.LineTotal
+			view.ItemCount += line.Quantity
+		}
+		if len(line.Issues) > 0 {
+			view.HasIssues = true
+		}
+		view.Items = append(view.Items, line)
+	}
+
+	view.Subtotal = roundPrice(view.Subtotal)
+	return view, nil
+}
+
+func roundPrice(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
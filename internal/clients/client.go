@@ -0,0 +1,258 @@
+// Package clients contains typed HTTP clients for calling the order,
+// payment, product and user services from one another.
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/oguzhan/e-commerce/pkg/resilience"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+)
+
+const (
+	RequestIDHeader = tracing.RequestIDHeader
+	// APIKeyHeader carries Options.APIKey, which services check on routes
+	// meant for other services only.
+	APIKeyHeader = "X-API-Key"
+)
+
+var (
+	ErrNotFound  = errors.New("resource not found")
+	ErrForbidden = errors.New("access denied")
+	ErrConflict  = errors.New("conflict")
+)
+
+// APIError is returned when a service answers with a non-2xx status. It
+// matches ErrNotFound, ErrForbidden and ErrConflict under errors.Is.
+type APIError struct {
+	Service    string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s service: %d %s", e.Service, e.StatusCode, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden || e.StatusCode == http.StatusUnauthorized
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+type Options struct {
+	// Timeout bounds each attempt. The caller's context still bounds the
+	// call as a whole.
+	Timeout time.Duration
+	// MaxRetries is the number of extra attempts made for idempotent calls
+	// after a network error or a 429/5xx answer.
+	MaxRetries int
+	// Backoff is the longest wait before the first retry; it doubles on
+	// every retry and the actual wait is jittered below it.
+	Backoff time.Duration
+	// Breaker and MaxConcurrent configure the circuit breaker and the
+	// bulkhead guarding calls to the service.
+	Breaker       resilience.BreakerOptions
+	MaxConcurrent int
+	// APIKey, when set, is sent with every call in APIKeyHeader.
+	APIKey string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Timeout:       5 * time.Second,
+		MaxRetries:    2,
+		Backoff:       100 * time.Millisecond,
+		Breaker:       resilience.DefaultBreakerOptions(),
+		MaxConcurrent: 50,
+	}
+}
+
+type caller struct {
+	authorization string
+	requestID     string
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context whose outgoing service calls carry the given
+// Authorization header and request ID.
+func WithCaller(ctx context.Context, authorization, requestID string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller{authorization: authorization, requestID: requestID})
+}
+
+// Propagate stores the incoming Authorization header and request ID on the
+// request context, so service calls made while handling the request act on
+// behalf of the same caller. The ID set by the tracing middleware is reused;
+// requests without an ID are given one.
+func Propagate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		requestID := tracing.RequestID(ctx)
+		if requestID == "" {
+			requestID = c.GetHeader(RequestIDHeader)
+		}
+		if requestID == "" {
+			requestID = tracing.NewRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+		ctx = tracing.ContextWithRequestID(ctx, requestID)
+		c.Request = c.Request.WithContext(WithCaller(ctx, c.GetHeader("Authorization"), requestID))
+		c.Next()
+	}
+}
+
+// client is the transport shared by the typed service clients.
+type client struct {
+	service string
+	baseURL string
+	http    *http.Client
+	options Options
+	retry   resilience.RetryPolicy
+}
+
+func newClient(service, baseURL string, options Options) *client {
+	defaults := DefaultOptions()
+	if options.Timeout <= 0 {
+		options.Timeout = defaults.Timeout
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = defaults.Backoff
+	}
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = defaults.MaxConcurrent
+	}
+	transport := resilience.NewTransport(service, http.DefaultTransport, resilience.Options{
+		Breaker:       options.Breaker,
+		MaxConcurrent: options.MaxConcurrent,
+	})
+	return &client{
+		service: service,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Transport: tracing.Transport(transport)},
+		options: options,
+		retry: resilience.RetryPolicy{
+			MaxAttempts: 1 + options.MaxRetries,
+			BaseDelay:   options.Backoff,
+			MaxDelay:    10 * options.Backoff,
+			Budget:      resilience.NewRetryBudget(0.1, 10),
+			OnRetry:     func(int, error) { metrics.RecordOutboundRetry(service) },
+		},
+	}
+}
+
+// do sends the request and decodes a JSON answer into out. Only idempotent
+// calls are retried, since a retried write may already have been applied.
+func (c *client) do(ctx context.Context, method, path string, body, out interface{}, idempotent bool) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	retry := c.retry
+	if !idempotent {
+		retry.MaxAttempts = 1
+	}
+	return retry.Do(ctx, func(ctx context.Context) error {
+		retryable, err := c.attempt(ctx, method, path, payload, out)
+		if err != nil && !retryable {
+			return resilience.Permanent(err)
+		}
+		return err
+	})
+}
+
+func (c *client) attempt(ctx context.Context, method, path string, payload []byte, out interface{}) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.options.APIKey != "" {
+		req.Header.Set(APIKeyHeader, c.options.APIKey)
+	}
+	if caller, ok := ctx.Value(callerKey{}).(caller); ok {
+		if caller.authorization != "" {
+			req.Header.Set("Authorization", caller.authorization)
+		}
+		if caller.requestID != "" {
+			req.Header.Set(RequestIDHeader, caller.requestID)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// Retrying is pointless while the breaker or bulkhead turns calls away.
+		retry := !errors.Is(err, resilience.ErrCircuitOpen) && !errors.Is(err, resilience.ErrBulkheadFull)
+		return retry, fmt.Errorf("%s service: %w", c.service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, &APIError{Service: c.service, StatusCode: resp.StatusCode, Message: errorMessage(resp)}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("%s service: decoding response: %w", c.service, err)
+	}
+	return false, nil
+}
+
+// errorMessage reads the detail of a problem+json body or the message of a
+// {"error": "..."} body, or falls back to the plain text body written by
+// http.Error.
+func errorMessage(resp *http.Response) string {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var body struct {
+		Detail string `json:"detail"`
+		Error  string `json:"error"`
+	}
+	if json.Unmarshal(raw, &body) == nil {
+		if body.Detail != "" {
+			return body.Detail
+		}
+		if body.Error != "" {
+			return body.Error
+		}
+	}
+	if message := strings.TrimSpace(string(raw)); message != "" {
+		return message
+	}
+	return http.StatusText(resp.StatusCode)
+}
@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testOptions = Options{Timeout: time.Second, MaxRetries: 2, Backoff: time.Millisecond}
+
+func TestGetProduct_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":7,"name":"Lamp","price":19.9,"stock":3}`))
+	}))
+	defer server.Close()
+
+	product, err := NewProductClient(server.URL, testOptions).GetProduct(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 19.9, product.Price)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestReserveStock_NotRetriedAndMapsConflict(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/products/1/reserve" {
+			http.Error(w, "insufficient stock", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	client := NewProductClient(server.URL, testOptions)
+
+	err := client.ReserveStock(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Contains(t, err.Error(), "insufficient stock")
+
+	err = client.ReleaseStock(context.Background(), 2, 2)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestAPIKeyIsSentWhenSet(t *testing.T) {
+	var apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get(APIKeyHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	options := testOptions
+	options.APIKey = "service-key"
+	assert.NoError(t, NewProductClient(server.URL, options).ReserveStock(context.Background(), 1, 1))
+	assert.Equal(t, "service-key", apiKey)
+
+	assert.NoError(t, NewProductClient(server.URL, testOptions).ReleaseStock(context.Background(), 1, 1))
+	assert.Equal(t, "", apiKey)
+}
+
+func TestPropagate_ForwardsAuthAndRequestID(t *testing.T) {
+	var authorization, requestID string
+	orders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		requestID = r.Header.Get(RequestIDHeader)
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusForbidden)
+	}))
+	defer orders.Close()
+	client := NewOrderClient(orders.URL, testOptions)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Propagate())
+	router.GET("/payments", func(c *gin.Context) {
+		_, err := client.GetOrder(c.Request.Context(), 5)
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.Contains(t, err.Error(), "unauthorized")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/payments", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "Bearer token", authorization)
+	assert.Equal(t, "req-123", requestID)
+	assert.Equal(t, "req-123", w.Header().Get(RequestIDHeader))
+}
+
+func TestTimeoutBoundsEachAttempt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewUserClient(server.URL, Options{Timeout: 20 * time.Millisecond, Backoff: time.Millisecond})
+	start := time.Now()
+	_, err := client.GetUser(context.Background(), 1)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,96 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+// FakeCatalog is an in-process stand-in for ProductClient, for tests and
+// for running a service without the product service.
+type FakeCatalog struct {
+	mu       sync.Mutex
+	products map[uint]Product
+}
+
+func NewFakeCatalog(products ...Product) *FakeCatalog {
+	f := &FakeCatalog{products: make(map[uint]Product)}
+	for _, product := range products {
+		f.products[product.ID] = product
+	}
+	return f
+}
+
+func (f *FakeCatalog) GetProduct(ctx context.Context, id uint) (*Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	product, ok := f.products[id]
+	if !ok {
+		return nil, &APIError{Service: "product", StatusCode: http.StatusNotFound, Message: "product not found"}
+	}
+	return &product, nil
+}
+
+func (f *FakeCatalog) ReserveStock(ctx context.Context, id uint, quantity int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	product, ok := f.products[id]
+	if !ok {
+		return &APIError{Service: "product", StatusCode: http.StatusNotFound, Message: "product not found"}
+	}
+	if product.Stock < quantity {
+		return &APIError{Service: "product", StatusCode: http.StatusConflict, Message: "insufficient stock"}
+	}
+	product.Stock -= quantity
+	f.products[id] = product
+	return nil
+}
+
+func (f *FakeCatalog) ReleaseStock(ctx context.Context, id uint, quantity int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	product, ok := f.products[id]
+	if !ok {
+		return &APIError{Service: "product", StatusCode: http.StatusNotFound, Message: "product not found"}
+	}
+	product.Stock += quantity
+	f.products[id] = product
+	return nil
+}
+
+// Stock returns the product's current stock, or -1 if it does not exist.
+func (f *FakeCatalog) Stock(id uint) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	product, ok := f.products[id]
+	if !ok {
+		return -1
+	}
+	return product.Stock
+}
+
+// FakeOrders is an in-process stand-in for OrderClient.
+type FakeOrders struct {
+	mu     sync.Mutex
+	orders map[uint]models.Order
+}
+
+func NewFakeOrders(orders ...models.Order) *FakeOrders {
+	f := &FakeOrders{orders: make(map[uint]models.Order)}
+	for _, order := range orders {
+		f.orders[order.ID] = order
+	}
+	return f
+}
+
+func (f *FakeOrders) GetOrder(ctx context.Context, id uint) (*models.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	order, ok := f.orders[id]
+	if !ok {
+		return nil, &APIError{Service: "order", StatusCode: http.StatusNotFound, Message: "order not found"}
+	}
+	return &order, nil
+}
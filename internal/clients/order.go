@@ -0,0 +1,27 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+type OrderClient struct {
+	client *client
+}
+
+func NewOrderClient(baseURL string, options Options) *OrderClient {
+	return &OrderClient{client: newClient("order", baseURL, options)}
+}
+
+// GetOrder fetches an order on behalf of the caller. The order service only
+// returns the caller's own orders and answers ErrForbidden otherwise.
+func (c *OrderClient) GetOrder(ctx context.Context, id uint) (*models.Order, error) {
+	var order models.Order
+	if err := c.client.do(ctx, http.MethodGet, fmt.Sprintf("/orders/%d", id), nil, &order, true); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
@@ -0,0 +1,33 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+type PaymentClient struct {
+	client *client
+}
+
+func NewPaymentClient(baseURL string, options Options) *PaymentClient {
+	return &PaymentClient{client: newClient("payment", baseURL, options)}
+}
+
+func (c *PaymentClient) GetPayment(ctx context.Context, id uint) (*models.Payment, error) {
+	var payment models.Payment
+	if err := c.client.do(ctx, http.MethodGet, fmt.Sprintf("/payments/%d", id), nil, &payment, true); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (c *PaymentClient) GetOrderPayments(ctx context.Context, orderID uint) ([]models.Payment, error) {
+	var payments []models.Payment
+	if err := c.client.do(ctx, http.MethodGet, fmt.Sprintf("/payments/order/%d", orderID), nil, &payments, true); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
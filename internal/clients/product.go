@@ -0,0 +1,46 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Product is a product as returned by the product service.
+type Product struct {
+	ID          uint    `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Stock       int     `json:"stock"`
+	Category    string  `json:"category"`
+}
+
+type ProductClient struct {
+	client *client
+}
+
+func NewProductClient(baseURL string, options Options) *ProductClient {
+	return &ProductClient{client: newClient("product", baseURL, options)}
+}
+
+func (c *ProductClient) GetProduct(ctx context.Context, id uint) (*Product, error) {
+	var product Product
+	if err := c.client.do(ctx, http.MethodGet, fmt.Sprintf("/products/%d", id), nil, &product, true); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// ReserveStock takes quantity units of the product out of stock. It fails
+// with ErrConflict when not enough stock is left.
+func (c *ProductClient) ReserveStock(ctx context.Context, id uint, quantity int) error {
+	body := map[string]int{"quantity": quantity}
+	return c.client.do(ctx, http.MethodPost, fmt.Sprintf("/products/%d/reserve", id), body, nil, false)
+}
+
+// ReleaseStock puts reserved units back into stock.
+func (c *ProductClient) ReleaseStock(ctx context.Context, id uint, quantity int) error {
+	body := map[string]int{"quantity": quantity}
+	return c.client.do(ctx, http.MethodPost, fmt.Sprintf("/products/%d/release", id), body, nil, false)
+}
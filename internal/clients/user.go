@@ -0,0 +1,31 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// User is a user as returned by the user service.
+type User struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+}
+
+type UserClient struct {
+	client *client
+}
+
+func NewUserClient(baseURL string, options Options) *UserClient {
+	return &UserClient{client: newClient("user", baseURL, options)}
+}
+
+func (c *UserClient) GetUser(ctx context.Context, id uint) (*User, error) {
+	var user User
+	if err := c.client.do(ctx, http.MethodGet, fmt.Sprintf("/users/%d", id), nil, &user, true); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,249 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Envelope is an outbox event as handed to subscribers.
+type Envelope struct {
+	ID         uint
+	Name       string
+	Payload    json.RawMessage
+	Attempt    int
+	OccurredAt time.Time
+}
+
+// Decode unmarshals the event payload into v.
+func (e Envelope) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Handler processes one event. Delivery is at least once, so handlers must
+// tolerate seeing the same event more than once.
+type Handler func(ctx context.Context, envelope Envelope) error
+
+type DispatcherOptions struct {
+	// BatchSize is the maximum number of events claimed per poll.
+	BatchSize int
+	// MaxAttempts is how many times an event is tried before it is moved to
+	// the dead-letter table.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; it doubles with each
+	// further attempt up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Lease is how long a claimed event is hidden from other dispatchers
+	// while it is being handled.
+	Lease time.Duration
+}
+
+func DefaultDispatcherOptions() DispatcherOptions {
+	return DispatcherOptions{
+		BatchSize:   100,
+		MaxAttempts: 8,
+		BaseBackoff: time.Second,
+		MaxBackoff:  10 * time.Minute,
+		Lease:       time.Minute,
+	}
+}
+
+// Dispatcher polls the outbox and delivers events to in-process subscribers.
+// Several dispatchers may share one database; each event is claimed by one
+// of them at a time.
+type Dispatcher struct {
+	db          *gorm.DB
+	options     DispatcherOptions
+	logger      *zap.Logger
+	now         func() time.Time
+	mu          sync.RWMutex
+	subscribers map[string][]Handler
+}
+
+func NewDispatcher(db *gorm.DB, options DispatcherOptions, logger *zap.Logger) *Dispatcher {
+	defaults := DefaultDispatcherOptions()
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaults.BatchSize
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaults.MaxAttempts
+	}
+	if options.BaseBackoff <= 0 {
+		options.BaseBackoff = defaults.BaseBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaults.MaxBackoff
+	}
+	if options.Lease <= 0 {
+		options.Lease = defaults.Lease
+	}
+	return &Dispatcher{
+		db:          db,
+		options:     options,
+		logger:      logger,
+		now:         time.Now,
+		subscribers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers handler for events with the given name.
+func (d *Dispatcher) Subscribe(name string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscribers[name] = append(d.subscribers[name], handler)
+}
+
+// On registers a handler that receives the decoded event of type T.
+func On[T Event](d *Dispatcher, handler func(ctx context.Context, event T) error) {
+	var zero T
+	d.Subscribe(zero.EventName(), func(ctx context.Context, envelope Envelope) error {
+		var event T
+		if err := envelope.Decode(&event); err != nil {
+			return err
+		}
+		return handler(ctx, event)
+	})
+}
+
+// Run polls the outbox every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.RunOnce(ctx); err != nil {
+				d.logger.Error("event dispatch failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RunOnce delivers the events that are currently due and returns how many
+// were handled successfully.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	now := d.now()
+
+	var due []OutboxEvent
+	if err := d.db.Where("next_attempt_at <= ?", now).
+		Order("id").
+		Limit(d.options.BatchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, event := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		claimed, err := d.claim(event, now)
+		if err != nil {
+			return delivered, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := d.deliver(ctx, event); err != nil {
+			if err := d.fail(event, err); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err := d.db.Delete(&OutboxEvent{}, event.ID).Error; err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// claim pushes the event's next attempt past the lease so that concurrent
+// dispatchers skip it. It reports false when another dispatcher got there
+// first.
+func (d *Dispatcher) claim(event OutboxEvent, now time.Time) (bool, error) {
+	result := d.db.Model(&OutboxEvent{}).
+		Where("id = ? AND next_attempt_at = ?", event.ID, event.NextAttemptAt).
+		Update("next_attempt_at", now.Add(d.options.Lease))
+	return result.RowsAffected == 1, result.Error
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, event OutboxEvent) (err error) {
+	d.mu.RLock()
+	handlers := d.subscribers[event.Name]
+	d.mu.RUnlock()
+
+	envelope := Envelope{
+		ID:         event.ID,
+		Name:       event.Name,
+		Payload:    json.RawMessage(event.Payload),
+		Attempt:    event.Attempts + 1,
+		OccurredAt: event.CreatedAt,
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("subscriber panicked: %v", r)
+		}
+	}()
+	for _, handler := range handlers {
+		if err := handler(ctx, envelope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail schedules a retry with exponential backoff, or moves the event to the
+// dead-letter table once it has used up its attempts.
+func (d *Dispatcher) fail(event OutboxEvent, cause error) error {
+	attempts := event.Attempts + 1
+	d.logger.Warn("event delivery failed",
+		zap.Uint("event_id", event.ID),
+		zap.String("event", event.Name),
+		zap.Int("attempt", attempts),
+		zap.Error(cause))
+
+	if attempts >= d.options.MaxAttempts {
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&DeadLetter{
+				OutboxID:   event.ID,
+				Name:       event.Name,
+				Payload:    event.Payload,
+				Attempts:   attempts,
+				LastError:  cause.Error(),
+				OccurredAt: event.CreatedAt,
+				FailedAt:   d.now(),
+			}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&OutboxEvent{}, event.ID).Error
+		})
+	}
+
+	return d.db.Model(&OutboxEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": d.now().Add(d.backoff(attempts)),
+		"last_error":      cause.Error(),
+	}).Error
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.options.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.options.MaxBackoff {
+			return d.options.MaxBackoff
+		}
+	}
+	return delay
+}
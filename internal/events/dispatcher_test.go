@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(append(Models(), &models.Product{})...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestPublish_RollsBackWithTransaction(t *testing.T) {
+	db := setupTestDB(t)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := Publish(tx, StockLow{ProductID: 1, Stock: 2}); err != nil {
+			return err
+		}
+		return errors.New("state change failed")
+	})
+	assert.Error(t, err)
+
+	var count int64
+	db.Model(&OutboxEvent{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return Publish(tx, StockLow{ProductID: 1, Stock: 2})
+	}))
+	db.Model(&OutboxEvent{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDispatcher_DeliversToTypedSubscribers(t *testing.T) {
+	db := setupTestDB(t)
+	dispatcher := NewDispatcher(db, DispatcherOptions{}, zap.NewNop())
+
+	var placed []OrderPlaced
+	On(dispatcher, func(ctx context.Context, e OrderPlaced) error {
+		placed = append(placed, e)
+		return nil
+	})
+
+	assert.NoError(t, Publish(db, OrderPlaced{OrderID: 7, TotalAmount: 42}, UserRegistered{UserID: 3}))
+
+	delivered, err := dispatcher.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, []OrderPlaced{{OrderID: 7, TotalAmount: 42}}, placed)
+
+	var remaining int64
+	db.Model(&OutboxEvent{}).Count(&remaining)
+	assert.Equal(t, int64(0), remaining)
+}
+
+func TestDispatcher_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	db := setupTestDB(t)
+	dispatcher := NewDispatcher(db, DispatcherOptions{
+		MaxAttempts: 3,
+		BaseBackoff: time.Minute,
+	}, zap.NewNop())
+	calls := 0
+	dispatcher.Subscribe(NameStockLow, func(ctx context.Context, envelope Envelope) error {
+		calls++
+		return errors.New("warehouse unavailable")
+	})
+	assert.NoError(t, Publish(db, StockLow{ProductID: 1, Stock: 2}))
+
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+
+	_, err := dispatcher.RunOnce(context.Background())
+	assert.NoError(t, err)
+
+	var event OutboxEvent
+	db.First(&event)
+	assert.Equal(t, 1, event.Attempts)
+	assert.Equal(t, "warehouse unavailable", event.LastError)
+	assert.WithinDuration(t, now.Add(time.Minute), event.NextAttemptAt, time.Second)
+
+	// Not due yet.
+	dispatcher.RunOnce(context.Background())
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(time.Minute)
+	dispatcher.RunOnce(context.Background())
+	db.First(&event)
+	assert.Equal(t, 2, event.Attempts)
+	assert.WithinDuration(t, now.Add(2*time.Minute), event.NextAttemptAt, time.Second)
+
+	now = now.Add(2 * time.Minute)
+	dispatcher.RunOnce(context.Background())
+	assert.Equal(t, 3, calls)
+
+	var outbox int64
+	db.Model(&OutboxEvent{}).Count(&outbox)
+	assert.Equal(t, int64(0), outbox)
+
+	var dead DeadLetter
+	assert.NoError(t, db.First(&dead).Error)
+	assert.Equal(t, NameStockLow, dead.Name)
+	assert.Equal(t, 3, dead.Attempts)
+}
+
+func TestDispatcher_RecoversPanickingSubscriber(t *testing.T) {
+	db := setupTestDB(t)
+	dispatcher := NewDispatcher(db, DispatcherOptions{}, zap.NewNop())
+	dispatcher.Subscribe(NameUserRegistered, func(ctx context.Context, envelope Envelope) error {
+		panic("boom")
+	})
+	assert.NoError(t, Publish(db, UserRegistered{UserID: 1}))
+
+	delivered, err := dispatcher.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	var event OutboxEvent
+	db.First(&event)
+	assert.Contains(t, event.LastError, "boom")
+}
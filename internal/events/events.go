@@ -0,0 +1,77 @@
+package events
+
+import (
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+// Event is a domain event. Name identifies the event type in the outbox and
+// is what subscribers register for.
+type Event interface {
+	EventName() string
+}
+
+const (
+	NameOrderPlaced        = "order.placed"
+	NameOrderStatusChanged = "order.status_changed"
+	NamePaymentCaptured    = "payment.captured"
+	NamePaymentRefunded    = "payment.refunded"
+	NameStockLow           = "product.stock_low"
+	NameUserRegistered     = "user.registered"
+)
+
+type OrderPlaced struct {
+	OrderID     uint    `json:"order_id"`
+	UserID      uint    `json:"user_id,omitempty"`
+	GuestEmail  string  `json:"guest_email,omitempty"`
+	TotalAmount float64 `json:"total_amount"`
+	ItemCount   int     `json:"item_count"`
+}
+
+func (OrderPlaced) EventName() string { return NameOrderPlaced }
+
+type OrderStatusChanged struct {
+	OrderID uint               `json:"order_id"`
+	UserID  uint               `json:"user_id,omitempty"`
+	From    models.OrderStatus `json:"from"`
+	To      models.OrderStatus `json:"to"`
+}
+
+func (OrderStatusChanged) EventName() string { return NameOrderStatusChanged }
+
+type PaymentCaptured struct {
+	PaymentID     uint    `json:"payment_id"`
+	OrderID       uint    `json:"order_id"`
+	UserID        uint    `json:"user_id"`
+	Amount        float64 `json:"amount"`
+	PaymentMethod string  `json:"payment_method"`
+	TransactionID string  `json:"transaction_id"`
+}
+
+func (PaymentCaptured) EventName() string { return NamePaymentCaptured }
+
+type PaymentRefunded struct {
+	PaymentID     uint    `json:"payment_id"`
+	OrderID       uint    `json:"order_id"`
+	UserID        uint    `json:"user_id"`
+	Amount        float64 `json:"amount"`
+	TransactionID string  `json:"transaction_id"`
+}
+
+func (PaymentRefunded) EventName() string { return NamePaymentRefunded }
+
+type StockLow struct {
+	ProductID uint   `json:"product_id"`
+	SKU       string `json:"sku"`
+	Stock     int    `json:"stock"`
+	Threshold int    `json:"threshold"`
+}
+
+func (StockLow) EventName() string { return NameStockLow }
+
+type UserRegistered struct {
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+}
+
+func (UserRegistered) EventName() string { return NameUserRegistered }
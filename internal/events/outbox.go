@@ -0,0 +1,63 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxEvent is an event waiting to be delivered. Rows are written in the
+// same transaction as the state change they describe and deleted once every
+// subscriber has handled them.
+type OutboxEvent struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Name          string    `json:"name" gorm:"not null;index"`
+	Payload       string    `json:"payload" gorm:"type:text;not null"`
+	Attempts      int       `json:"attempts" gorm:"default:0"`
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"index"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// DeadLetter is an event that still failed after the maximum number of
+// delivery attempts.
+type DeadLetter struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OutboxID   uint      `json:"outbox_id" gorm:"index"`
+	Name       string    `json:"name" gorm:"not null;index"`
+	Payload    string    `json:"payload" gorm:"type:text;not null"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error"`
+	OccurredAt time.Time `json:"occurred_at"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// Models returns the outbox tables for schema migration.
+func Models() []interface{} {
+	return []interface{}{&OutboxEvent{}, &DeadLetter{}}
+}
+
+// Publish writes events to the outbox using tx. Call it inside the
+// transaction that performs the state change so the events are stored if and
+// only if the change commits.
+func Publish(tx *gorm.DB, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	now := time.Now()
+	rows := make([]OutboxEvent, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, OutboxEvent{
+			Name:          event.EventName(),
+			Payload:       string(payload),
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	return tx.Create(&rows).Error
+}
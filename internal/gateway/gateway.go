@@ -0,0 +1,257 @@
+// Package gateway reverse-proxies API routes to the microservices when
+// cmd/api runs as an edge gateway.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+	"go.uber.org/zap"
+)
+
+const (
+	// UserIDHeader carries the user ID verified by the gateway. Any value
+	// sent by the client is removed before the request is forwarded.
+	UserIDHeader = "X-User-ID"
+
+	DefaultTimeout = 30 * time.Second
+)
+
+var (
+	ErrRouteNotFound       = apperrors.New(http.StatusNotFound, "route_not_found", "route not found")
+	ErrAuthRequired        = apperrors.New(http.StatusUnauthorized, "missing_authorization", "authorization header is required")
+	ErrUpstreamTimeout     = apperrors.New(http.StatusGatewayTimeout, "upstream_timeout", "upstream timed out")
+	ErrUpstreamUnavailable = apperrors.New(http.StatusBadGateway, "upstream_unavailable", "upstream unavailable")
+)
+
+// Route maps a public path prefix to a service.
+type Route struct {
+	// Prefix is matched against the request path on segment boundaries.
+	Prefix string
+	// Target replaces Prefix in the forwarded path. A request for Prefix
+	// itself is forwarded to Target exactly, so services that register
+	// their collection routes with a trailing slash can keep doing so.
+	Target string
+	Pool   *Pool
+	// Timeout bounds the whole upstream exchange. Zero means DefaultTimeout.
+	Timeout time.Duration
+	// PublicMethods lists methods that may be called without a token, such
+	// as GET for the product catalogue.
+	PublicMethods []string
+	// Internal lists path suffixes, such as "/reserve", that only other
+	// services may call. The gateway answers them as unknown routes.
+	Internal []string
+}
+
+func (r *Route) matches(path string) bool {
+	if !strings.HasPrefix(path, r.Prefix) {
+		return false
+	}
+	rest := path[len(r.Prefix):]
+	return rest == "" || rest[0] == '/'
+}
+
+func (r *Route) rewrite(path string) string {
+	rest := path[len(r.Prefix):]
+	if rest == "" {
+		return r.Target
+	}
+	return strings.TrimSuffix(r.Target, "/") + rest
+}
+
+func (r *Route) internal(p string) bool {
+	p = path.Clean(p)
+	for _, suffix := range r.Internal {
+		if strings.HasSuffix(p, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Route) public(method string) bool {
+	for _, m := range r.PublicMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator verifies an Authorization header and returns the user ID.
+type Authenticator func(authHeader string) (uint, error)
+
+type Gateway struct {
+	routes       []Route
+	authenticate Authenticator
+	proxy        *httputil.ReverseProxy
+	logger       *zap.Logger
+}
+
+type proxyTargetKey struct{}
+
+type proxyTarget struct {
+	route    *Route
+	upstream *upstream
+	userID   uint
+}
+
+func New(routes []Route, authenticate Authenticator, logger *zap.Logger) *Gateway {
+	g := &Gateway{routes: routes, authenticate: authenticate, logger: logger}
+	g.proxy = &httputil.ReverseProxy{
+		Rewrite:        g.rewrite,
+		Transport:      tracing.Transport(http.DefaultTransport),
+		ModifyResponse: stripCORSHeaders,
+		ErrorHandler:   g.proxyError,
+	}
+	return g
+}
+
+// Handler proxies requests that match a route and answers 404 otherwise. It
+// is meant to be installed with router.NoRoute, so routes served locally
+// always take precedence.
+func (g *Gateway) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := g.match(c.Request.URL.Path)
+		if route == nil || route.internal(c.Request.URL.Path) {
+			middleware.WriteError(c, ErrRouteNotFound)
+			return
+		}
+
+		var userID uint
+		if header := c.GetHeader("Authorization"); header != "" {
+			id, err := g.authenticate(header)
+			if err != nil {
+				if !apperrors.IsAppError(err) {
+					err = apperrors.ErrInvalidToken.Wrap(err)
+				}
+				middleware.WriteError(c, err)
+				return
+			}
+			userID = id
+			c.Set("user_id", userID)
+		} else if !route.public(c.Request.Method) {
+			middleware.WriteError(c, ErrAuthRequired)
+			return
+		}
+
+		instance, err := route.Pool.pick()
+		if err != nil {
+			middleware.WriteError(c, err)
+			return
+		}
+
+		timeout := route.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		ctx = context.WithValue(ctx, proxyTargetKey{}, &proxyTarget{route: route, upstream: instance, userID: userID})
+
+		g.proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
+
+func (g *Gateway) match(path string) *Route {
+	for i := range g.routes {
+		if g.routes[i].matches(path) {
+			return &g.routes[i]
+		}
+	}
+	return nil
+}
+
+func (g *Gateway) rewrite(pr *httputil.ProxyRequest) {
+	target := pr.In.Context().Value(proxyTargetKey{}).(*proxyTarget)
+
+	pr.Out.URL.Path = target.route.rewrite(pr.In.URL.Path)
+	pr.Out.URL.RawPath = ""
+	pr.SetURL(target.upstream.url)
+	pr.SetXForwarded()
+
+	pr.Out.Header.Del(UserIDHeader)
+	if target.userID != 0 {
+		pr.Out.Header.Set(UserIDHeader, strconv.FormatUint(uint64(target.userID), 10))
+	}
+}
+
+// stripCORSHeaders drops CORS headers set by a service, since the gateway
+// answers CORS itself and duplicate headers make browsers reject the
+// response.
+func stripCORSHeaders(resp *http.Response) error {
+	for name := range resp.Header {
+		if strings.HasPrefix(name, "Access-Control-") {
+			resp.Header.Del(name)
+		}
+	}
+	return nil
+}
+
+// proxyError answers 504 when the route timeout expired and 502 otherwise.
+// An instance that could not be reached is taken out of rotation until its
+// next successful health check.
+func (g *Gateway) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	target := r.Context().Value(proxyTargetKey{}).(*proxyTarget)
+
+	problem := ErrUpstreamUnavailable
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		problem = ErrUpstreamTimeout
+	case errors.Is(err, context.Canceled):
+		// The client went away; nobody is left to read the answer.
+		return
+	default:
+		target.upstream.healthy.Store(false)
+	}
+
+	g.logger.Warn("proxy request failed",
+		zap.String("upstream", target.route.Pool.name),
+		zap.String("url", target.upstream.url.String()),
+		zap.Error(err))
+
+	apperrors.WriteProblem(w, r, problem)
+}
+
+// Check fails while a route has no healthy instance, so the gateway can
+// report itself unready when it cannot serve part of the API.
+func (g *Gateway) Check(ctx context.Context) error {
+	for _, route := range g.routes {
+		if route.Pool.Healthy() == 0 {
+			return fmt.Errorf("no healthy %s instance", route.Pool.name)
+		}
+	}
+	return nil
+}
+
+// RunHealthChecks probes every pool's instances each interval until ctx is
+// cancelled.
+func (g *Gateway) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	pools := make(map[*Pool]bool)
+	for _, route := range g.routes {
+		pools[route.Pool] = true
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for pool := range pools {
+				pool.CheckHealth(ctx)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testAuth(header string) (uint, error) {
+	if header == "Bearer good" {
+		return 42, nil
+	}
+	return 0, errors.New("invalid token")
+}
+
+func newTestRouter(routes ...Route) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/local", func(c *gin.Context) { c.String(http.StatusOK, "local") })
+	router.NoRoute(New(routes, testAuth, zap.NewNop()).Handler())
+	return router
+}
+
+func serve(router *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set(UserIDHeader, "1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGateway_RewritesPathAndForwardsIdentity(t *testing.T) {
+	var path, userID string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.RequestURI()
+		userID = r.Header.Get(UserIDHeader)
+		w.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+	pool, err := NewPool("orders", upstream.URL, "/health")
+	assert.NoError(t, err)
+	router := newTestRouter(Route{Prefix: "/api/v1/orders", Target: "/orders/", Pool: pool})
+
+	w := serve(router, http.MethodGet, "/api/v1/orders/7?expand=items", "good")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/orders/7?expand=items", path)
+	assert.Equal(t, "42", userID)
+
+	serve(router, http.MethodGet, "/api/v1/orders", "good")
+	assert.Equal(t, "/orders/", path)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(router, http.MethodGet, "/api/v1/orders", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(router, http.MethodGet, "/api/v1/orders", "bad").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodGet, "/api/v1/ordersx", "good").Code)
+	assert.Equal(t, "local", serve(router, http.MethodGet, "/api/v1/local", "").Body.String())
+}
+
+func TestGateway_PublicMethodsStripSpoofedIdentity(t *testing.T) {
+	userID := "unset"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = r.Header.Get(UserIDHeader)
+	}))
+	defer upstream.Close()
+	pool, _ := NewPool("products", upstream.URL, "/health")
+	router := newTestRouter(Route{Prefix: "/api/v1/products", Target: "/products", Pool: pool, PublicMethods: []string{http.MethodGet}})
+
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/api/v1/products", "").Code)
+	assert.Equal(t, "", userID)
+	assert.Equal(t, http.StatusUnauthorized, serve(router, http.MethodPost, "/api/v1/products", "").Code)
+}
+
+func TestGateway_RefusesInternalPaths(t *testing.T) {
+	var calls int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer upstream.Close()
+	pool, _ := NewPool("products", upstream.URL, "/health")
+	router := newTestRouter(Route{Prefix: "/api/v1/products", Target: "/products", Pool: pool,
+		Internal: []string{"/reserve", "/release"}})
+
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodPost, "/api/v1/products/1/reserve", "good").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodPost, "/api/v1/products/1/release/", "good").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodPost, "/api/v1/products/1/x/../reserve", "good").Code)
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodPatch, "/api/v1/products/1/stock", "good").Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestGateway_FailsOverAndRecoversWithHealthChecks(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer healthy.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	downURL := down.URL
+	down.Close()
+
+	pool, err := NewPool("users", downURL+","+healthy.URL, "/health")
+	assert.NoError(t, err)
+	router := newTestRouter(Route{Prefix: "/api/v1/users", Target: "/users", Pool: pool})
+
+	// The first request lands on the dead instance and takes it out of rotation.
+	assert.Equal(t, http.StatusBadGateway, serve(router, http.MethodGet, "/api/v1/users/1", "good").Code)
+	assert.Equal(t, 1, pool.Healthy())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/api/v1/users/1", "good").Code)
+	}
+
+	pool.upstreams[0].healthy.Store(true)
+	pool.CheckHealth(context.Background())
+	assert.Equal(t, 1, pool.Healthy())
+
+	pool.upstreams[1].healthy.Store(false)
+	pool.upstreams[0].healthy.Store(false)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(router, http.MethodGet, "/api/v1/users/1", "good").Code)
+	pool.CheckHealth(context.Background())
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/api/v1/users/1", "good").Code)
+}
+
+func TestGateway_RouteTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	pool, _ := NewPool("payments", slow.URL, "/health")
+	router := newTestRouter(Route{Prefix: "/api/v1/payments", Target: "/payments/", Pool: pool, Timeout: 20 * time.Millisecond})
+
+	w := serve(router, http.MethodGet, "/api/v1/payments/1", "good")
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, 1, pool.Healthy())
+}
+
+func TestGateway_CheckFailsWithoutHealthyInstances(t *testing.T) {
+	pool, _ := NewPool("orders", "http://orders.internal", "/readyz")
+	gw := New([]Route{{Prefix: "/api/v1/orders", Target: "/orders", Pool: pool}}, testAuth, zap.NewNop())
+	assert.NoError(t, gw.Check(context.Background()))
+
+	pool.upstreams[0].healthy.Store(false)
+	assert.EqualError(t, gw.Check(context.Background()), "no healthy orders instance")
+}
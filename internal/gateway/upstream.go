@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+)
+
+var ErrNoHealthyUpstream = apperrors.New(http.StatusServiceUnavailable, "no_healthy_upstream", "no healthy upstream")
+
+// upstream is one instance of a service.
+type upstream struct {
+	url     *url.URL
+	healthy atomic.Bool
+}
+
+// Pool balances requests over the instances of one service. Instances are
+// taken out of rotation when a proxied request fails to reach them or a
+// health check fails, and put back once a health check succeeds.
+type Pool struct {
+	name       string
+	upstreams  []*upstream
+	next       atomic.Uint32
+	healthPath string
+	client     *http.Client
+}
+
+// NewPool creates a pool from a comma separated list of base URLs.
+func NewPool(name, urls, healthPath string) (*Pool, error) {
+	pool := &Pool{
+		name:       name,
+		healthPath: healthPath,
+		client:     &http.Client{Timeout: 2 * time.Second},
+	}
+	for _, raw := range strings.Split(urls, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%s upstream: invalid URL %q", name, raw)
+		}
+		instance := &upstream{url: u}
+		instance.healthy.Store(true)
+		pool.upstreams = append(pool.upstreams, instance)
+	}
+	if len(pool.upstreams) == 0 {
+		return nil, fmt.Errorf("%s upstream: no URLs configured", name)
+	}
+	return pool, nil
+}
+
+// pick returns the next healthy instance in round-robin order.
+func (p *Pool) pick() (*upstream, error) {
+	n := len(p.upstreams)
+	start := int(p.next.Add(1) - 1)
+	for i := 0; i < n; i++ {
+		instance := p.upstreams[(start+i)%n]
+		if instance.healthy.Load() {
+			return instance, nil
+		}
+	}
+	return nil, ErrNoHealthyUpstream
+}
+
+// Healthy reports how many instances are currently in rotation.
+func (p *Pool) Healthy() int {
+	count := 0
+	for _, instance := range p.upstreams {
+		if instance.healthy.Load() {
+			count++
+		}
+	}
+	return count
+}
+
+// CheckHealth probes every instance once and updates its state.
+func (p *Pool) CheckHealth(ctx context.Context) {
+	for _, instance := range p.upstreams {
+		instance.healthy.Store(p.probe(ctx, instance))
+	}
+}
+
+func (p *Pool) probe(ctx context.Context, instance *upstream) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, instance.url.JoinPath(p.healthPath).String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode <= 299
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/database"
+)
+
+// ConsistencyHeader set to "strong" makes a read-only request read from the
+// primary, for clients that must see a write they just made.
+const ConsistencyHeader = "X-Consistency"
+
+// StickToPrimary sends every query of a request to the primary database
+// when the request may write, so it reads back what it wrote, or when the
+// client asks for it with ConsistencyHeader. Other requests may read from
+// a replica where the service allows it.
+func StickToPrimary() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if !strings.EqualFold(c.GetHeader(ConsistencyHeader), "strong") {
+				c.Next()
+				return
+			}
+		}
+		c.Request = c.Request.WithContext(database.WithPrimary(c.Request.Context()))
+		c.Next()
+	}
+}
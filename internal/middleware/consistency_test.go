@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStickToPrimary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var primary bool
+	router := gin.New()
+	router.Use(StickToPrimary())
+	router.Any("/orders", func(c *gin.Context) {
+		primary = database.UsesPrimary(c.Request.Context())
+	})
+
+	tests := []struct {
+		method      string
+		consistency string
+		want        bool
+	}{
+		{http.MethodGet, "", false},
+		{http.MethodGet, "strong", true},
+		{http.MethodGet, "eventual", false},
+		{http.MethodPost, "", true},
+		{http.MethodDelete, "", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/orders", nil)
+		if tt.consistency != "" {
+			req.Header.Set(ConsistencyHeader, tt.consistency)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, tt.want, primary, "%s with %q", tt.method, tt.consistency)
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const dbStartKey = "metrics:start"
+
+// DBMetrics is a gorm plugin that records every query with
+// metrics.RecordDBOperation, logs queries slower than SlowThreshold and exports the
+// connection pool stats of the underlying sql.DB.
+type DBMetrics struct {
+	Logger *zap.Logger
+	// SlowThreshold of zero disables slow query logging.
+	SlowThreshold time.Duration
+	// LogValues writes bind values into slow query logs. By default they
+	// are left as placeholders since they may hold personal data.
+	LogValues bool
+	// DBName labels the pool stats; it defaults to the dialect name.
+	DBName string
+}
+
+func (DBMetrics) Name() string {
+	return "metrics"
+}
+
+func (m DBMetrics) Initialize(db *gorm.DB) error {
+	if m.Logger == nil {
+		m.Logger = zap.NewNop()
+	}
+	if err := m.registerPoolStats(db); err != nil {
+		return err
+	}
+
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", startDBOperation),
+		cb.Create().After("gorm:create").Register("metrics:after_create", m.endDBOperation("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", startDBOperation),
+		cb.Query().After("gorm:query").Register("metrics:after_query", m.endDBOperation("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", startDBOperation),
+		cb.Update().After("gorm:update").Register("metrics:after_update", m.endDBOperation("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", startDBOperation),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", m.endDBOperation("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", startDBOperation),
+		cb.Row().After("gorm:row").Register("metrics:after_row", m.endDBOperation("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", startDBOperation),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", m.endDBOperation("raw")),
+	)
+}
+
+// registerPoolStats exports open and in-use connections, waits and wait
+// time. A collector left over for the same database name is replaced.
+func (m DBMetrics) registerPoolStats(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	name := m.DBName
+	if name == "" {
+		name = db.Dialector.Name()
+	}
+
+	return metrics.Register(collectors.NewDBStatsCollector(sqlDB, name))
+}
+
+func startDBOperation(db *gorm.DB) {
+	db.InstanceSet(dbStartKey, time.Now())
+}
+
+func (m DBMetrics) endDBOperation(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(dbStartKey)
+		if !ok {
+			return
+		}
+		duration := time.Since(value.(time.Time))
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		metrics.RecordDBOperation(operation, table, duration, err)
+
+		if m.SlowThreshold > 0 && duration >= m.SlowThreshold {
+			fields := append([]zap.Field{
+				zap.String("operation", operation),
+				zap.String("table", table),
+				zap.Duration("duration", duration),
+				zap.String("sql", m.statement(db)),
+				zap.Int64("rows", db.Statement.RowsAffected),
+				zap.Error(err),
+			}, tracing.Fields(db.Statement.Context)...)
+			m.Logger.Warn("slow query", fields...)
+		}
+	}
+}
+
+func (m DBMetrics) statement(db *gorm.DB) string {
+	sql := db.Statement.SQL.String()
+	if m.LogValues {
+		return db.Dialector.Explain(sql, db.Statement.Vars...)
+	}
+	return sql
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type gadget struct {
+	ID    uint
+	Name  string
+	Owner string
+}
+
+func openMetricsDB(t *testing.T, plugin DBMetrics) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Use(plugin))
+	assert.NoError(t, db.AutoMigrate(&gadget{}))
+	return db
+}
+
+func TestDBMetrics_RecordsOperations(t *testing.T) {
+	db := openMetricsDB(t, DBMetrics{DBName: "gadgets"})
+	created := testutil.ToFloat64(metrics.DBOperationsTotal.WithLabelValues("create", "gadgets", "ok"))
+	queried := testutil.ToFloat64(metrics.DBOperationsTotal.WithLabelValues("query", "gadgets", "ok"))
+	failed := testutil.ToFloat64(metrics.DBOperationsTotal.WithLabelValues("raw", "unknown", "error"))
+
+	assert.NoError(t, db.Create(&gadget{Name: "a"}).Error)
+	var found gadget
+	assert.True(t, errors.Is(db.First(&found, 99).Error, gorm.ErrRecordNotFound))
+	assert.Error(t, db.Exec("INSERT INTO missing VALUES (1)").Error)
+
+	assert.Equal(t, created+1, testutil.ToFloat64(metrics.DBOperationsTotal.WithLabelValues("create", "gadgets", "ok")))
+	assert.Equal(t, queried+1, testutil.ToFloat64(metrics.DBOperationsTotal.WithLabelValues("query", "gadgets", "ok")))
+	assert.Equal(t, failed+1, testutil.ToFloat64(metrics.DBOperationsTotal.WithLabelValues("raw", "unknown", "error")))
+}
+
+func TestDBMetrics_LogsSlowQueries(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	db := openMetricsDB(t, DBMetrics{Logger: zap.New(core), SlowThreshold: 1, DBName: "gadgets"})
+
+	assert.NoError(t, db.Create(&gadget{Name: "a", Owner: "jane@example.com"}).Error)
+	entries := logs.FilterMessage("slow query").All()
+	assert.NotEmpty(t, entries)
+	sql := entries[len(entries)-1].ContextMap()["sql"].(string)
+	assert.Contains(t, sql, "INSERT INTO `gadgets`")
+	assert.NotContains(t, sql, "jane@example.com")
+
+	logs.TakeAll()
+	db = openMetricsDB(t, DBMetrics{Logger: zap.New(core), SlowThreshold: 1, LogValues: true, DBName: "gadgets"})
+	assert.NoError(t, db.Create(&gadget{Name: "a", Owner: "jane@example.com"}).Error)
+	entries = logs.FilterMessage("slow query").All()
+	assert.Contains(t, entries[len(entries)-1].ContextMap()["sql"], "jane@example.com")
+}
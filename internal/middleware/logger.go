@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -27,7 +28,7 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 		errorMessage := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
 		// Create structured log entry
-		logger.Info("incoming request",
+		fields := []zap.Field{
 			zap.String("path", path),
 			zap.String("query", query),
 			zap.String("ip", ip),
@@ -36,7 +37,8 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 			zap.Int("status", statusCode),
 			zap.Duration("latency", latency),
 			zap.String("error", errorMessage),
-		)
+		}
+		logger.Info("incoming request", append(fields, tracing.Fields(c.Request.Context())...)...)
 	}
 }
 
@@ -1,68 +1,151 @@
 package middleware
 
 import (
-	"net/http"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/ulule/limiter/v3"
-	"github.com/ulule/limiter/v3/drivers/store/memory"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	sharedmiddleware "github.com/oguzhan/e-commerce/pkg/middleware"
+	"github.com/oguzhan/e-commerce/pkg/ratelimit"
+	"go.uber.org/zap"
 )
 
+// RoutePolicy applies a policy to requests whose path starts with Path on a
+// segment boundary. An empty Method matches every method.
+type RoutePolicy struct {
+	Method string
+	Path   string
+	Policy ratelimit.Policy
+}
+
 // RateLimitConfig holds the configuration for rate limiting
 type RateLimitConfig struct {
-	Rate      int           // Number of requests allowed
-	Period    time.Duration // Time period for the rate limit
-	StoreType string        // Type of store to use (memory, redis, etc.)
+	// Default applies to requests that match no route policy.
+	Default ratelimit.Policy
+	// Routes are checked most specific path first.
+	Routes []RoutePolicy
+	Store  ratelimit.Store
+	// AllowList holds IPs and CIDRs of internal callers that are never
+	// limited.
+	AllowList []string
+	// UserID identifies authenticated callers, who are limited per user
+	// instead of per IP.
+	UserID func(c *gin.Context) (uint, bool)
+	Logger *zap.Logger
 }
 
 // DefaultConfig returns a default rate limit configuration
 func DefaultConfig() *RateLimitConfig {
 	return &RateLimitConfig{
-		Rate:      100,         // 100 requests
-		Period:    time.Minute, // per minute
-		StoreType: "memory",    // using in-memory store
+		Default: ratelimit.Policy{Name: "default", Limit: 100, Window: time.Minute},
+		Store:   ratelimit.NewMemoryStore(),
+	}
+}
+
+// ParseRoutePolicies parses route policies keyed by "METHOD /path" or
+// "/path", with values such as "5/1m".
+func ParseRoutePolicies(routes map[string]string) ([]RoutePolicy, error) {
+	policies := make([]RoutePolicy, 0, len(routes))
+	for route, text := range routes {
+		method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok {
+			method, path = "", route
+		}
+		policy, err := ratelimit.ParsePolicy(route, text)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, RoutePolicy{
+			Method: strings.ToUpper(method),
+			Path:   strings.TrimSpace(path),
+			Policy: policy,
+		})
 	}
+	return policies, nil
 }
 
 // RateLimit creates a new rate limiting middleware
 func RateLimit(config *RateLimitConfig) gin.HandlerFunc {
-	// Create a new rate limiter
-	store := memory.NewStore()
-	rate := limiter.Rate{
-		Period: config.Period,
-		Limit:  int64(config.Rate),
+	allowList := parseAllowList(config.AllowList)
+	logger := config.Logger
+	if logger == nil {
+		logger = zap.NewNop()
 	}
-	limiter := limiter.New(store, rate)
 
 	return func(c *gin.Context) {
-		// Get the IP address of the client
-		ip := c.ClientIP()
+		ip := net.ParseIP(c.ClientIP())
+		for _, network := range allowList {
+			if ip != nil && network.Contains(ip) {
+				c.Next()
+				return
+			}
+		}
 
-		// Get the current rate limit for the IP
-		context, err := limiter.Get(c.Request.Context(), ip)
+		policy := config.policyFor(c.Request.Method, c.Request.URL.Path)
+		key := policy.Name + ":ip:" + c.ClientIP()
+		if config.UserID != nil {
+			if userID, ok := config.UserID(c); ok {
+				key = policy.Name + ":user:" + strconv.FormatUint(uint64(userID), 10)
+			}
+		}
+
+		result, err := config.Store.Allow(c.Request.Context(), key, policy)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "rate limit error",
-			})
-			c.Abort()
+			// Failing open keeps the API up when the shared store is down.
+			logger.Warn("rate limit store unavailable", zap.Error(err))
+			c.Next()
 			return
 		}
 
-		// Set rate limit headers
-		c.Header("X-RateLimit-Limit", string(context.Limit))
-		c.Header("X-RateLimit-Remaining", string(context.Remaining))
-		c.Header("X-RateLimit-Reset", string(context.Reset))
-
-		// Check if the rate limit is exceeded
-		if context.Reached {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "rate limit exceeded",
-			})
-			c.Abort()
+		ratelimit.WriteHeaders(c.Writer.Header(), policy, result)
+		if !result.Allowed {
+			sharedmiddleware.WriteError(c, apperrors.ErrRateLimit)
 			return
 		}
 
 		c.Next()
 	}
 }
+
+func (config *RateLimitConfig) policyFor(method, path string) ratelimit.Policy {
+	var best *RoutePolicy
+	for i := range config.Routes {
+		route := &config.Routes[i]
+		if route.Method != "" && route.Method != method {
+			continue
+		}
+		if path != route.Path && !strings.HasPrefix(path, strings.TrimSuffix(route.Path, "/")+"/") {
+			continue
+		}
+		if best == nil || len(route.Path) > len(best.Path) || (len(route.Path) == len(best.Path) && route.Method != "") {
+			best = route
+		}
+	}
+	if best == nil {
+		return config.Default
+	}
+	return best.Policy
+}
+
+func parseAllowList(entries []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
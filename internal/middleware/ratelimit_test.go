@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRateLimitedRouter(t *testing.T) *gin.Engine {
+	routes, err := ParseRoutePolicies(map[string]string{
+		"POST /api/v1/auth/login": "2/1m",
+		"GET /api/v1/products":    "5/1m",
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RateLimit(&RateLimitConfig{
+		Default:   ratelimit.Policy{Name: "default", Limit: 3, Window: time.Minute},
+		Routes:    routes,
+		Store:     ratelimit.NewMemoryStore(),
+		AllowList: []string{"10.0.0.0/8"},
+		UserID: func(c *gin.Context) (uint, bool) {
+			id, err := strconv.Atoi(c.GetHeader("X-Test-User"))
+			return uint(id), err == nil
+		},
+	}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/api/v1/auth/login", ok)
+	router.GET("/api/v1/products", ok)
+	router.GET("/api/v1/products/:id", ok)
+	router.GET("/api/v1/orders", ok)
+	return router
+}
+
+func send(router *gin.Engine, method, path, ip, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_RoutePolicies(t *testing.T) {
+	router := newRateLimitedRouter(t)
+
+	assert.Equal(t, http.StatusOK, send(router, "POST", "/api/v1/auth/login", "1.2.3.4", "").Code)
+	assert.Equal(t, http.StatusOK, send(router, "POST", "/api/v1/auth/login", "1.2.3.4", "").Code)
+	w := send(router, "POST", "/api/v1/auth/login", "1.2.3.4", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Product reads, including sub-paths, share the looser policy and the
+	// login limit does not count against other routes.
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, send(router, "GET", "/api/v1/products/"+strconv.Itoa(i), "1.2.3.4", "").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send(router, "GET", "/api/v1/products", "1.2.3.4", "").Code)
+	w = send(router, "GET", "/api/v1/orders", "1.2.3.4", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimit_KeysByUserAndSkipsAllowList(t *testing.T) {
+	router := newRateLimitedRouter(t)
+
+	// The same user is limited across IPs; anonymous callers per IP.
+	for _, ip := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		assert.Equal(t, http.StatusOK, send(router, "GET", "/api/v1/orders", ip, "7").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send(router, "GET", "/api/v1/orders", "4.4.4.4", "7").Code)
+	assert.Equal(t, http.StatusOK, send(router, "GET", "/api/v1/orders", "4.4.4.4", "").Code)
+	assert.Equal(t, http.StatusOK, send(router, "GET", "/api/v1/orders", "4.4.4.4", "8").Code)
+
+	for i := 0; i < 10; i++ {
+		w := send(router, "GET", "/api/v1/orders", "10.1.2.3", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+)
+
+// Tracing accepts or generates the X-Request-ID and W3C traceparent
+// headers, stores them on the request context and runs the rest of the
+// chain in a server span. Both headers are echoed on the response.
+func Tracing(tracer *tracing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := tracing.StartRequest(tracer, c.Request, c.Request.Method+" "+route(c))
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(tracing.RequestIDHeader, tracing.RequestID(ctx))
+		c.Header(tracing.TraceparentHeader, span.SpanContext().Traceparent())
+
+		c.Next()
+
+		var err error
+		if last := c.Errors.Last(); last != nil {
+			err = last
+		}
+		tracing.EndRequest(span, c.Writer.Status(), err)
+	}
+}
+
+// route names the span after the matched route pattern rather than the raw
+// path, so IDs in paths do not create a span name per resource.
+func route(c *gin.Context) string {
+	if path := c.FullPath(); path != "" {
+		return path
+	}
+	return "unmatched"
+}
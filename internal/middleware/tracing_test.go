@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTracing_PropagatesRequestIDAndTraceContext(t *testing.T) {
+	var spans bytes.Buffer
+	var seen string
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Tracing(tracing.NewTracer("api", tracing.NewWriterExporter(&spans))), Logger(zap.New(core)))
+	router.GET("/orders/:id", func(c *gin.Context) {
+		seen = tracing.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-42", seen)
+	assert.Equal(t, "req-42", w.Header().Get("X-Request-ID"))
+	sc, ok := tracing.ParseTraceparent(w.Header().Get("traceparent"))
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+
+	var span tracing.SpanData
+	assert.NoError(t, json.Unmarshal(spans.Bytes(), &span))
+	assert.Equal(t, "GET /orders/:id", span.Name)
+	assert.Equal(t, tracing.KindServer, span.Kind)
+	assert.Equal(t, "00f067aa0ba902b7", span.ParentSpanID)
+	assert.Equal(t, float64(http.StatusOK), span.Attributes["http.status_code"])
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "req-42", fields["request_id"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields["trace_id"])
+	assert.Equal(t, span.SpanID, fields["span_id"])
+
+	// Requests without headers get a fresh ID and trace.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/8", nil))
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, w.Header().Get("X-Request-ID"))
+	_, ok = tracing.ParseTraceparent(w.Header().Get("traceparent"))
+	assert.True(t, ok)
+}
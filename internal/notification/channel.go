@@ -0,0 +1,39 @@
+package notification
+
+import (
+	"context"
+)
+
+type ChannelName string
+
+const (
+	ChannelEmail ChannelName = "email"
+	ChannelSMS   ChannelName = "sms"
+	ChannelInApp ChannelName = "in_app"
+)
+
+// Recipient is who a notification is for and how to reach them.
+type Recipient struct {
+	UserID uint
+	Name   string
+	Email  string
+	Phone  string
+	Locale string
+}
+
+// Message is a rendered notification. Channels use the parts that suit them:
+// email sends Subject, Text and HTML, SMS sends SMS, and the in-app inbox
+// stores Subject and Text.
+type Message struct {
+	Event   string
+	Subject string
+	Text    string
+	HTML    string
+	SMS     string
+}
+
+// Channel delivers rendered messages over one medium.
+type Channel interface {
+	Name() ChannelName
+	Send(ctx context.Context, recipient Recipient, message Message) error
+}
@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) ListNotifications(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, unread, err := h.service.ListNotifications(c.GetUint("user_id"), unreadOnly, page, limit)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"unread":        unread,
+		"page":          page,
+		"limit":         limit,
+	})
+}
+
+func (h *Handler) MarkRead(c *gin.Context) {
+	h.setRead(c, true)
+}
+
+func (h *Handler) MarkUnread(c *gin.Context) {
+	h.setRead(c, false)
+}
+
+func (h *Handler) setRead(c *gin.Context, read bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.WriteError(c, ErrInvalidNotificationID)
+		return
+	}
+	userID := c.GetUint("user_id")
+	var notification *Notification
+	if read {
+		notification, err = h.service.MarkRead(userID, uint(id))
+	} else {
+		notification, err = h.service.MarkUnread(userID, uint(id))
+	}
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, notification)
+}
+
+func (h *Handler) MarkAllRead(c *gin.Context) {
+	updated, err := h.service.MarkAllRead(c.GetUint("user_id"))
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+func (h *Handler) GetPreferences(c *gin.Context) {
+	preference, err := h.service.GetPreferences(c.GetUint("user_id"))
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, preference)
+}
+
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	var req PreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	preference, err := h.service.UpdatePreferences(c.GetUint("user_id"), &req)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, preference)
+}
@@ -0,0 +1,42 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification is an entry in a user's in-app inbox.
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Event     string     `json:"event" gorm:"not null"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body" gorm:"type:text"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InAppChannel stores notifications in the database for the in-app inbox.
+type InAppChannel struct {
+	db *gorm.DB
+}
+
+func NewInAppChannel(db *gorm.DB) *InAppChannel {
+	return &InAppChannel{db: db}
+}
+
+func (c *InAppChannel) Name() ChannelName { return ChannelInApp }
+
+func (c *InAppChannel) Send(ctx context.Context, recipient Recipient, message Message) error {
+	if recipient.UserID == 0 {
+		return nil
+	}
+	return c.db.WithContext(ctx).Create(&Notification{
+		UserID: recipient.UserID,
+		Event:  message.Event,
+		Title:  message.Subject,
+		Body:   message.Text,
+	}).Error
+}
@@ -0,0 +1,322 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/oguzhan/e-commerce/internal/cart"
+	"github.com/oguzhan/e-commerce/internal/events"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotificationNotFound  = apperrors.New(http.StatusNotFound, "notification_not_found", "notification not found")
+	ErrContactNotFound       = apperrors.New(http.StatusNotFound, "contact_not_found", "contact not found")
+	ErrInvalidNotificationID = apperrors.New(http.StatusBadRequest, "invalid_notification_id", "invalid notification ID")
+)
+
+// Preference holds a user's notification settings. Users without a stored
+// row get DefaultPreference. Text messages go to the chosen contact's phone
+// number, or to the user's default contact when none is chosen.
+type Preference struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex"`
+	EmailEnabled bool      `json:"email_enabled" gorm:"not null"`
+	SMSEnabled   bool      `json:"sms_enabled" gorm:"not null"`
+	InAppEnabled bool      `json:"in_app_enabled" gorm:"not null"`
+	Locale       string    `json:"locale" gorm:"type:varchar(10);not null"`
+	ContactID    *uint     `json:"contact_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (Preference) TableName() string {
+	return "notification_preferences"
+}
+
+func DefaultPreference(userID uint) Preference {
+	return Preference{
+		UserID:       userID,
+		EmailEnabled: true,
+		InAppEnabled: true,
+		Locale:       DefaultLocale,
+	}
+}
+
+func (p *Preference) enabled(channel ChannelName) bool {
+	switch channel {
+	case ChannelEmail:
+		return p.EmailEnabled
+	case ChannelSMS:
+		return p.SMSEnabled
+	case ChannelInApp:
+		return p.InAppEnabled
+	}
+	return false
+}
+
+type PreferenceRequest struct {
+	EmailEnabled *bool   `json:"email_enabled"`
+	SMSEnabled   *bool   `json:"sms_enabled"`
+	InAppEnabled *bool   `json:"in_app_enabled"`
+	Locale       *string `json:"locale" binding:"omitempty,oneof=en tr"`
+	ContactID    *uint   `json:"contact_id"`
+}
+
+// Models returns the notification tables for schema migration.
+func Models() []interface{} {
+	return []interface{}{&Notification{}, &Preference{}}
+}
+
+type Service struct {
+	db       *gorm.DB
+	renderer *Renderer
+	channels []Channel
+	logger   *zap.Logger
+}
+
+// NewService creates a notification service that sends through the given
+// channels. Channels that a user has turned off are skipped.
+func NewService(db *gorm.DB, renderer *Renderer, logger *zap.Logger, channels ...Channel) *Service {
+	return &Service{db: db, renderer: renderer, channels: channels, logger: logger}
+}
+
+// Notify renders the event for the user and sends it over every channel the
+// user has enabled. Failures on single channels are logged; an error is
+// returned only when no channel succeeded, so retries do not repeat
+// notifications that already went out.
+func (s *Service) Notify(ctx context.Context, userID uint, event string, data TemplateData) error {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return err
+	}
+	preference, err := s.GetPreferences(userID)
+	if err != nil {
+		return err
+	}
+	phone, err := s.phoneNumber(userID, preference.ContactID)
+	if err != nil {
+		return err
+	}
+
+	recipient := Recipient{
+		UserID: user.ID,
+		Name:   user.FirstName,
+		Email:  user.Email,
+		Phone:  phone,
+		Locale: preference.Locale,
+	}
+	return s.send(ctx, recipient, event, data, preference.enabled)
+}
+
+// NotifyGuest sends an email-only notification to an address without an
+// account, such as the buyer of a guest order.
+func (s *Service) NotifyGuest(ctx context.Context, email string, event string, data TemplateData) error {
+	recipient := Recipient{Email: email, Locale: DefaultLocale}
+	return s.send(ctx, recipient, event, data, func(channel ChannelName) bool {
+		return channel == ChannelEmail
+	})
+}
+
+func (s *Service) send(ctx context.Context, recipient Recipient, event string, data TemplateData, enabled func(ChannelName) bool) error {
+	data.Recipient = recipient
+	message, err := s.renderer.Render(event, recipient.Locale, data)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	attempted, sent := 0, 0
+	for _, channel := range s.channels {
+		if !enabled(channel.Name()) {
+			continue
+		}
+		attempted++
+		if err := channel.Send(ctx, recipient, message); err != nil {
+			lastErr = err
+			s.logger.Warn("notification failed",
+				zap.String("channel", string(channel.Name())),
+				zap.String("event", event),
+				zap.Uint("user_id", recipient.UserID),
+				zap.Error(err))
+			continue
+		}
+		sent++
+	}
+	if attempted > 0 && sent == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// phoneNumber returns the phone number of the chosen contact, or of the
+// user's default contact.
+func (s *Service) phoneNumber(userID uint, contactID *uint) (string, error) {
+	var contact models.Contact
+	query := s.db.Where("user_id = ?", userID)
+	if contactID != nil {
+		query = query.Where("id = ?", *contactID)
+	} else {
+		query = query.Where("is_default = ?", true)
+	}
+	err := query.First(&contact).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return contact.PhoneNumber, err
+}
+
+func (s *Service) GetPreferences(userID uint) (*Preference, error) {
+	preference := DefaultPreference(userID)
+	err := s.db.Where("user_id = ?", userID).First(&preference).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &preference, nil
+}
+
+func (s *Service) UpdatePreferences(userID uint, req *PreferenceRequest) (*Preference, error) {
+	preference, err := s.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ContactID != nil {
+		var count int64
+		if err := s.db.Model(&models.Contact{}).
+			Where("id = ? AND user_id = ?", *req.ContactID, userID).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrContactNotFound
+		}
+		preference.ContactID = req.ContactID
+	}
+	if req.EmailEnabled != nil {
+		preference.EmailEnabled = *req.EmailEnabled
+	}
+	if req.SMSEnabled != nil {
+		preference.SMSEnabled = *req.SMSEnabled
+	}
+	if req.InAppEnabled != nil {
+		preference.InAppEnabled = *req.InAppEnabled
+	}
+	if req.Locale != nil {
+		preference.Locale = *req.Locale
+	}
+	if err := s.db.Save(preference).Error; err != nil {
+		return nil, err
+	}
+	return preference, nil
+}
+
+// ListNotifications returns the user's inbox, newest first, together with
+// the total number of matching entries and the number of unread ones.
+func (s *Service) ListNotifications(userID uint, unreadOnly bool, page, limit int) ([]Notification, int64, int64, error) {
+	var notifications []Notification
+	var total, unread int64
+
+	if err := s.db.Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&unread).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	query := s.db.Model(&Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, 0, err
+	}
+	if err := query.Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&notifications).Error; err != nil {
+		return nil, 0, 0, err
+	}
+	return notifications, total, unread, nil
+}
+
+func (s *Service) MarkRead(userID, id uint) (*Notification, error) {
+	now := time.Now()
+	return s.setReadAt(userID, id, &now)
+}
+
+func (s *Service) MarkUnread(userID, id uint) (*Notification, error) {
+	return s.setReadAt(userID, id, nil)
+}
+
+func (s *Service) setReadAt(userID, id uint, readAt *time.Time) (*Notification, error) {
+	var notification Notification
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	notification.ReadAt = readAt
+	if err := s.db.Model(&notification).Update("read_at", readAt).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+func (s *Service) MarkAllRead(userID uint) (int64, error) {
+	result := s.db.Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// HandleEvent is an events subscriber that notifies customers about their
+// orders and refunds.
+func (s *Service) HandleEvent(ctx context.Context, envelope events.Envelope) error {
+	switch envelope.Name {
+	case events.NameOrderPlaced:
+		var placed events.OrderPlaced
+		if err := envelope.Decode(&placed); err != nil {
+			return err
+		}
+		return s.notifyOrder(ctx, placed.OrderID, EventOrderPlaced)
+
+	case events.NameOrderStatusChanged:
+		var changed events.OrderStatusChanged
+		if err := envelope.Decode(&changed); err != nil {
+			return err
+		}
+		if changed.To != models.OrderStatusShipped {
+			return nil
+		}
+		return s.notifyOrder(ctx, changed.OrderID, EventOrderShipped)
+
+	case events.NamePaymentRefunded:
+		var refunded events.PaymentRefunded
+		if err := envelope.Decode(&refunded); err != nil {
+			return err
+		}
+		return s.Notify(ctx, refunded.UserID, EventPaymentRefunded, TemplateData{Refund: &refunded})
+	}
+	return nil
+}
+
+func (s *Service) notifyOrder(ctx context.Context, orderID uint, event string) error {
+	var order models.Order
+	if err := s.db.WithContext(ctx).Preload("OrderItems").First(&order, orderID).Error; err != nil {
+		return err
+	}
+	if order.UserID == 0 {
+		if order.GuestEmail == "" {
+			return nil
+		}
+		return s.NotifyGuest(ctx, order.GuestEmail, event, TemplateData{Order: &order})
+	}
+	return s.Notify(ctx, order.UserID, event, TemplateData{Order: &order})
+}
+
+// SendCartReminder implements cart.ReminderNotifier.
+func (s *Service) SendCartReminder(ctx context.Context, reminder cart.Reminder) error {
+	return s.Notify(ctx, reminder.UserID, EventCartReminder, TemplateData{Cart: &reminder})
+}
@@ -0,0 +1,157 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fakeChannel struct {
+	name       ChannelName
+	err        error
+	recipients []Recipient
+	messages   []Message
+}
+
+func (c *fakeChannel) Name() ChannelName { return c.name }
+
+func (c *fakeChannel) Send(ctx context.Context, recipient Recipient, message Message) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.recipients = append(c.recipients, recipient)
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func setupTestService(t *testing.T, channels ...Channel) (*Service, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(append(Models(), &models.User{}, &models.Contact{}, &models.Order{}, &models.OrderItem{})...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	return NewService(db, renderer, zap.NewNop(), channels...), db
+}
+
+func createTestUser(t *testing.T, db *gorm.DB) *models.User {
+	user := &models.User{Email: "ayse@example.com", Password: "secret", FirstName: "Ayse", LastName: "Yilmaz", IsActive: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return user
+}
+
+func TestRender_FallsBackToBaseAndDefaultLocale(t *testing.T) {
+	renderer, err := NewRenderer()
+	assert.NoError(t, err)
+	data := TemplateData{Recipient: Recipient{Name: "Ayse"}, Order: &models.Order{Model: gorm.Model{ID: 42}, TotalAmount: 99.5}}
+
+	turkish, err := renderer.Render(EventOrderPlaced, "tr-TR", data)
+	assert.NoError(t, err)
+	english, err := renderer.Render(EventOrderPlaced, "de", data)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, turkish.Subject, english.Subject)
+	assert.Contains(t, english.Subject, "42")
+	assert.Contains(t, english.Text, "99.50")
+	assert.Contains(t, english.HTML, "Ayse")
+	assert.NotEmpty(t, english.SMS)
+
+	_, err = renderer.Render("unknown.event", "en", data)
+	assert.Error(t, err)
+}
+
+func TestNotify_RespectsPreferencesAndUsesContactPhone(t *testing.T) {
+	email := &fakeChannel{name: ChannelEmail}
+	sms := &fakeChannel{name: ChannelSMS}
+	service, db := setupTestService(t, email, sms)
+	user := createTestUser(t, db)
+	db.Create(&models.Contact{UserID: user.ID, Type: "mobile", Title: "Mobile", PhoneNumber: "+905551112233", IsDefault: true})
+
+	order := &models.Order{UserID: user.ID, TotalAmount: 10}
+	assert.NoError(t, service.Notify(context.Background(), user.ID, EventOrderPlaced, TemplateData{Order: order}))
+	assert.Len(t, email.messages, 1)
+	assert.Empty(t, sms.messages)
+
+	enabled, disabled := true, false
+	_, err := service.UpdatePreferences(user.ID, &PreferenceRequest{EmailEnabled: &disabled, SMSEnabled: &enabled})
+	assert.NoError(t, err)
+	preference, err := service.GetPreferences(user.ID)
+	assert.NoError(t, err)
+	assert.False(t, preference.EmailEnabled)
+	assert.True(t, preference.SMSEnabled)
+
+	assert.NoError(t, service.Notify(context.Background(), user.ID, EventOrderPlaced, TemplateData{Order: order}))
+	assert.Len(t, email.messages, 1)
+	assert.Len(t, sms.messages, 1)
+	assert.Equal(t, "+905551112233", sms.recipients[0].Phone)
+
+	foreign := uint(999)
+	_, err = service.UpdatePreferences(user.ID, &PreferenceRequest{ContactID: &foreign})
+	assert.ErrorIs(t, err, ErrContactNotFound)
+}
+
+func TestNotify_FailsOnlyWhenEveryChannelFails(t *testing.T) {
+	broken := &fakeChannel{name: ChannelEmail, err: errors.New("smtp down")}
+	service, db := setupTestService(t, broken)
+	service.channels = append(service.channels, NewInAppChannel(db))
+	user := createTestUser(t, db)
+
+	data := TemplateData{Refund: &events.PaymentRefunded{PaymentID: 1, OrderID: 7, UserID: user.ID, Amount: 25}}
+	assert.NoError(t, service.Notify(context.Background(), user.ID, EventPaymentRefunded, data))
+
+	inAppOff := false
+	_, err := service.UpdatePreferences(user.ID, &PreferenceRequest{InAppEnabled: &inAppOff})
+	assert.NoError(t, err)
+	assert.Error(t, service.Notify(context.Background(), user.ID, EventPaymentRefunded, data))
+}
+
+func TestInbox_ReadAndUnread(t *testing.T) {
+	service, db := setupTestService(t)
+	service.channels = []Channel{NewInAppChannel(db)}
+	user := createTestUser(t, db)
+
+	for i := 0; i < 3; i++ {
+		order := &models.Order{Model: gorm.Model{ID: uint(i + 1)}, UserID: user.ID}
+		assert.NoError(t, service.Notify(context.Background(), user.ID, EventOrderShipped, TemplateData{Order: order}))
+	}
+
+	notifications, total, unread, err := service.ListNotifications(user.ID, false, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, int64(3), unread)
+	assert.True(t, strings.Contains(notifications[0].Title, "3"))
+
+	_, err = service.MarkRead(user.ID, notifications[0].ID)
+	assert.NoError(t, err)
+	_, _, unread, _ = service.ListNotifications(user.ID, false, 1, 10)
+	assert.Equal(t, int64(2), unread)
+
+	_, err = service.MarkRead(user.ID+1, notifications[1].ID)
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+
+	updated, err := service.MarkAllRead(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+
+	_, err = service.MarkUnread(user.ID, notifications[2].ID)
+	assert.NoError(t, err)
+	unreadOnly, total, _, err := service.ListNotifications(user.ID, true, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, notifications[2].ID, unreadOnly[0].ID)
+}
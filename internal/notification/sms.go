@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var ErrNoPhoneNumber = errors.New("recipient has no phone number")
+
+type SMSConfig struct {
+	// URL is the provider's send endpoint. It receives a JSON body with
+	// "to", "from" and "body" fields.
+	URL    string
+	APIKey string
+	From   string
+}
+
+// HTTPSMSChannel sends text messages through an HTTP SMS provider.
+type HTTPSMSChannel struct {
+	config SMSConfig
+	client *http.Client
+}
+
+func NewHTTPSMSChannel(config SMSConfig) *HTTPSMSChannel {
+	return &HTTPSMSChannel{config: config, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *HTTPSMSChannel) Name() ChannelName { return ChannelSMS }
+
+func (c *HTTPSMSChannel) Send(ctx context.Context, recipient Recipient, message Message) error {
+	if recipient.Phone == "" {
+		return ErrNoPhoneNumber
+	}
+	body, err := json.Marshal(map[string]string{
+		"to":   recipient.Phone,
+		"from": c.config.From,
+		"body": message.SMS,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms provider responded with status %d: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
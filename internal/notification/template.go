@@ -0,0 +1,137 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/oguzhan/e-commerce/internal/cart"
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+// Template events. Each has templates/<locale>/<event>.tmpl, defining the
+// "subject", "text" and "sms" templates, and an optional <event>.html.
+const (
+	EventOrderPlaced     = "order.placed"
+	EventOrderShipped    = "order.shipped"
+	EventPaymentRefunded = "payment.refunded"
+	EventCartReminder    = "cart.reminder"
+)
+
+const DefaultLocale = "en"
+
+//go:embed templates
+var templateFS embed.FS
+
+// TemplateData is passed to every template. Only the fields relevant to the
+// event are set.
+type TemplateData struct {
+	Recipient Recipient
+	Order     *models.Order
+	Refund    *events.PaymentRefunded
+	Cart      *cart.Reminder
+}
+
+var templateFuncs = map[string]interface{}{
+	"money": func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+}
+
+// Renderer renders messages from the embedded templates.
+type Renderer struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	err := fs.WalkDir(templateFS, "templates", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := templateFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		locale := path.Base(path.Dir(name))
+		file := path.Base(name)
+
+		switch path.Ext(file) {
+		case ".tmpl":
+			t, err := texttemplate.New(file).Funcs(templateFuncs).Parse(string(content))
+			if err != nil {
+				return err
+			}
+			r.text[locale+"/"+strings.TrimSuffix(file, ".tmpl")] = t
+		case ".html":
+			t, err := htmltemplate.New(file).Funcs(templateFuncs).Parse(string(content))
+			if err != nil {
+				return err
+			}
+			r.html[locale+"/"+strings.TrimSuffix(file, ".html")] = t
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Render renders the event's templates in the given locale. A regional
+// locale such as "tr-TR" falls back to "tr", and missing locales fall back to
+// DefaultLocale.
+func (r *Renderer) Render(event, locale string, data TemplateData) (Message, error) {
+	key, ok := r.resolve(event, locale)
+	if !ok {
+		return Message{}, fmt.Errorf("no template for event %q", event)
+	}
+
+	message := Message{Event: event}
+	text := r.text[key]
+	for name, target := range map[string]*string{
+		"subject": &message.Subject,
+		"text":    &message.Text,
+		"sms":     &message.SMS,
+	} {
+		var buf bytes.Buffer
+		if err := text.ExecuteTemplate(&buf, name, data); err != nil {
+			return Message{}, err
+		}
+		*target = strings.TrimSpace(buf.String())
+	}
+
+	if html, ok := r.html[key]; ok {
+		var buf bytes.Buffer
+		if err := html.Execute(&buf, data); err != nil {
+			return Message{}, err
+		}
+		message.HTML = buf.String()
+	}
+	return message, nil
+}
+
+func (r *Renderer) resolve(event, locale string) (string, bool) {
+	locale = strings.ToLower(locale)
+	candidates := []string{locale}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, DefaultLocale)
+
+	for _, candidate := range candidates {
+		key := candidate + "/" + event
+		if _, ok := r.text[key]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
 	"github.com/oguzhan/e-commerce/pkg/models"
 )
 
@@ -30,17 +31,16 @@ func (h *Handler) RegisterRoutes(router *gin.Engine) {
 }
 
 func (h *Handler) CreateOrder(c *gin.Context) {
-	var order models.Order
-	if err := c.ShouldBindJSON(&order); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request models.CreateOrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 
 	userID := c.GetUint("user_id")
-	order.UserID = userID
-
-	if err := h.service.CreateOrder(&order); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	order, err := h.service.PlaceOrder(c.Request.Context(), userID, &request)
+	if err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -50,19 +50,19 @@ func (h *Handler) CreateOrder(c *gin.Context) {
 func (h *Handler) GetOrder(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		middleware.WriteError(c, ErrInvalidOrderID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	order, err := h.service.GetOrderByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if order.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		middleware.WriteError(c, ErrNotOrderOwner)
 		return
 	}
 
@@ -71,9 +71,9 @@ func (h *Handler) GetOrder(c *gin.Context) {
 
 func (h *Handler) GetUserOrders(c *gin.Context) {
 	userID := c.GetUint("user_id")
-	orders, err := h.service.GetOrdersByUserID(userID)
+	orders, err := h.service.GetOrdersByUserID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -92,9 +92,9 @@ func (h *Handler) ListOrders(c *gin.Context) {
 		limit = 10
 	}
 
-	orders, total, err := h.service.ListOrders(userID, page, limit)
+	orders, total, err := h.service.ListOrders(c.Request.Context(), userID, page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -109,40 +109,45 @@ func (h *Handler) ListOrders(c *gin.Context) {
 func (h *Handler) UpdateOrder(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		middleware.WriteError(c, ErrInvalidOrderID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	order, err := h.service.GetOrderByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if order.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		middleware.WriteError(c, ErrNotOrderOwner)
 		return
 	}
 
 	var updateOrder models.Order
 	if err := c.ShouldBindJSON(&updateOrder); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.UpdateOrder(uint(id), &updateOrder); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, updateOrder)
+	order, err = h.service.GetOrderByID(uint(id))
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
 }
 
 func (h *Handler) UpdateOrderStatus(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		middleware.WriteError(c, ErrInvalidOrderID)
 		return
 	}
 
@@ -151,13 +156,39 @@ func (h *Handler) UpdateOrderStatus(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	userID := c.GetUint("user_id")
-	if err := h.service.UpdateOrderStatus(uint(id), userID, request.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.UpdateOrderStatus(c.Request.Context(), uint(id), userID, request.Status); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// SetOrderStatus moves any order to the requested status. Register it
+// behind an admin check.
+func (h *Handler) SetOrderStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.WriteError(c, ErrInvalidOrderID)
+		return
+	}
+
+	var request struct {
+		Status string `json:"status" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+
+	if err := h.service.SetOrderStatus(c.Request.Context(), uint(id), request.Status); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -167,13 +198,13 @@ func (h *Handler) UpdateOrderStatus(c *gin.Context) {
 func (h *Handler) CancelOrder(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		middleware.WriteError(c, ErrInvalidOrderID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
-	if err := h.service.CancelOrder(uint(id), userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.CancelOrder(c.Request.Context(), uint(id), userID); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 
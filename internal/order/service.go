@@ -1,45 +1,288 @@
 package order
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
 
+	"github.com/oguzhan/e-commerce/internal/clients"
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/pkg/database"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
 	"github.com/oguzhan/e-commerce/pkg/models"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrOrderNotFound    = apperrors.New(http.StatusNotFound, "order_not_found", "order not found")
+	ErrNotOrderOwner    = apperrors.New(http.StatusForbidden, "order_forbidden", "the order belongs to another user")
+	ErrInvalidOrderID   = apperrors.New(http.StatusBadRequest, "invalid_order_id", "invalid order ID")
+	ErrInvalidStatus    = apperrors.New(http.StatusBadRequest, "invalid_order_status", "status must be one of pending, processing, shipped, delivered or cancelled")
+	ErrStatusForbidden  = apperrors.New(http.StatusForbidden, "order_status_forbidden", "customers can only cancel their orders")
+	ErrOrderCancelled   = apperrors.New(http.StatusConflict, "order_cancelled", "cancelled orders cannot change status")
+	ErrStatusChanged    = apperrors.New(http.StatusConflict, "order_status_changed", "the order status was changed by another request")
+	ErrAddressNotFound  = apperrors.New(http.StatusNotFound, "address_not_found", "address not found")
+	ErrNoDefaultAddress = apperrors.New(http.StatusBadRequest, "no_default_address", "no address given and no default address on file")
+	ErrAmbiguousAddress = apperrors.New(http.StatusBadRequest, "ambiguous_address", "give either an address ID or an inline address, not both")
+	ErrGuestAddress     = apperrors.New(http.StatusBadRequest, "guest_address_required", "guest orders require inline shipping and billing addresses")
+	ErrNoItems          = apperrors.New(http.StatusBadRequest, "no_items", "order has no items")
+	ErrProductNotFound  = apperrors.New(http.StatusNotFound, "product_not_found", "product not found")
+	ErrOutOfStock       = apperrors.New(http.StatusConflict, "out_of_stock", "not enough stock for ordered product")
+
+	errNoCatalog = errors.New("guest orders need a catalog to price items")
+)
+
+// Catalog is the product service as seen by orders. When a service has a
+// catalog, item prices come from it and stock is reserved through it.
+type Catalog interface {
+	GetProduct(ctx context.Context, id uint) (*clients.Product, error)
+	ReserveStock(ctx context.Context, id uint, quantity int) error
+	ReleaseStock(ctx context.Context, id uint, quantity int) error
+}
+
+// addressColumns lists the order columns that hold address snapshots. They
+// are written once at creation time and never updated afterwards.
+var addressColumns = []string{
+	"shipping_address", "billing_address",
+	"shipping_source_address_id", "shipping_title", "shipping_address_line", "shipping_city",
+	"shipping_state", "shipping_country", "shipping_postal_code",
+	"billing_source_address_id", "billing_title", "billing_address_line", "billing_city",
+	"billing_state", "billing_country", "billing_postal_code",
+}
+
 type Service struct {
-	db *gorm.DB
+	db      *gorm.DB
+	catalog Catalog
 }
 
 func NewService(db *gorm.DB) *Service {
 	return &Service{db: db}
 }
 
+// NewServiceWithCatalog creates a service that prices items and reserves
+// stock through the product service.
+func NewServiceWithCatalog(db *gorm.DB, catalog Catalog) *Service {
+	return &Service{db: db, catalog: catalog}
+}
+
+// CreateOrder stores the order and publishes OrderPlaced in the same
+// transaction.
 func (s *Service) CreateOrder(order *models.Order) error {
-	return s.db.Create(order).Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		return events.Publish(tx, events.OrderPlaced{
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			GuestEmail:  order.GuestEmail,
+			TotalAmount: order.TotalAmount,
+			ItemCount:   len(order.OrderItems),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RecordOrderCreated()
+	return nil
+}
+
+// PlaceOrder creates an order for userID, snapshotting the shipping and
+// billing addresses from the request or the user's address book.
+func (s *Service) PlaceOrder(ctx context.Context, userID uint, req *models.CreateOrderRequest) (*models.Order, error) {
+	shipping, err := s.resolveAddress(userID, req.ShippingAddressID, req.ShippingAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	billing, err := s.resolveAddress(userID, req.BillingAddressID, req.BillingAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		UserID:          userID,
+		Status:          models.OrderStatusPending,
+		TotalAmount:     req.TotalAmount,
+		ShippingAddress: shipping.String(),
+		BillingAddress:  billing.String(),
+		ShippingDetails: shipping,
+		BillingDetails:  billing,
+		PaymentMethod:   req.PaymentMethod,
+		OrderItems:      req.OrderItems,
+	}
+
+	if err := s.place(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// PlaceGuestOrder creates an order for a visitor without an account. The
+// order is tied to the given email address instead of a user. Guests are
+// not trusted with prices: items are priced through the catalog, which is
+// required.
+func (s *Service) PlaceGuestOrder(ctx context.Context, req *models.GuestOrderRequest, items []models.OrderItem) (*models.Order, error) {
+	if s.catalog == nil {
+		return nil, errNoCatalog
+	}
+	if req.ShippingAddress == nil || req.BillingAddress == nil {
+		return nil, ErrGuestAddress
+	}
+
+	shipping := req.ShippingAddress.Snapshot()
+	billing := req.BillingAddress.Snapshot()
+	order := &models.Order{
+		GuestEmail:      req.Email,
+		Status:          models.OrderStatusPending,
+		ShippingAddress: shipping.String(),
+		BillingAddress:  billing.String(),
+		ShippingDetails: shipping,
+		BillingDetails:  billing,
+		PaymentMethod:   req.PaymentMethod,
+		OrderItems:      items,
+	}
+
+	if err := s.place(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// place creates the order. With a catalog, item prices and the total are
+// taken from the product service and stock is reserved before the order is
+// stored; the reservation is released again if the order cannot be stored.
+func (s *Service) place(ctx context.Context, order *models.Order) error {
+	if s.catalog == nil {
+		return s.CreateOrder(order)
+	}
+	if len(order.OrderItems) == 0 {
+		return ErrNoItems
+	}
+
+	var total float64
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		product, err := s.catalog.GetProduct(ctx, item.ProductID)
+		if err != nil {
+			if errors.Is(err, clients.ErrNotFound) {
+				return ErrProductNotFound.WithMessage(fmt.Sprintf("product %d not found", item.ProductID))
+			}
+			return err
+		}
+		item.Price = product.Price
+		total += product.Price * float64(item.Quantity)
+	}
+	order.TotalAmount = total
+
+	if err := s.reserve(ctx, order.OrderItems); err != nil {
+		return err
+	}
+	if err := s.CreateOrder(order); err != nil {
+		s.release(ctx, order.OrderItems)
+		return err
+	}
+	return nil
+}
+
+// reserve reserves stock for every item, releasing what it already took if
+// one of them fails.
+func (s *Service) reserve(ctx context.Context, items []models.OrderItem) error {
+	for i, item := range items {
+		if err := s.catalog.ReserveStock(ctx, item.ProductID, item.Quantity); err != nil {
+			s.release(ctx, items[:i])
+			if errors.Is(err, clients.ErrConflict) {
+				return ErrOutOfStock.WithMessage(fmt.Sprintf("not enough stock for product %d", item.ProductID))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) release(ctx context.Context, items []models.OrderItem) error {
+	var errs []error
+	for _, item := range items {
+		if err := s.catalog.ReleaseStock(ctx, item.ProductID, item.Quantity); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) resolveAddress(userID uint, id *uint, input *models.AddressInput) (models.AddressSnapshot, error) {
+	if id != nil && input != nil {
+		return models.AddressSnapshot{}, ErrAmbiguousAddress
+	}
+
+	if input != nil {
+		return input.Snapshot(), nil
+	}
+
+	query := s.db.Where("user_id = ?", userID)
+	if id != nil {
+		query = query.Where("id = ?", *id)
+	} else {
+		query = query.Where("is_default = ?", true)
+	}
+
+	var address models.Address
+	if err := query.First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if id != nil {
+				return models.AddressSnapshot{}, ErrAddressNotFound
+			}
+			return models.AddressSnapshot{}, ErrNoDefaultAddress
+		}
+		return models.AddressSnapshot{}, err
+	}
+
+	return models.NewAddressSnapshot(&address), nil
 }
 
 func (s *Service) GetOrderByID(id uint) (*models.Order, error) {
 	var order models.Order
 	if err := s.db.Preload("OrderItems").First(&order, id).Error; err != nil {
-		return nil, err
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+// GetOrder lets services running in the same process look orders up; it
+// satisfies payment.OrderLookup.
+func (s *Service) GetOrder(ctx context.Context, id uint) (*models.Order, error) {
+	var order models.Order
+	if err := s.db.WithContext(ctx).Preload("OrderItems").First(&order, id).Error; err != nil {
+		return nil, notFound(err)
 	}
 	return &order, nil
 }
 
-func (s *Service) GetOrdersByUserID(userID uint) ([]models.Order, error) {
+// notFound turns a missing record into ErrOrderNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrOrderNotFound.Wrap(err)
+	}
+	return err
+}
+
+// GetOrdersByUserID and ListOrders read the order history from a replica
+// when there is one.
+func (s *Service) GetOrdersByUserID(ctx context.Context, userID uint) ([]models.Order, error) {
 	var orders []models.Order
-	if err := s.db.Preload("OrderItems").Where("user_id = ?", userID).Find(&orders).Error; err != nil {
+	if err := database.Read(s.db.WithContext(ctx)).Preload("OrderItems").Where("user_id = ?", userID).Find(&orders).Error; err != nil {
 		return nil, err
 	}
 	return orders, nil
 }
 
-func (s *Service) ListOrders(userID uint, page, limit int) ([]models.Order, int64, error) {
+func (s *Service) ListOrders(ctx context.Context, userID uint, page, limit int) ([]models.Order, int64, error) {
 	var orders []models.Order
 	var total int64
 
-	query := s.db.Model(&models.Order{}).Where("user_id = ?", userID)
+	query := database.Read(s.db.WithContext(ctx)).Model(&models.Order{}).Where("user_id = ?", userID)
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
@@ -54,37 +297,133 @@ func (s *Service) ListOrders(userID uint, page, limit int) ([]models.Order, int6
 	return orders, total, nil
 }
 
+// ownerLockedColumns cannot be changed through UpdateOrder: the status only
+// moves through fulfilment, and the owner and total are fixed at checkout.
+var ownerLockedColumns = append([]string{"status", "user_id", "guest_email", "total_amount"}, addressColumns...)
+
 func (s *Service) UpdateOrder(id uint, order *models.Order) error {
 	var existingOrder models.Order
 	if err := s.db.First(&existingOrder, id).Error; err != nil {
 		return err
 	}
-
-	return s.db.Model(&existingOrder).Updates(order).Error
+	return s.db.Model(&existingOrder).Omit(ownerLockedColumns...).Updates(order).Error
 }
 
-func (s *Service) CancelOrder(id uint, userID uint) error {
+// CancelOrder cancels the user's order. With a catalog, the order's stock is
+// released once the cancellation is stored.
+func (s *Service) CancelOrder(ctx context.Context, id uint, userID uint) error {
 	var order models.Order
-	if err := s.db.First(&order, id).Error; err != nil {
+	if err := s.db.Preload("OrderItems").First(&order, id).Error; err != nil {
+		return notFound(err)
+	}
+
+	if order.UserID != userID {
+		return ErrNotOrderOwner
+	}
+	return s.changeStatus(ctx, &order, models.OrderStatusCancelled)
+}
+
+// UpdateOrderStatus changes the status on behalf of the order's owner, who
+// may only cancel it. Every other transition is left to fulfilment through
+// SetOrderStatus, so customers cannot mark their own orders delivered.
+func (s *Service) UpdateOrderStatus(ctx context.Context, id, userID uint, status string) error {
+	if err := validStatus(status); err != nil {
 		return err
 	}
 
+	var order models.Order
+	if err := s.db.Preload("OrderItems").First(&order, id).Error; err != nil {
+		return notFound(err)
+	}
+
 	if order.UserID != userID {
-		return errors.New("unauthorized")
+		return ErrNotOrderOwner
+	}
+	if models.OrderStatus(status) != models.OrderStatusCancelled {
+		return ErrStatusForbidden
 	}
 
-	return s.db.Model(&order).Update("status", "cancelled").Error
+	return s.changeStatus(ctx, &order, models.OrderStatusCancelled)
 }
 
-func (s *Service) UpdateOrderStatus(id, userID uint, status string) error {
-	var order models.Order
-	if err := s.db.First(&order, id).Error; err != nil {
+// SetOrderStatus changes the status of any order. It is meant for admins
+// and fulfilment.
+func (s *Service) SetOrderStatus(ctx context.Context, id uint, status string) error {
+	if err := validStatus(status); err != nil {
 		return err
 	}
 
-	if order.UserID != userID {
-		return errors.New("unauthorized")
+	var order models.Order
+	if err := s.db.Preload("OrderItems").First(&order, id).Error; err != nil {
+		return notFound(err)
+	}
+	return s.changeStatus(ctx, &order, models.OrderStatus(status))
+}
+
+func validStatus(status string) error {
+	switch models.OrderStatus(status) {
+	case models.OrderStatusPending, models.OrderStatusProcessing, models.OrderStatusShipped,
+		models.OrderStatusDelivered, models.OrderStatusCancelled:
+		return nil
 	}
+	return ErrInvalidStatus
+}
+
+// StartFulfilment moves a pending order to processing once it has been paid.
+// Orders that have already moved on are left alone, so repeated payment
+// events are harmless.
+func (s *Service) StartFulfilment(id uint) error {
+	var order models.Order
+	if err := s.db.First(&order, id).Error; err != nil {
+		return notFound(err)
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil
+	}
+	return s.changeStatus(context.Background(), &order, models.OrderStatusProcessing)
+}
 
-	return s.db.Model(&order).Update("status", status).Error
+// changeStatus updates the order's status and publishes OrderStatusChanged in
+// the same transaction. Every path to cancelled goes through here: with a
+// catalog, the order's stock is released once the cancellation is stored.
+// The update only applies to the status the order was read with, so of
+// several concurrent cancellations exactly one releases the stock, and
+// cancelled orders are never reopened.
+func (s *Service) changeStatus(ctx context.Context, order *models.Order, status models.OrderStatus) error {
+	if order.Status == status {
+		return nil
+	}
+	if order.Status == models.OrderStatusCancelled {
+		return ErrOrderCancelled
+	}
+	from := order.Status
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(order).Where("status = ?", from).Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStatusChanged
+		}
+		return events.Publish(tx, events.OrderStatusChanged{
+			OrderID: order.ID,
+			UserID:  order.UserID,
+			From:    from,
+			To:      status,
+		})
+	})
+	if errors.Is(err, ErrStatusChanged) {
+		// Another request got there first; it did whatever was needed.
+		var current models.Order
+		if s.db.Select("status").First(&current, order.ID).Error == nil && current.Status == status {
+			return nil
+		}
+	}
+	if err != nil || status != models.OrderStatusCancelled || s.catalog == nil {
+		return err
+	}
+	if err := s.release(ctx, order.OrderItems); err != nil {
+		return fmt.Errorf("order cancelled but stock was not released: %w", err)
+	}
+	return nil
 }
@@ -1,9 +1,16 @@
 package order
 
 import (
+	"context"
+	"net/http"
 	"testing"
 
+	"github.com/oguzhan/e-commerce/internal/clients"
+	"github.com/oguzhan/e-commerce/internal/events"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -35,3 +42,279 @@ func TestFindByID_NotFound(t *testing.T) {
 		t.Fatal("expected nil, got order")
 	}
 }
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	if err := db.AutoMigrate(append(events.Models(), &models.Address{}, &models.Product{}, &models.Order{}, &models.OrderItem{})...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	return db
+}
+
+func createTestAddress(t *testing.T, db *gorm.DB, userID uint, city string, isDefault bool) *models.Address {
+	address := &models.Address{
+		UserID:      userID,
+		Type:        models.AddressTypeHome,
+		Title:       "Home",
+		AddressLine: "Main Street 1",
+		City:        city,
+		Country:     "TR",
+		PostalCode:  "34000",
+		IsDefault:   isDefault,
+	}
+	if err := db.Create(address).Error; err != nil {
+		t.Fatalf("Failed to create test address: %v", err)
+	}
+	return address
+}
+
+func TestPlaceOrder_UsesDefaultAddress(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	createTestAddress(t, db, 1, "Ankara", false)
+	defaultAddress := createTestAddress(t, db, 1, "Istanbul", true)
+
+	order, err := service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Istanbul", order.ShippingDetails.City)
+	assert.Equal(t, "Istanbul", order.BillingDetails.City)
+	assert.Equal(t, defaultAddress.ID, *order.ShippingDetails.SourceAddressID)
+	assert.Equal(t, "Main Street 1, Istanbul, 34000, TR", order.ShippingAddress)
+}
+
+func TestPlaceOrder_AddressIDAndInline(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	address := createTestAddress(t, db, 1, "Izmir", false)
+
+	order, err := service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{
+		ShippingAddressID: &address.ID,
+		BillingAddress: &models.AddressInput{
+			AddressLine: "Office Road 5",
+			City:        "Bursa",
+			Country:     "TR",
+			PostalCode:  "16000",
+		},
+		PaymentMethod: "credit_card",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Izmir", order.ShippingDetails.City)
+	assert.Equal(t, "Bursa", order.BillingDetails.City)
+	assert.Nil(t, order.BillingDetails.SourceAddressID)
+}
+
+func TestPlaceOrder_RejectsForeignAddress(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	address := createTestAddress(t, db, 2, "Izmir", true)
+
+	_, err := service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{
+		ShippingAddressID: &address.ID,
+		PaymentMethod:     "credit_card",
+	})
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+
+	_, err = service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{PaymentMethod: "credit_card"})
+	assert.ErrorIs(t, err, ErrNoDefaultAddress)
+}
+
+func TestPlaceOrder_SnapshotSurvivesAddressBookChanges(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	address := createTestAddress(t, db, 1, "Istanbul", true)
+
+	order, err := service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Model(address).Update("city", "Antalya").Error)
+	assert.NoError(t, db.Delete(address).Error)
+	assert.NoError(t, service.UpdateOrder(order.ID, &models.Order{
+		ShippingAddress: "Somewhere else",
+		ShippingDetails: models.AddressSnapshot{City: "Konya"},
+		PaymentMethod:   "bank_transfer",
+	}))
+
+	stored, err := service.GetOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Istanbul", stored.ShippingDetails.City)
+	assert.Equal(t, "Main Street 1, Istanbul, 34000, TR", stored.ShippingAddress)
+	assert.Equal(t, "bank_transfer", stored.PaymentMethod)
+}
+
+func TestOrderEvents_PublishedWithStateChanges(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	createTestAddress(t, db, 1, "Istanbul", true)
+
+	order, err := service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{PaymentMethod: "credit_card", TotalAmount: 80})
+	assert.NoError(t, err)
+
+	assert.NoError(t, service.StartFulfilment(order.ID))
+	// A repeated payment event must not move the order again.
+	assert.NoError(t, service.StartFulfilment(order.ID))
+
+	var outbox []events.OutboxEvent
+	db.Order("id").Find(&outbox)
+	assert.Len(t, outbox, 2)
+	assert.Equal(t, events.NameOrderPlaced, outbox[0].Name)
+	assert.Equal(t, events.NameOrderStatusChanged, outbox[1].Name)
+	assert.JSONEq(t, `{"order_id":1,"user_id":1,"from":"pending","to":"processing"}`, outbox[1].Payload)
+}
+
+func TestPlaceOrder_PricesAndReservesThroughCatalog(t *testing.T) {
+	db := setupTestDB(t)
+	catalog := clients.NewFakeCatalog(
+		clients.Product{ID: 1, Price: 10, Stock: 5},
+		clients.Product{ID: 2, Price: 2.5, Stock: 1},
+	)
+	service := NewServiceWithCatalog(db, catalog)
+	createTestAddress(t, db, 1, "Istanbul", true)
+
+	order, err := service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{
+		PaymentMethod: "credit_card",
+		TotalAmount:   1,
+		OrderItems: []models.OrderItem{
+			{ProductID: 1, Quantity: 2, Price: 0.01},
+			{ProductID: 2, Quantity: 1},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 22.5, order.TotalAmount)
+	assert.Equal(t, 10.0, order.OrderItems[0].Price)
+	assert.Equal(t, 3, catalog.Stock(1))
+	assert.Equal(t, 0, catalog.Stock(2))
+
+	// The second item is sold out, so the first reservation is given back.
+	_, err = service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{
+		PaymentMethod: "credit_card",
+		OrderItems: []models.OrderItem{
+			{ProductID: 1, Quantity: 1},
+			{ProductID: 2, Quantity: 1},
+		},
+	})
+	assert.ErrorIs(t, err, ErrOutOfStock)
+	assert.Equal(t, 3, catalog.Stock(1))
+
+	_, err = service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{
+		PaymentMethod: "credit_card",
+		OrderItems:    []models.OrderItem{{ProductID: 9, Quantity: 1}},
+	})
+	assert.ErrorIs(t, err, ErrProductNotFound)
+
+	assert.NoError(t, service.CancelOrder(context.Background(), order.ID, 1))
+	assert.NoError(t, service.CancelOrder(context.Background(), order.ID, 1))
+	assert.Equal(t, 5, catalog.Stock(1))
+	assert.Equal(t, 1, catalog.Stock(2))
+}
+
+func TestPlaceGuestOrder_PricesTheGivenItems(t *testing.T) {
+	db := setupTestDB(t)
+	catalog := clients.NewFakeCatalog(clients.Product{ID: 1, Price: 10, Stock: 5})
+	address := &models.AddressInput{AddressLine: "1 Main St", City: "Istanbul", Country: "TR", PostalCode: "34000"}
+	request := &models.GuestOrderRequest{
+		Email:           "guest@example.com",
+		ShippingAddress: address,
+		BillingAddress:  address,
+		PaymentMethod:   "credit_card",
+	}
+
+	_, err := NewService(db).PlaceGuestOrder(context.Background(), request, []models.OrderItem{{ProductID: 1, Quantity: 1}})
+	assert.Error(t, err)
+
+	service := NewServiceWithCatalog(db, catalog)
+	order, err := service.PlaceGuestOrder(context.Background(), request, []models.OrderItem{{ProductID: 1, Quantity: 2, Price: 0.01}})
+	assert.NoError(t, err)
+	assert.Equal(t, "guest@example.com", order.GuestEmail)
+	assert.Equal(t, 20.0, order.TotalAmount)
+	assert.Equal(t, 3, catalog.Stock(1))
+
+	_, err = service.PlaceGuestOrder(context.Background(), request, nil)
+	assert.ErrorIs(t, err, ErrNoItems)
+	_, err = service.PlaceGuestOrder(context.Background(), &models.GuestOrderRequest{Email: "guest@example.com"}, order.OrderItems)
+	assert.ErrorIs(t, err, ErrGuestAddress)
+}
+
+func TestOrderStatus_CancellingReleasesStockOnce(t *testing.T) {
+	db := setupTestDB(t)
+	catalog := clients.NewFakeCatalog(clients.Product{ID: 1, Price: 10, Stock: 5})
+	service := NewServiceWithCatalog(db, catalog)
+	createTestAddress(t, db, 1, "Istanbul", true)
+	ctx := context.Background()
+	place := func() *models.Order {
+		order, err := service.PlaceOrder(ctx, 1, &models.CreateOrderRequest{
+			PaymentMethod: "credit_card",
+			OrderItems:    []models.OrderItem{{ProductID: 1, Quantity: 2}},
+		})
+		assert.NoError(t, err)
+		return order
+	}
+
+	// By the owner through the status endpoint
+	order := place()
+	assert.Equal(t, 3, catalog.Stock(1))
+	assert.NoError(t, service.UpdateOrderStatus(ctx, order.ID, 1, string(models.OrderStatusCancelled)))
+	assert.NoError(t, service.UpdateOrderStatus(ctx, order.ID, 1, string(models.OrderStatusCancelled)))
+	assert.NoError(t, service.CancelOrder(ctx, order.ID, 1))
+	assert.Equal(t, 5, catalog.Stock(1))
+
+	// By fulfilment; a cancelled order is not reopened
+	order = place()
+	assert.NoError(t, service.SetOrderStatus(ctx, order.ID, string(models.OrderStatusProcessing)))
+	assert.NoError(t, service.SetOrderStatus(ctx, order.ID, string(models.OrderStatusCancelled)))
+	assert.NoError(t, service.SetOrderStatus(ctx, order.ID, string(models.OrderStatusCancelled)))
+	assert.Equal(t, 5, catalog.Stock(1))
+	assert.ErrorIs(t, service.SetOrderStatus(ctx, order.ID, string(models.OrderStatusPending)), ErrOrderCancelled)
+
+	// A stale read loses to the cancellation that was stored first
+	order = place()
+	stale, err := service.GetOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, service.CancelOrder(ctx, order.ID, 1))
+	assert.NoError(t, service.changeStatus(ctx, stale, models.OrderStatusCancelled))
+	assert.Equal(t, 5, catalog.Stock(1))
+	stale.Status = models.OrderStatusPending
+	assert.ErrorIs(t, service.changeStatus(ctx, stale, models.OrderStatusShipped), ErrStatusChanged)
+}
+
+func TestUpdateOrderStatus_TypedErrors(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	createTestAddress(t, db, 1, "Istanbul", true)
+
+	order, err := service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{PaymentMethod: "credit_card", TotalAmount: 80})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, service.UpdateOrderStatus(context.Background(), 99, 1, string(models.OrderStatusShipped)), ErrOrderNotFound)
+	assert.ErrorIs(t, service.UpdateOrderStatus(context.Background(), order.ID, 2, string(models.OrderStatusShipped)), ErrNotOrderOwner)
+	assert.ErrorIs(t, service.UpdateOrderStatus(context.Background(), order.ID, 1, "lost"), ErrInvalidStatus)
+	assert.Equal(t, http.StatusNotFound, apperrors.From(service.UpdateOrderStatus(context.Background(), 99, 1, "shipped")).Status)
+	assert.ErrorIs(t, service.SetOrderStatus(context.Background(), order.ID, "lost"), ErrInvalidStatus)
+}
+
+func TestOrderStatus_OnlyFulfilmentShipsAndDelivers(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	createTestAddress(t, db, 1, "Istanbul", true)
+
+	order, err := service.PlaceOrder(context.Background(), 1, &models.CreateOrderRequest{PaymentMethod: "credit_card", TotalAmount: 80})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, service.UpdateOrderStatus(context.Background(), order.ID, 1, string(models.OrderStatusDelivered)), ErrStatusForbidden)
+	assert.NoError(t, service.UpdateOrder(order.ID, &models.Order{Status: models.OrderStatusDelivered, TotalAmount: 1, UserID: 2}))
+	stored, err := service.GetOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPending, stored.Status)
+	assert.Equal(t, 80.0, stored.TotalAmount)
+	assert.Equal(t, uint(1), stored.UserID)
+
+	assert.NoError(t, service.SetOrderStatus(context.Background(), order.ID, string(models.OrderStatusShipped)))
+	assert.NoError(t, service.UpdateOrderStatus(context.Background(), order.ID, 1, string(models.OrderStatusCancelled)))
+	stored, err = service.GetOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCancelled, stored.Status)
+}
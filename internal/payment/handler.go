@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
 	"github.com/oguzhan/e-commerce/pkg/models"
 )
 
@@ -19,15 +20,15 @@ func NewHandler(service *Service) *Handler {
 func (h *Handler) CreatePayment(c *gin.Context) {
 	var payment models.Payment
 	if err := c.ShouldBindJSON(&payment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	payment.UserID = userID
 
-	if err := h.service.CreatePayment(&payment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.CreatePayment(c.Request.Context(), &payment); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -37,13 +38,13 @@ func (h *Handler) CreatePayment(c *gin.Context) {
 func (h *Handler) ProcessPayment(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		middleware.WriteError(c, ErrInvalidPaymentID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	if err := h.service.ProcessPayment(uint(id), userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -53,19 +54,19 @@ func (h *Handler) ProcessPayment(c *gin.Context) {
 func (h *Handler) GetPayment(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		middleware.WriteError(c, ErrInvalidPaymentID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	payment, err := h.service.GetPaymentByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if payment.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		middleware.WriteError(c, ErrNotPaymentOwner)
 		return
 	}
 
@@ -76,7 +77,7 @@ func (h *Handler) GetUserPayments(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	payments, err := h.service.GetPaymentsByUserID(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -86,19 +87,19 @@ func (h *Handler) GetUserPayments(c *gin.Context) {
 func (h *Handler) GetOrderPayments(c *gin.Context) {
 	orderID, err := strconv.ParseUint(c.Param("order_id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		middleware.WriteError(c, ErrInvalidOrderID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	payments, err := h.service.GetPaymentsByOrderID(uint(orderID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if len(payments) > 0 && payments[0].UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		middleware.WriteError(c, ErrNotPaymentOwner)
 		return
 	}
 
@@ -108,13 +109,13 @@ func (h *Handler) GetOrderPayments(c *gin.Context) {
 func (h *Handler) RefundPayment(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		middleware.WriteError(c, ErrInvalidPaymentID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	if err := h.service.RefundPayment(uint(id), userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -134,7 +135,7 @@ func (h *Handler) ListPayments(c *gin.Context) {
 
 	payments, total, err := h.service.ListPayments(page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/internal/events"
 	"github.com/oguzhan/e-commerce/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -21,7 +22,7 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 		t.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.Order{}, &models.Payment{})
+	err = db.AutoMigrate(append(events.Models(), &models.Order{}, &models.Payment{})...)
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -1,45 +1,169 @@
 package payment
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"net/http"
+	"time"
 
+	"github.com/oguzhan/e-commerce/internal/clients"
+	"github.com/oguzhan/e-commerce/internal/events"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/metrics"
 	"github.com/oguzhan/e-commerce/pkg/models"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrOrderNotFound    = apperrors.New(http.StatusNotFound, "order_not_found", "order not found")
+	ErrAmountMismatch   = apperrors.New(http.StatusBadRequest, "amount_mismatch", "payment amount does not match order total")
+	ErrPaymentNotFound  = apperrors.New(http.StatusNotFound, "payment_not_found", "payment not found")
+	ErrNotPaymentOwner  = apperrors.New(http.StatusForbidden, "payment_forbidden", "the payment belongs to another user")
+	ErrInvalidPaymentID = apperrors.New(http.StatusBadRequest, "invalid_payment_id", "invalid payment ID")
+	ErrInvalidOrderID   = apperrors.New(http.StatusBadRequest, "invalid_order_id", "invalid order ID")
+
+	errNoOrders = errors.New("guest payments need an order lookup")
+)
+
+// OrderLookup is the order service as seen by payments.
+type OrderLookup interface {
+	GetOrder(ctx context.Context, id uint) (*models.Order, error)
+}
+
 type Service struct {
-	db *gorm.DB
+	db     *gorm.DB
+	orders OrderLookup
 }
 
 func NewService(db *gorm.DB) *Service {
 	return &Service{db: db}
 }
 
-func (s *Service) CreatePayment(payment *models.Payment) error {
-	return s.db.Create(payment).Error
+// NewServiceWithOrders creates a service that checks new payments against
+// the order they pay for, looked up through the order service or, in the
+// monolith, the local order.Service.
+func NewServiceWithOrders(db *gorm.DB, orders OrderLookup) *Service {
+	return &Service{db: db, orders: orders}
+}
+
+func (s *Service) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	if err := s.checkOrder(ctx, payment); err != nil {
+		return err
+	}
+	if payment.TransactionID == "" {
+		id, err := newTransactionID()
+		if err != nil {
+			return err
+		}
+		payment.TransactionID = id
+	}
+	if err := s.db.Create(payment).Error; err != nil {
+		return err
+	}
+	metrics.RecordPaymentCreated()
+	return nil
+}
+
+// PayGuestOrder pays a guest order in full right away. Guests have no
+// account to come back with, so the payment is created and processed in
+// one step; only orders without a user can be paid this way.
+func (s *Service) PayGuestOrder(ctx context.Context, orderID uint, paymentMethod string) (*models.Payment, error) {
+	if s.orders == nil {
+		return nil, errNoOrders
+	}
+	payment := &models.Payment{OrderID: orderID, PaymentMethod: paymentMethod}
+	if err := s.CreatePayment(ctx, payment); err != nil {
+		return nil, err
+	}
+	if err := s.ProcessPayment(payment.ID, 0); err != nil {
+		return nil, err
+	}
+	return s.GetPaymentByID(payment.ID)
+}
+
+// checkOrder makes sure the payment is for one of the payer's own orders and
+// fills in the order total when no amount was given.
+func (s *Service) checkOrder(ctx context.Context, payment *models.Payment) error {
+	if s.orders == nil {
+		return nil
+	}
+	order, err := s.orders.GetOrder(ctx, payment.OrderID)
+	if err != nil {
+		if errors.Is(err, clients.ErrNotFound) || errors.Is(err, clients.ErrForbidden) || errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		return err
+	}
+	if order.UserID != payment.UserID {
+		return ErrOrderNotFound
+	}
+	if payment.Amount == 0 {
+		payment.Amount = order.TotalAmount
+	} else if payment.Amount != order.TotalAmount {
+		return ErrAmountMismatch
+	}
+	return nil
+}
+
+func newTransactionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "txn_" + hex.EncodeToString(b), nil
 }
 
 func (s *Service) ProcessPayment(id, userID uint) error {
 	var payment models.Payment
 	if err := s.db.First(&payment, id).Error; err != nil {
-		return err
+		return notFound(err)
 	}
 
 	if payment.UserID != userID {
-		return errors.New("unauthorized")
+		return ErrNotPaymentOwner
 	}
 
-	return s.db.Model(&payment).Update("status", "completed").Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&payment).Updates(map[string]interface{}{
+			"status":       models.PaymentStatusCompleted,
+			"payment_date": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return events.Publish(tx, events.PaymentCaptured{
+			PaymentID:     payment.ID,
+			OrderID:       payment.OrderID,
+			UserID:        payment.UserID,
+			Amount:        payment.Amount,
+			PaymentMethod: payment.PaymentMethod,
+			TransactionID: payment.TransactionID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RecordPaymentProcessed(payment.Amount)
+	return nil
 }
 
 func (s *Service) GetPaymentByID(id uint) (*models.Payment, error) {
 	var payment models.Payment
 	if err := s.db.First(&payment, id).Error; err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 	return &payment, nil
 }
 
+// notFound turns a missing record into ErrPaymentNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPaymentNotFound.Wrap(err)
+	}
+	return err
+}
+
 func (s *Service) GetPaymentsByUserID(userID uint) ([]models.Payment, error) {
 	var payments []models.Payment
 	if err := s.db.Where("user_id = ?", userID).Find(&payments).Error; err != nil {
@@ -59,20 +183,39 @@ func (s *Service) GetPaymentsByOrderID(orderID uint) ([]models.Payment, error) {
 func (s *Service) RefundPayment(id, userID uint) error {
 	var payment models.Payment
 	if err := s.db.First(&payment, id).Error; err != nil {
-		return err
+		return notFound(err)
 	}
 
 	if payment.UserID != userID {
-		return errors.New("unauthorized")
+		return ErrNotPaymentOwner
 	}
 
-	return s.db.Model(&payment).Update("status", "refunded").Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&payment).Update("status", models.PaymentStatusRefunded).Error; err != nil {
+			return err
+		}
+		return events.Publish(tx, events.PaymentRefunded{
+			PaymentID:     payment.ID,
+			OrderID:       payment.OrderID,
+			UserID:        payment.UserID,
+			Amount:        payment.Amount,
+			TransactionID: payment.TransactionID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RecordRefund(payment.Amount)
+	return nil
 }
 
 func (s *Service) ListPayments(page, limit int) ([]models.Payment, int64, error) {
 	var payments []models.Payment
 	var total int64
-	if err := s.db.Limit(limit).Offset((page - 1) * limit).Find(&payments).Count(&total).Error; err != nil {
+	if err := s.db.Model(&models.Payment{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := s.db.Limit(limit).Offset((page - 1) * limit).Find(&payments).Error; err != nil {
 		return nil, 0, err
 	}
 	return payments, total, nil
@@ -1,8 +1,11 @@
 package payment
 
 import (
+	"context"
 	"testing"
 
+	"github.com/oguzhan/e-commerce/internal/clients"
+	"github.com/oguzhan/e-commerce/internal/events"
 	"github.com/oguzhan/e-commerce/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -16,7 +19,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// Auto migrate models
-	err = db.AutoMigrate(&models.Order{}, &models.Payment{})
+	err = db.AutoMigrate(append(events.Models(), &models.Order{}, &models.Payment{})...)
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -45,13 +48,52 @@ func TestCreatePayment(t *testing.T) {
 		PaymentMethod: "credit_card",
 	}
 
-	err := service.CreatePayment(payment)
+	err := service.CreatePayment(context.Background(), payment)
 	assert.NoError(t, err)
 	assert.NotZero(t, payment.ID)
 	assert.Equal(t, models.PaymentStatusPending, payment.Status)
 	assert.NotEmpty(t, payment.TransactionID)
 }
 
+func TestCreatePayment_ChecksOrderThroughOrderService(t *testing.T) {
+	db := setupTestDB(t)
+	orders := clients.NewFakeOrders(models.Order{Model: gorm.Model{ID: 1}, UserID: 1, TotalAmount: 75})
+	service := NewServiceWithOrders(db, orders)
+
+	payment := &models.Payment{OrderID: 1, UserID: 1, PaymentMethod: "credit_card"}
+	assert.NoError(t, service.CreatePayment(context.Background(), payment))
+	assert.Equal(t, 75.0, payment.Amount)
+
+	err := service.CreatePayment(context.Background(), &models.Payment{OrderID: 1, UserID: 1, Amount: 10, PaymentMethod: "credit_card"})
+	assert.ErrorIs(t, err, ErrAmountMismatch)
+
+	err = service.CreatePayment(context.Background(), &models.Payment{OrderID: 1, UserID: 2, PaymentMethod: "credit_card"})
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+
+	err = service.CreatePayment(context.Background(), &models.Payment{OrderID: 2, UserID: 1, PaymentMethod: "credit_card"})
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestPayGuestOrder(t *testing.T) {
+	db := setupTestDB(t)
+	orders := clients.NewFakeOrders(
+		models.Order{Model: gorm.Model{ID: 1}, UserID: 1, TotalAmount: 75},
+		models.Order{Model: gorm.Model{ID: 2}, GuestEmail: "guest@example.com", TotalAmount: 30},
+	)
+
+	_, err := NewService(db).PayGuestOrder(context.Background(), 2, "credit_card")
+	assert.Error(t, err)
+
+	service := NewServiceWithOrders(db, orders)
+	payment, err := service.PayGuestOrder(context.Background(), 2, "credit_card")
+	assert.NoError(t, err)
+	assert.Equal(t, 30.0, payment.Amount)
+	assert.Equal(t, models.PaymentStatusCompleted, payment.Status)
+
+	_, err = service.PayGuestOrder(context.Background(), 1, "credit_card")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
 func TestProcessPayment(t *testing.T) {
 	db := setupTestDB(t)
 	service := NewService(db)
@@ -63,7 +105,7 @@ func TestProcessPayment(t *testing.T) {
 		Amount:        100.00,
 		PaymentMethod: "credit_card",
 	}
-	err := service.CreatePayment(payment)
+	err := service.CreatePayment(context.Background(), payment)
 	assert.NoError(t, err)
 
 	// Process the payment
@@ -87,7 +129,7 @@ func TestGetPaymentByID(t *testing.T) {
 		Amount:        100.00,
 		PaymentMethod: "credit_card",
 	}
-	err := service.CreatePayment(payment)
+	err := service.CreatePayment(context.Background(), payment)
 	assert.NoError(t, err)
 
 	// Get the payment
@@ -128,7 +170,7 @@ func TestGetPaymentsByUserID(t *testing.T) {
 	}
 
 	for _, payment := range payments {
-		err := service.CreatePayment(payment)
+		err := service.CreatePayment(context.Background(), payment)
 		assert.NoError(t, err)
 	}
 
@@ -149,7 +191,7 @@ func TestRefundPayment(t *testing.T) {
 		Amount:        100.00,
 		PaymentMethod: "credit_card",
 	}
-	err := service.CreatePayment(payment)
+	err := service.CreatePayment(context.Background(), payment)
 	assert.NoError(t, err)
 
 	err = service.ProcessPayment(payment.ID, payment.UserID)
@@ -176,7 +218,7 @@ func TestListPayments(t *testing.T) {
 		Amount:        100.00,
 		PaymentMethod: "credit_card",
 	}
-	err := service.CreatePayment(payment)
+	err := service.CreatePayment(context.Background(), payment)
 	assert.NoError(t, err)
 
 	// Create multiple orders and payments
@@ -196,7 +238,7 @@ func TestListPayments(t *testing.T) {
 			Amount:        float64(i * 100),
 			PaymentMethod: "credit_card",
 		}
-		err = service.CreatePayment(payment)
+		err = service.CreatePayment(context.Background(), payment)
 		assert.NoError(t, err)
 	}
 
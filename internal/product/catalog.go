@@ -0,0 +1,58 @@
+package product
+
+import (
+	"context"
+	"errors"
+
+	"github.com/oguzhan/e-commerce/internal/clients"
+)
+
+// Catalog prices order items and reserves stock for orders placed in the
+// same process, as clients.ProductClient does for the order service. It
+// answers with the same errors, so orders handle both alike.
+type Catalog struct {
+	service *Service
+}
+
+func NewCatalog(service *Service) *Catalog {
+	return &Catalog{service: service}
+}
+
+// GetProduct reads the product from the database rather than the cache, so
+// orders are always priced at the current price. Inactive products cannot
+// be ordered and are reported as not found.
+func (c *Catalog) GetProduct(ctx context.Context, id uint) (*clients.Product, error) {
+	product, err := c.service.loadProduct(id)
+	if err != nil {
+		return nil, catalogError(err)
+	}
+	if !product.IsActive {
+		return nil, clients.ErrNotFound
+	}
+	return &clients.Product{
+		ID:          product.ID,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Stock:       product.Stock,
+		Category:    product.Category,
+	}, nil
+}
+
+func (c *Catalog) ReserveStock(ctx context.Context, id uint, quantity int) error {
+	return catalogError(c.service.ReserveStock(id, quantity))
+}
+
+func (c *Catalog) ReleaseStock(ctx context.Context, id uint, quantity int) error {
+	return catalogError(c.service.ReleaseStock(id, quantity))
+}
+
+func catalogError(err error) error {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		return clients.ErrNotFound
+	case errors.Is(err, ErrOutOfStock):
+		return clients.ErrConflict
+	}
+	return err
+}
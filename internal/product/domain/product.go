@@ -1,7 +1,16 @@
 package domain
 
 import (
+	"net/http"
 	"time"
+
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+)
+
+var (
+	ErrProductNotFound   = apperrors.New(http.StatusNotFound, "product_not_found", "product not found")
+	ErrInsufficientStock = apperrors.New(http.StatusConflict, "insufficient_stock", "insufficient stock")
+	ErrInvalidQuantity   = apperrors.New(http.StatusBadRequest, "invalid_quantity", "quantity must be greater than zero")
 )
 
 type Product struct {
@@ -22,6 +31,7 @@ type ProductRepository interface {
 	Update(product *Product) error
 	Delete(id uint) error
 	UpdateStock(id uint, quantity int) error
+	ReserveStock(id uint, quantity int) error
 }
 
 type ProductService interface {
@@ -31,4 +41,6 @@ type ProductService interface {
 	UpdateProduct(product *Product) error
 	DeleteProduct(id uint) error
 	UpdateStock(id uint, quantity int) error
+	ReserveStock(id uint, quantity int) error
+	ReleaseStock(id uint, quantity int) error
 }
@@ -1,10 +1,15 @@
 package product
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
 	"github.com/oguzhan/e-commerce/pkg/models"
 )
 
@@ -19,12 +24,12 @@ func NewHandler(service *Service) *Handler {
 func (h *Handler) CreateProduct(c *gin.Context) {
 	var product models.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.CreateProduct(&product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -34,17 +39,17 @@ func (h *Handler) CreateProduct(c *gin.Context) {
 func (h *Handler) GetProduct(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		middleware.WriteError(c, ErrInvalidProductID)
 		return
 	}
 
 	product, err := h.service.GetProductByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, product)
+	writeWithETag(c, product.ToResponse())
 }
 
 func (h *Handler) ListProducts(c *gin.Context) {
@@ -58,35 +63,66 @@ func (h *Handler) ListProducts(c *gin.Context) {
 		limit = 10
 	}
 
-	products, total, err := h.service.ListProducts(page, limit)
+	products, total, err := h.service.ListProducts(c.Request.Context(), page, limit, c.Query("sort"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"products": products,
+	writeWithETag(c, gin.H{
+		"products": models.NewProductResponses(products),
 		"total":    total,
 		"page":     page,
 		"limit":    limit,
 	})
 }
 
+// writeWithETag answers 200 with body and its ETag, or 304 when the client
+// already holds the same representation.
+func writeWithETag(c *gin.Context, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// etagMatches applies the weak comparison used for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *Handler) UpdateProduct(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		middleware.WriteError(c, ErrInvalidProductID)
 		return
 	}
 
 	var product models.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.UpdateProduct(uint(id), &product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -96,12 +132,12 @@ func (h *Handler) UpdateProduct(c *gin.Context) {
 func (h *Handler) DeleteProduct(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		middleware.WriteError(c, ErrInvalidProductID)
 		return
 	}
 
 	if err := h.service.DeleteProduct(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -111,7 +147,7 @@ func (h *Handler) DeleteProduct(c *gin.Context) {
 func (h *Handler) UpdateStock(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		middleware.WriteError(c, ErrInvalidProductID)
 		return
 	}
 
@@ -120,12 +156,12 @@ func (h *Handler) UpdateStock(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.UpdateStock(uint(id), request.Quantity); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -138,11 +174,11 @@ func (h *Handler) SearchProducts(c *gin.Context) {
 	minPrice, _ := strconv.ParseFloat(c.Query("min_price"), 64)
 	maxPrice, _ := strconv.ParseFloat(c.Query("max_price"), 64)
 
-	products, err := h.service.SearchProducts(query, category, minPrice, maxPrice)
+	products, err := h.service.SearchProducts(c.Request.Context(), query, category, minPrice, maxPrice)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	c.JSON(http.StatusOK, models.NewProductResponses(products))
 }
@@ -1,14 +1,37 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/oguzhan/e-commerce/internal/clients"
 	"github.com/oguzhan/e-commerce/internal/product/domain"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 )
 
+var (
+	ErrInvalidProductID = apperrors.New(http.StatusBadRequest, "invalid_product_id", "invalid product ID")
+	ErrInvalidAPIKey    = apperrors.New(http.StatusUnauthorized, "invalid_api_key", "a valid service API key is required")
+)
+
+// RequireAPIKey only lets through requests that carry key in the
+// clients.APIKeyHeader. With an empty key every request is refused.
+func RequireAPIKey(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sent := r.Header.Get(clients.APIKeyHeader)
+			if key == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(key)) != 1 {
+				apperrors.WriteProblem(w, r, ErrInvalidAPIKey)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 type ProductHandler struct {
 	service domain.ProductService
 }
@@ -20,12 +43,12 @@ func NewProductHandler(service domain.ProductService) *ProductHandler {
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var product domain.Product
 	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
 	if err := h.service.CreateProduct(&product); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -37,13 +60,13 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, ErrInvalidProductID)
 		return
 	}
 
 	product, err := h.service.GetProduct(uint(id))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -53,7 +76,7 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
 	products, err := h.service.GetAllProducts()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -64,19 +87,19 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, ErrInvalidProductID)
 		return
 	}
 
 	var product domain.Product
 	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
 	product.ID = uint(id)
 	if err := h.service.UpdateProduct(&product); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -87,12 +110,12 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, ErrInvalidProductID)
 		return
 	}
 
 	if err := h.service.DeleteProduct(uint(id)); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -103,7 +126,7 @@ func (h *ProductHandler) UpdateStock(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, ErrInvalidProductID)
 		return
 	}
 
@@ -111,14 +134,50 @@ func (h *ProductHandler) UpdateStock(w http.ResponseWriter, r *http.Request) {
 		Quantity int `json:"quantity"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
 	if err := h.service.UpdateStock(uint(id), request.Quantity); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// ReserveStock takes stock for an order. It fails with 409 Conflict when
+// fewer than the requested units are left.
+func (h *ProductHandler) ReserveStock(w http.ResponseWriter, r *http.Request) {
+	h.changeReservation(w, r, h.service.ReserveStock)
+}
+
+// ReleaseStock returns stock taken by ReserveStock, for example when the
+// order could not be placed or was cancelled.
+func (h *ProductHandler) ReleaseStock(w http.ResponseWriter, r *http.Request) {
+	h.changeReservation(w, r, h.service.ReleaseStock)
+}
+
+func (h *ProductHandler) changeReservation(w http.ResponseWriter, r *http.Request, change func(id uint, quantity int) error) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		apperrors.WriteProblem(w, r, ErrInvalidProductID)
+		return
+	}
+
+	var request struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		apperrors.WriteProblem(w, r, err)
+		return
+	}
+
+	if err := change(uint(id), request.Quantity); err != nil {
+		apperrors.WriteProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oguzhan/e-commerce/internal/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		key  string
+		sent string
+		want int
+	}{
+		{"secret", "secret", http.StatusOK},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "guess", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/products/1/reserve", nil)
+		if tt.sent != "" {
+			req.Header.Set(clients.APIKeyHeader, tt.sent)
+		}
+		w := httptest.NewRecorder()
+		RequireAPIKey(tt.key)(ok).ServeHTTP(w, req)
+		assert.Equal(t, tt.want, w.Code, "key %q, sent %q", tt.key, tt.sent)
+	}
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
 	"time"
 
 	"github.com/oguzhan/e-commerce/internal/product/domain"
@@ -57,7 +56,7 @@ func (r *productRepository) GetByID(id uint) (*domain.Product, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("product not found")
+		return nil, domain.ErrProductNotFound
 	}
 
 	return product, err
@@ -132,3 +131,28 @@ func (r *productRepository) UpdateStock(id uint, quantity int) error {
 	_, err := r.db.Exec(query, quantity, time.Now(), id)
 	return err
 }
+
+// ReserveStock takes quantity units out of stock in a single statement, so
+// concurrent reservations can never drive the stock below zero.
+func (r *productRepository) ReserveStock(id uint, quantity int) error {
+	query := `
+		UPDATE products
+		SET stock = stock - $1, updated_at = $2
+		WHERE id = $3 AND stock >= $1`
+
+	result, err := r.db.Exec(query, quantity, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		if _, err := r.GetByID(id); err != nil {
+			return err
+		}
+		return domain.ErrInsufficientStock
+	}
+	return nil
+}
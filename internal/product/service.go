@@ -1,70 +1,294 @@
 package product
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/pkg/cache"
+	"github.com/oguzhan/e-commerce/pkg/database"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 	"github.com/oguzhan/e-commerce/pkg/models"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrProductNotFound  = apperrors.New(http.StatusNotFound, "product_not_found", "product not found")
+	ErrInvalidProductID = apperrors.New(http.StatusBadRequest, "invalid_product_id", "invalid product ID")
+	ErrInvalidQuantity  = apperrors.New(http.StatusBadRequest, "invalid_quantity", "quantity must be greater than zero")
+	ErrOutOfStock       = apperrors.New(http.StatusConflict, "out_of_stock", "not enough stock for the requested quantity")
+)
+
+// ratingColumns are maintained by the review package and never written
+// through product create or update requests.
+var ratingColumns = []string{"rating_average", "rating_count"}
+
+// sortOrders maps the sort query values accepted by ListProducts to ORDER BY
+// clauses.
+var sortOrders = map[string]string{
+	"newest":     "created_at DESC",
+	"price_asc":  "price ASC",
+	"price_desc": "price DESC",
+	"rating":     "rating_average DESC, rating_count DESC",
+}
+
+// LowStockThreshold is the stock level at or below which StockLow is
+// published. The event fires once when stock drops past it.
+const LowStockThreshold = 5
+
+// listGenerationKey holds a value that is part of every cached list key.
+// Changing it on writes orphans all cached pages at once.
+const listGenerationKey = "products:generation"
+
 type Service struct {
-	db *gorm.DB
+	db    *gorm.DB
+	cache *cache.ReadThrough
 }
 
 func NewService(db *gorm.DB) *Service {
 	return &Service{db: db}
 }
 
+// NewServiceWithCache serves GetProductByID and ListProducts through the
+// given cache and invalidates it on every write.
+func NewServiceWithCache(db *gorm.DB, readThrough *cache.ReadThrough) *Service {
+	return &Service{db: db, cache: readThrough}
+}
+
+// productPage is the cached form of a ListProducts result.
+type productPage struct {
+	Products []models.Product `json:"products"`
+	Total    int64            `json:"total"`
+}
+
+func productKey(id uint) cache.Key[models.Product] {
+	return cache.Key[models.Product](fmt.Sprintf("product:%d", id))
+}
+
+func listKey(generation int64, page, limit int, sort string) cache.Key[productPage] {
+	return cache.Key[productPage](fmt.Sprintf("products:%d:%d:%d:%s", generation, page, limit, sort))
+}
+
 func (s *Service) CreateProduct(product *models.Product) error {
-	return s.db.Create(product).Error
+	if err := s.db.Omit(ratingColumns...).Create(product).Error; err != nil {
+		return err
+	}
+	s.invalidate(0)
+	return nil
 }
 
 func (s *Service) GetProductByID(id uint) (*models.Product, error) {
+	if s.cache == nil {
+		return s.loadProduct(id)
+	}
+	product, err := cache.Fetch(context.Background(), s.cache, productKey(id), func(context.Context) (models.Product, error) {
+		product, err := s.loadProduct(id)
+		if err != nil {
+			return models.Product{}, err
+		}
+		return *product, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (s *Service) loadProduct(id uint) (*models.Product, error) {
 	var product models.Product
 	if err := s.db.First(&product, id).Error; err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 	return &product, nil
 }
 
+// notFound turns a missing record into ErrProductNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrProductNotFound.Wrap(err)
+	}
+	return err
+}
+
 func (s *Service) GetProductBySKU(sku string) (*models.Product, error) {
 	var product models.Product
 	if err := s.db.Where("sku = ?", sku).First(&product).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("product not found")
-		}
-		return nil, err
+		return nil, notFound(err)
 	}
 	return &product, nil
 }
 
 func (s *Service) UpdateProduct(id uint, product *models.Product) error {
-	return s.db.Model(&models.Product{}).Where("id = ?", id).Updates(product).Error
+	defer s.invalidate(id)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Product
+		if err := database.ForUpdate(tx).First(&existing, id).Error; err != nil {
+			return notFound(err)
+		}
+		if err := tx.Model(&existing).Omit(ratingColumns...).Updates(product).Error; err != nil {
+			return err
+		}
+		if product.Stock == 0 {
+			// Zero fields are not written by Updates.
+			return nil
+		}
+		return publishStockLow(tx, &existing, product.Stock)
+	})
 }
 
 func (s *Service) DeleteProduct(id uint) error {
+	defer s.invalidate(id)
 	return s.db.Delete(&models.Product{}, id).Error
 }
 
-func (s *Service) ListProducts(page, limit int) ([]models.Product, int64, error) {
+// ListProducts reads from a replica when there is one, so a page may lag
+// the latest writes by the replication delay. Cached pages are filled from
+// the primary instead: a lagging page would otherwise be served for the
+// whole TTL after the write that invalidated it.
+func (s *Service) ListProducts(ctx context.Context, page, limit int, sort string) ([]models.Product, int64, error) {
+	if s.cache == nil {
+		return s.loadProducts(ctx, page, limit, sort)
+	}
+	key := listKey(s.listGeneration(ctx), page, limit, sort)
+	result, err := cache.Fetch(ctx, s.cache, key, func(context.Context) (productPage, error) {
+		products, total, err := s.loadProducts(database.WithPrimary(ctx), page, limit, sort)
+		return productPage{Products: products, Total: total}, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return result.Products, result.Total, nil
+}
+
+func (s *Service) loadProducts(ctx context.Context, page, limit int, sort string) ([]models.Product, int64, error) {
 	var products []models.Product
 	var total int64
-	if err := s.db.Limit(limit).Offset((page - 1) * limit).Find(&products).Count(&total).Error; err != nil {
+
+	query := database.Read(s.db.WithContext(ctx)).Model(&models.Product{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if order, ok := sortOrders[sort]; ok {
+		query = query.Order(order)
+	}
+	if err := query.Order("id").Limit(limit).Offset((page - 1) * limit).Find(&products).Error; err != nil {
 		return nil, 0, err
 	}
 	return products, total, nil
 }
 
 func (s *Service) UpdateStock(id uint, quantity int) error {
-	return s.db.Model(&models.Product{}).Where("id = ?", id).Update("stock", quantity).Error
+	defer s.invalidate(id)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := database.ForUpdate(tx).First(&product, id).Error; err != nil {
+			return notFound(err)
+		}
+		if err := tx.Model(&product).Update("stock", quantity).Error; err != nil {
+			return err
+		}
+		return publishStockLow(tx, &product, quantity)
+	})
+}
+
+// ReserveStock takes quantity units out of stock for an order. The
+// conditional update keeps concurrent reservations from driving the stock
+// below zero.
+func (s *Service) ReserveStock(id uint, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	defer s.invalidate(id)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := database.ForUpdate(tx).First(&product, id).Error; err != nil {
+			return notFound(err)
+		}
+		result := tx.Model(&product).Where("stock >= ?", quantity).
+			Update("stock", gorm.Expr("stock - ?", quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOutOfStock
+		}
+		return publishStockLow(tx, &product, product.Stock-quantity)
+	})
+}
+
+// ReleaseStock puts units taken by ReserveStock back into stock.
+func (s *Service) ReleaseStock(id uint, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	defer s.invalidate(id)
+	result := s.db.Model(&models.Product{}).Where("id = ?", id).
+		Update("stock", gorm.Expr("stock + ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// listGeneration returns the current list generation, starting a new one
+// if it was evicted so pages cached under an older value are never reused.
+func (s *Service) listGeneration(ctx context.Context) int64 {
+	var generation int64
+	if err := s.cache.Cache().Get(ctx, listGenerationKey, &generation); err == nil {
+		return generation
+	}
+	generation = time.Now().UnixNano()
+	_ = s.cache.Cache().Set(ctx, listGenerationKey, generation, 0)
+	return generation
+}
+
+// invalidate drops the cached product, if id is set, and every cached
+// page. Writers defer it so it runs after the transaction has committed.
+func (s *Service) invalidate(id uint) {
+	if s.cache == nil {
+		return
+	}
+	ctx := context.Background()
+	if id != 0 {
+		_ = s.cache.Invalidate(ctx, string(productKey(id)))
+	}
+	_ = s.cache.Cache().Set(ctx, listGenerationKey, time.Now().UnixNano(), 0)
+}
+
+// InvalidateProduct drops the cached product and every cached page, for
+// writers outside this service such as the review service's ratings.
+func (s *Service) InvalidateProduct(id uint) {
+	s.invalidate(id)
+}
+
+// publishStockLow publishes StockLow when a stock change takes the product
+// from above the threshold to at or below it.
+func publishStockLow(tx *gorm.DB, product *models.Product, stock int) error {
+	if product.Stock <= LowStockThreshold || stock > LowStockThreshold {
+		return nil
+	}
+	return events.Publish(tx, events.StockLow{
+		ProductID: product.ID,
+		SKU:       product.SKU,
+		Stock:     stock,
+		Threshold: LowStockThreshold,
+	})
 }
 
-func (s *Service) SearchProducts(query, category string, minPrice, maxPrice float64) ([]models.Product, error) {
+func (s *Service) SearchProducts(ctx context.Context, query, category string, minPrice, maxPrice float64) ([]models.Product, error) {
 	var products []models.Product
-	db := s.db
+	db := database.Read(s.db.WithContext(ctx))
 
 	if query != "" {
-		db = db.Where("name LIKE ? OR description LIKE ?", "%"+query+"%", "%"+query+"%")
+		pattern := "%" + query + "%"
+		db = db.Where(database.ILike(db, "name")+" OR "+database.ILike(db, "description"), pattern, pattern)
 	}
 
 	if category != "" {
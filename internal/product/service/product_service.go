@@ -1,9 +1,8 @@
 package service
 
 import (
-	"errors"
-
 	"github.com/oguzhan/e-commerce/internal/product/domain"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 )
 
 type productService struct {
@@ -14,15 +13,27 @@ func NewProductService(repo domain.ProductRepository) domain.ProductService {
 	return &productService{repo: repo}
 }
 
-func (s *productService) CreateProduct(product *domain.Product) error {
+// validate reports every invalid field of product at once.
+func validate(product *domain.Product) error {
+	var fields []apperrors.FieldError
 	if product.Name == "" {
-		return errors.New("product name is required")
+		fields = append(fields, apperrors.FieldError{Field: "name", Message: "is required"})
 	}
 	if product.Price <= 0 {
-		return errors.New("product price must be greater than zero")
+		fields = append(fields, apperrors.FieldError{Field: "price", Message: "must be greater than 0"})
 	}
 	if product.Stock < 0 {
-		return errors.New("product stock cannot be negative")
+		fields = append(fields, apperrors.FieldError{Field: "stock", Message: "must be greater than or equal to 0"})
+	}
+	if len(fields) > 0 {
+		return apperrors.ErrValidation.WithFields(fields...)
+	}
+	return nil
+}
+
+func (s *productService) CreateProduct(product *domain.Product) error {
+	if err := validate(product); err != nil {
+		return err
 	}
 
 	return s.repo.Create(product)
@@ -37,14 +48,8 @@ func (s *productService) GetAllProducts() ([]*domain.Product, error) {
 }
 
 func (s *productService) UpdateProduct(product *domain.Product) error {
-	if product.Name == "" {
-		return errors.New("product name is required")
-	}
-	if product.Price <= 0 {
-		return errors.New("product price must be greater than zero")
-	}
-	if product.Stock < 0 {
-		return errors.New("product stock cannot be negative")
+	if err := validate(product); err != nil {
+		return err
 	}
 
 	existingProduct, err := s.repo.GetByID(product.ID)
@@ -74,8 +79,25 @@ func (s *productService) UpdateStock(id uint, quantity int) error {
 
 	newStock := product.Stock + quantity
 	if newStock < 0 {
-		return errors.New("insufficient stock")
+		return domain.ErrInsufficientStock
 	}
 
 	return s.repo.UpdateStock(id, quantity)
 }
+
+func (s *productService) ReserveStock(id uint, quantity int) error {
+	if quantity <= 0 {
+		return domain.ErrInvalidQuantity
+	}
+	return s.repo.ReserveStock(id, quantity)
+}
+
+func (s *productService) ReleaseStock(id uint, quantity int) error {
+	if quantity <= 0 {
+		return domain.ErrInvalidQuantity
+	}
+	if _, err := s.repo.GetByID(id); err != nil {
+		return err
+	}
+	return s.repo.UpdateStock(id, quantity)
+}
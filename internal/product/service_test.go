@@ -1,9 +1,22 @@
 package product
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/internal/clients"
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/pkg/cache"
+	"github.com/oguzhan/e-commerce/pkg/config"
+	"github.com/oguzhan/e-commerce/pkg/database"
 	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -35,3 +48,174 @@ func TestFindByID_NotFound(t *testing.T) {
 		t.Fatal("expected nil, got product")
 	}
 }
+
+func TestListProducts_SortByRating(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Product{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, product := range []models.Product{
+		{Name: "Low", SKU: "P-1", Price: 10, RatingAverage: 3.2, RatingCount: 10},
+		{Name: "High", SKU: "P-2", Price: 10, RatingAverage: 4.8, RatingCount: 2},
+		{Name: "High, more reviews", SKU: "P-3", Price: 10, RatingAverage: 4.8, RatingCount: 7},
+	} {
+		db.Create(&product)
+	}
+
+	products, total, err := NewService(db).ListProducts(context.Background(), 1, 2, "rating")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, products, 2)
+	assert.Equal(t, "P-3", products[0].SKU)
+	assert.Equal(t, "P-2", products[1].SKU)
+}
+
+func setupCachedService(t *testing.T) (*gorm.DB, *Service) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(append(events.Models(), &models.Product{})...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	readThrough := cache.NewReadThrough("products", cache.NewLRUCache(100), time.Minute, 0)
+	return db, NewServiceWithCache(db, readThrough)
+}
+
+func TestCachedService_InvalidatesOnWrites(t *testing.T) {
+	db, service := setupCachedService(t)
+	product := &models.Product{Name: "Lamp", SKU: "L-1", Price: 20, Stock: 10}
+	assert.NoError(t, service.CreateProduct(product))
+
+	cached, err := service.GetProductByID(product.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lamp", cached.Name)
+	products, total, err := service.ListProducts(context.Background(), 1, 10, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// Writes that bypass the service are not seen until the entry expires.
+	db.Model(&models.Product{}).Where("id = ?", product.ID).Update("name", "Desk lamp")
+	cached, _ = service.GetProductByID(product.ID)
+	assert.Equal(t, "Lamp", cached.Name)
+
+	assert.NoError(t, service.UpdateStock(product.ID, 3))
+	cached, _ = service.GetProductByID(product.ID)
+	assert.Equal(t, "Desk lamp", cached.Name)
+	assert.Equal(t, 3, cached.Stock)
+	products, _, _ = service.ListProducts(context.Background(), 1, 10, "")
+	assert.Equal(t, 3, products[0].Stock)
+
+	assert.NoError(t, service.UpdateProduct(product.ID, &models.Product{Price: 25}))
+	cached, _ = service.GetProductByID(product.ID)
+	assert.Equal(t, 25.0, cached.Price)
+
+	assert.NoError(t, service.CreateProduct(&models.Product{Name: "Chair", SKU: "C-1", Price: 40, Stock: 2}))
+	_, total, _ = service.ListProducts(context.Background(), 1, 10, "")
+	assert.Equal(t, int64(2), total)
+
+	assert.NoError(t, service.DeleteProduct(product.ID))
+	_, err = service.GetProductByID(product.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, total, _ = service.ListProducts(context.Background(), 1, 10, "")
+	assert.Equal(t, int64(1), total)
+}
+
+func TestGetProduct_ETag(t *testing.T) {
+	_, service := setupCachedService(t)
+	product := &models.Product{Name: "Lamp", SKU: "L-1", Price: 20, Stock: 10}
+	assert.NoError(t, service.CreateProduct(product))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/products/:id", NewHandler(service).GetProduct)
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	w = get(`"other", W/` + etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	assert.NoError(t, service.UpdateStock(product.ID, 9))
+	w = get(etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+func TestListProducts_FillsCacheFromPrimary(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DBDriver = database.SQLite
+	cfg.DBPath = filepath.Join(dir, "primary.db")
+	cfg.DBReplicas = []string{filepath.Join(dir, "replica.db")}
+
+	// The replica lags behind: it has not seen the product yet.
+	replica, err := gorm.Open(sqlite.Open(cfg.DBReplicas[0]), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, replica.AutoMigrate(&models.Product{}))
+	assert.NoError(t, database.Close(replica))
+
+	db, err := database.InitDB(cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Cleanup(func() { database.Close(db) })
+	assert.NoError(t, db.AutoMigrate(&models.Product{}))
+	assert.NoError(t, db.Create(&models.Product{Name: "Lamp", SKU: "L-1", Price: 20}).Error)
+
+	_, total, err := NewService(db).ListProducts(context.Background(), 1, 10, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	readThrough := cache.NewReadThrough("products", cache.NewLRUCache(100), time.Minute, 0)
+	products, total, err := NewServiceWithCache(db, readThrough).ListProducts(context.Background(), 1, 10, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, products, 1)
+}
+
+func TestCatalog_PricesAndReservesStock(t *testing.T) {
+	db, service := setupCachedService(t)
+	catalog := NewCatalog(service)
+	ctx := context.Background()
+	product := &models.Product{Name: "Lamp", SKU: "L-1", Price: 20, Stock: 3}
+	assert.NoError(t, service.CreateProduct(product))
+	cached, _ := service.GetProductByID(product.ID)
+	assert.Equal(t, 3, cached.Stock)
+
+	priced, err := catalog.GetProduct(ctx, product.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, priced.Price)
+
+	assert.ErrorIs(t, catalog.ReserveStock(ctx, product.ID, 4), clients.ErrConflict)
+	assert.NoError(t, catalog.ReserveStock(ctx, product.ID, 2))
+	cached, _ = service.GetProductByID(product.ID)
+	assert.Equal(t, 1, cached.Stock)
+	assert.NoError(t, catalog.ReleaseStock(ctx, product.ID, 2))
+	cached, _ = service.GetProductByID(product.ID)
+	assert.Equal(t, 3, cached.Stock)
+
+	_, err = catalog.GetProduct(ctx, 99)
+	assert.ErrorIs(t, err, clients.ErrNotFound)
+	assert.ErrorIs(t, catalog.ReserveStock(ctx, 99, 1), clients.ErrNotFound)
+	assert.ErrorIs(t, catalog.ReleaseStock(ctx, 99, 1), clients.ErrNotFound)
+
+	db.Model(&models.Product{}).Where("id = ?", product.ID).Update("is_active", false)
+	_, err = catalog.GetProduct(ctx, product.ID)
+	assert.ErrorIs(t, err, clients.ErrNotFound)
+}
@@ -0,0 +1,188 @@
+package review
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func parseID(c *gin.Context, invalid error) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.WriteError(c, invalid)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 {
+		limit = 10
+	}
+	return page, limit
+}
+
+func listResponse(c *gin.Context, reviews []Review, total int64, page, limit int) {
+	c.JSON(http.StatusOK, gin.H{
+		"reviews": reviews,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// ListProductReviews serves the approved reviews of the product named by the
+// :id path parameter.
+func (h *Handler) ListProductReviews(c *gin.Context) {
+	productID, ok := parseID(c, ErrInvalidProductID)
+	if !ok {
+		return
+	}
+	page, limit := pagination(c)
+	reviews, total, err := h.service.ListProductReviews(productID, page, limit, c.Query("sort"))
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	listResponse(c, reviews, total, page, limit)
+}
+
+// CreateReview reviews the product named by the :id path parameter.
+func (h *Handler) CreateReview(c *gin.Context) {
+	productID, ok := parseID(c, ErrInvalidProductID)
+	if !ok {
+		return
+	}
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	review, err := h.service.CreateReview(c.GetUint("user_id"), productID, &req)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, review)
+}
+
+func (h *Handler) UpdateReview(c *gin.Context) {
+	id, ok := parseID(c, ErrInvalidReviewID)
+	if !ok {
+		return
+	}
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	review, err := h.service.UpdateReview(c.GetUint("user_id"), id, &req)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+func (h *Handler) DeleteReview(c *gin.Context) {
+	id, ok := parseID(c, ErrInvalidReviewID)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteReview(c.GetUint("user_id"), id); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) VoteHelpful(c *gin.Context) {
+	id, ok := parseID(c, ErrInvalidReviewID)
+	if !ok {
+		return
+	}
+	review, err := h.service.VoteHelpful(c.GetUint("user_id"), id)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+func (h *Handler) RemoveHelpfulVote(c *gin.Context) {
+	id, ok := parseID(c, ErrInvalidReviewID)
+	if !ok {
+		return
+	}
+	review, err := h.service.RemoveHelpfulVote(c.GetUint("user_id"), id)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+func (h *Handler) ListReviews(c *gin.Context) {
+	page, limit := pagination(c)
+	reviews, total, err := h.service.ListReviews(Status(c.Query("status")), page, limit)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	listResponse(c, reviews, total, page, limit)
+}
+
+func (h *Handler) ModerateReview(c *gin.Context) {
+	id, ok := parseID(c, ErrInvalidReviewID)
+	if !ok {
+		return
+	}
+	var req struct {
+		Status Status `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	review, err := h.service.Moderate(id, req.Status)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+func (h *Handler) ReplyToReview(c *gin.Context) {
+	id, ok := parseID(c, ErrInvalidReviewID)
+	if !ok {
+		return
+	}
+	var req struct {
+		Reply string `json:"reply" binding:"max=5000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	review, err := h.service.Reply(id, req.Reply)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
@@ -0,0 +1,57 @@
+package review
+
+import (
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+func (s Status) Valid() bool {
+	switch s {
+	case StatusPending, StatusApproved, StatusRejected:
+		return true
+	}
+	return false
+}
+
+// Review is a rating left by a user who received the product. Only approved
+// reviews are public and count towards the product's rating.
+type Review struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	ProductID    uint       `json:"product_id" gorm:"not null;uniqueIndex:idx_reviews_product_user"`
+	UserID       uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_reviews_product_user"`
+	Rating       int        `json:"rating" gorm:"not null"`
+	Title        string     `json:"title"`
+	Body         string     `json:"body"`
+	Status       Status     `json:"status" gorm:"type:varchar(20);default:'pending';index"`
+	HelpfulCount int        `json:"helpful_count" gorm:"default:0"`
+	Reply        string     `json:"reply,omitempty"`
+	RepliedAt    *time.Time `json:"replied_at,omitempty"`
+	ModeratedAt  *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type HelpfulVote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ReviewID  uint      `json:"review_id" gorm:"not null;uniqueIndex:idx_helpful_votes_review_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_helpful_votes_review_user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Title  string `json:"title" binding:"max=200"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+// Models returns the review tables for schema migration.
+func Models() []interface{} {
+	return []interface{}{&Review{}, &HelpfulVote{}}
+}
@@ -0,0 +1,324 @@
+package review
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReviewNotFound   = apperrors.New(http.StatusNotFound, "review_not_found", "review not found")
+	ErrNotVerifiedBuyer = apperrors.New(http.StatusForbidden, "not_verified_buyer", "only customers who received this product can review it")
+	ErrAlreadyReviewed  = apperrors.New(http.StatusConflict, "already_reviewed", "product already reviewed")
+	ErrInvalidRating    = apperrors.New(http.StatusBadRequest, "invalid_rating", "rating must be between 1 and 5")
+	ErrInvalidStatus    = apperrors.New(http.StatusBadRequest, "invalid_review_status", "invalid review status")
+	ErrOwnReview        = apperrors.New(http.StatusForbidden, "own_review", "cannot vote on your own review")
+	ErrInvalidProductID = apperrors.New(http.StatusBadRequest, "invalid_product_id", "invalid product ID")
+	ErrInvalidReviewID  = apperrors.New(http.StatusBadRequest, "invalid_review_id", "invalid review ID")
+)
+
+// sortOrders maps the sort query values accepted by ListProductReviews to
+// ORDER BY clauses.
+var sortOrders = map[string]string{
+	"newest":      "created_at DESC",
+	"helpful":     "helpful_count DESC, created_at DESC",
+	"rating_desc": "rating DESC, created_at DESC",
+	"rating_asc":  "rating ASC, created_at DESC",
+}
+
+// ProductCache drops cached copies of a product. The rating columns live
+// on the product row, so cached products go stale when a rating changes.
+type ProductCache interface {
+	InvalidateProduct(id uint)
+}
+
+type Service struct {
+	db       *gorm.DB
+	products ProductCache
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// NewServiceWithProductCache invalidates the product's cache entries
+// whenever its rating is recomputed.
+func NewServiceWithProductCache(db *gorm.DB, products ProductCache) *Service {
+	return &Service{db: db, products: products}
+}
+
+// CreateReview records a pending review. The user must have a delivered
+// order containing the product and may review each product once.
+func (s *Service) CreateReview(userID, productID uint, req *ReviewRequest) (*Review, error) {
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, ErrInvalidRating
+	}
+
+	verified, err := s.IsVerifiedBuyer(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, ErrNotVerifiedBuyer
+	}
+
+	var existing int64
+	if err := s.db.Model(&Review{}).Where("product_id = ? AND user_id = ?", productID, userID).
+		Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrAlreadyReviewed
+	}
+
+	review := Review{
+		ProductID: productID,
+		UserID:    userID,
+		Rating:    req.Rating,
+		Title:     req.Title,
+		Body:      req.Body,
+		Status:    StatusPending,
+	}
+	if err := s.db.Create(&review).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// IsVerifiedBuyer reports whether the user has a delivered order containing
+// the product.
+func (s *Service) IsVerifiedBuyer(userID, productID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?",
+			userID, models.OrderStatusDelivered, productID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *Service) GetReview(id uint) (*Review, error) {
+	var review Review
+	err := s.db.First(&review, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (s *Service) getOwnReview(userID, id uint) (*Review, error) {
+	review, err := s.GetReview(id)
+	if err != nil {
+		return nil, err
+	}
+	if review.UserID != userID {
+		return nil, ErrReviewNotFound
+	}
+	return review, nil
+}
+
+// UpdateReview edits the user's review and sends it back to moderation.
+func (s *Service) UpdateReview(userID, id uint, req *ReviewRequest) (*Review, error) {
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, ErrInvalidRating
+	}
+	review, err := s.getOwnReview(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	review.Rating = req.Rating
+	review.Title = req.Title
+	review.Body = req.Body
+	review.Status = StatusPending
+	review.ModeratedAt = nil
+	defer s.ratingChanged(review.ProductID)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(review).Error; err != nil {
+			return err
+		}
+		return recomputeRating(tx, review.ProductID)
+	})
+	return review, err
+}
+
+func (s *Service) DeleteReview(userID, id uint) error {
+	review, err := s.getOwnReview(userID, id)
+	if err != nil {
+		return err
+	}
+	defer s.ratingChanged(review.ProductID)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("review_id = ?", review.ID).Delete(&HelpfulVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(review).Error; err != nil {
+			return err
+		}
+		return recomputeRating(tx, review.ProductID)
+	})
+}
+
+// ListProductReviews returns the approved reviews of a product.
+func (s *Service) ListProductReviews(productID uint, page, limit int, sort string) ([]Review, int64, error) {
+	query := s.db.Model(&Review{}).Where("product_id = ? AND status = ?", productID, StatusApproved)
+	return paginate(query, page, limit, sort)
+}
+
+// ListReviews returns reviews in the given moderation state, or all reviews
+// when status is empty.
+func (s *Service) ListReviews(status Status, page, limit int) ([]Review, int64, error) {
+	query := s.db.Model(&Review{})
+	if status != "" {
+		if !status.Valid() {
+			return nil, 0, ErrInvalidStatus
+		}
+		query = query.Where("status = ?", status)
+	}
+	return paginate(query, page, limit, "newest")
+}
+
+func paginate(query *gorm.DB, page, limit int, sort string) ([]Review, int64, error) {
+	var reviews []Review
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order, ok := sortOrders[sort]
+	if !ok {
+		order = sortOrders["newest"]
+	}
+	if err := query.Order(order).Limit(limit).Offset((page - 1) * limit).Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// Moderate moves a review to the given state and refreshes the product's
+// rating.
+func (s *Service) Moderate(id uint, status Status) (*Review, error) {
+	if !status.Valid() {
+		return nil, ErrInvalidStatus
+	}
+	review, err := s.GetReview(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	review.Status = status
+	review.ModeratedAt = &now
+	defer s.ratingChanged(review.ProductID)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(review).Updates(map[string]interface{}{
+			"status":       status,
+			"moderated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return recomputeRating(tx, review.ProductID)
+	})
+	return review, err
+}
+
+// Reply sets the merchant's public reply to a review. An empty reply removes
+// it.
+func (s *Service) Reply(id uint, reply string) (*Review, error) {
+	review, err := s.GetReview(id)
+	if err != nil {
+		return nil, err
+	}
+
+	review.Reply = reply
+	review.RepliedAt = nil
+	if reply != "" {
+		now := time.Now()
+		review.RepliedAt = &now
+	}
+	err = s.db.Model(review).Select("reply", "replied_at").Updates(review).Error
+	return review, err
+}
+
+// VoteHelpful marks an approved review as helpful. Each user counts once;
+// repeated votes are ignored.
+func (s *Service) VoteHelpful(userID, id uint) (*Review, error) {
+	review, err := s.GetReview(id)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != StatusApproved {
+		return nil, ErrReviewNotFound
+	}
+	if review.UserID == userID {
+		return nil, ErrOwnReview
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&HelpfulVote{ReviewID: id, UserID: userID})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(review).UpdateColumn("helpful_count", gorm.Expr("helpful_count + 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReview(id)
+}
+
+func (s *Service) RemoveHelpfulVote(userID, id uint) (*Review, error) {
+	review, err := s.GetReview(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("review_id = ? AND user_id = ?", id, userID).Delete(&HelpfulVote{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(review).UpdateColumn("helpful_count", gorm.Expr("helpful_count - 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReview(id)
+}
+
+// ratingChanged invalidates the product's cache entries. Callers defer it
+// so it runs after the transaction that recomputed the rating has committed.
+func (s *Service) ratingChanged(productID uint) {
+	if s.products != nil {
+		s.products.InvalidateProduct(productID)
+	}
+}
+
+// recomputeRating stores the average and count of the product's approved
+// reviews on the product row.
+func recomputeRating(tx *gorm.DB, productID uint) error {
+	var aggregate struct {
+		Average float64
+		Count   int
+	}
+	if err := tx.Model(&Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, StatusApproved).
+		Scan(&aggregate).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Product{}).Where("id = ?", productID).UpdateColumns(map[string]interface{}{
+		"rating_average": math.Round(aggregate.Average*100) / 100,
+		"rating_count":   aggregate.Count,
+	}).Error
+}
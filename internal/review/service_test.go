@@ -0,0 +1,205 @@
+package review
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/internal/order"
+	"github.com/oguzhan/e-commerce/internal/product"
+	"github.com/oguzhan/e-commerce/pkg/cache"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	tables := append(Models(), &models.Product{}, &models.Order{}, &models.OrderItem{})
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	product := models.Product{Name: "Keyboard", Price: 50, Stock: 10, SKU: "KB-1", IsActive: true}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatalf("Failed to create test product: %v", err)
+	}
+	return NewService(db), db
+}
+
+func createOrder(t *testing.T, db *gorm.DB, userID, productID uint, status models.OrderStatus) {
+	order := models.Order{
+		UserID:          userID,
+		Status:          status,
+		TotalAmount:     50,
+		ShippingAddress: "Street 1",
+		BillingAddress:  "Street 1",
+		PaymentMethod:   "card",
+		OrderItems:      []models.OrderItem{{ProductID: productID, Quantity: 1, Price: 50}},
+	}
+	if err := db.Omit("OrderItems.Product").Create(&order).Error; err != nil {
+		t.Fatalf("Failed to create test order: %v", err)
+	}
+}
+
+func TestCreateReview_RequiresDeliveredOrder(t *testing.T) {
+	service, db := setupTestService(t)
+	req := &ReviewRequest{Rating: 5, Title: "Great"}
+
+	_, err := service.CreateReview(1, 1, req)
+	assert.ErrorIs(t, err, ErrNotVerifiedBuyer)
+
+	createOrder(t, db, 1, 1, models.OrderStatusShipped)
+	_, err = service.CreateReview(1, 1, req)
+	assert.ErrorIs(t, err, ErrNotVerifiedBuyer)
+
+	createOrder(t, db, 1, 1, models.OrderStatusDelivered)
+	review, err := service.CreateReview(1, 1, req)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, review.Status)
+
+	_, err = service.CreateReview(1, 1, req)
+	assert.ErrorIs(t, err, ErrAlreadyReviewed)
+
+	_, err = service.CreateReview(1, 1, &ReviewRequest{Rating: 6})
+	assert.ErrorIs(t, err, ErrInvalidRating)
+}
+
+func TestCreateReview_RejectsOrdersTheOwnerMarkedDelivered(t *testing.T) {
+	service, db := setupTestService(t)
+	if err := db.AutoMigrate(events.Models()...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	createOrder(t, db, 1, 1, models.OrderStatusPending)
+	orders := order.NewService(db)
+
+	assert.ErrorIs(t, orders.UpdateOrderStatus(context.Background(), 1, 1, string(models.OrderStatusDelivered)), order.ErrStatusForbidden)
+	assert.NoError(t, orders.UpdateOrder(1, &models.Order{Status: models.OrderStatusDelivered}))
+
+	_, err := service.CreateReview(1, 1, &ReviewRequest{Rating: 5, Title: "Great"})
+	assert.ErrorIs(t, err, ErrNotVerifiedBuyer)
+}
+
+func TestModerate_MaintainsProductRating(t *testing.T) {
+	service, db := setupTestService(t)
+	createOrder(t, db, 1, 1, models.OrderStatusDelivered)
+	createOrder(t, db, 2, 1, models.OrderStatusDelivered)
+
+	first, _ := service.CreateReview(1, 1, &ReviewRequest{Rating: 5})
+	second, _ := service.CreateReview(2, 1, &ReviewRequest{Rating: 2})
+
+	productRating := func() (float64, int) {
+		var product models.Product
+		db.First(&product, 1)
+		return product.RatingAverage, product.RatingCount
+	}
+
+	_, err := service.Moderate(first.ID, StatusApproved)
+	assert.NoError(t, err)
+	_, err = service.Moderate(second.ID, StatusApproved)
+	assert.NoError(t, err)
+	average, count := productRating()
+	assert.Equal(t, 3.5, average)
+	assert.Equal(t, 2, count)
+
+	_, err = service.Moderate(second.ID, StatusRejected)
+	assert.NoError(t, err)
+	average, count = productRating()
+	assert.Equal(t, 5.0, average)
+	assert.Equal(t, 1, count)
+
+	// Editing an approved review sends it back to moderation.
+	_, err = service.UpdateReview(1, first.ID, &ReviewRequest{Rating: 4})
+	assert.NoError(t, err)
+	average, count = productRating()
+	assert.Equal(t, 0.0, average)
+	assert.Equal(t, 0, count)
+
+	_, err = service.Moderate(first.ID, "hidden")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestRatingChanges_InvalidateCachedProduct(t *testing.T) {
+	_, db := setupTestService(t)
+	products := product.NewServiceWithCache(db, cache.NewReadThrough("products", cache.NewLRUCache(100), time.Minute, 0))
+	service := NewServiceWithProductCache(db, products)
+	createOrder(t, db, 1, 1, models.OrderStatusDelivered)
+	review, _ := service.CreateReview(1, 1, &ReviewRequest{Rating: 4})
+
+	cachedRating := func() (float64, int) {
+		cached, err := products.GetProductByID(1)
+		assert.NoError(t, err)
+		return cached.RatingAverage, cached.RatingCount
+	}
+	average, count := cachedRating()
+	assert.Equal(t, 0.0, average)
+	assert.Equal(t, 0, count)
+
+	_, err := service.Moderate(review.ID, StatusApproved)
+	assert.NoError(t, err)
+	average, count = cachedRating()
+	assert.Equal(t, 4.0, average)
+	assert.Equal(t, 1, count)
+
+	_, err = service.UpdateReview(1, review.ID, &ReviewRequest{Rating: 2})
+	assert.NoError(t, err)
+	_, count = cachedRating()
+	assert.Equal(t, 0, count)
+
+	service.Moderate(review.ID, StatusApproved)
+	cachedRating()
+	assert.NoError(t, service.DeleteReview(1, review.ID))
+	_, count = cachedRating()
+	assert.Equal(t, 0, count)
+}
+
+func TestVoteHelpful_CountsEachUserOnce(t *testing.T) {
+	service, db := setupTestService(t)
+	createOrder(t, db, 1, 1, models.OrderStatusDelivered)
+	review, _ := service.CreateReview(1, 1, &ReviewRequest{Rating: 4})
+
+	_, err := service.VoteHelpful(2, review.ID)
+	assert.ErrorIs(t, err, ErrReviewNotFound)
+
+	service.Moderate(review.ID, StatusApproved)
+
+	_, err = service.VoteHelpful(1, review.ID)
+	assert.ErrorIs(t, err, ErrOwnReview)
+
+	review, err = service.VoteHelpful(2, review.ID)
+	assert.NoError(t, err)
+	review, err = service.VoteHelpful(2, review.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, review.HelpfulCount)
+
+	review, err = service.RemoveHelpfulVote(2, review.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, review.HelpfulCount)
+}
+
+func TestListProductReviews_OnlyApproved(t *testing.T) {
+	service, db := setupTestService(t)
+	createOrder(t, db, 1, 1, models.OrderStatusDelivered)
+	createOrder(t, db, 2, 1, models.OrderStatusDelivered)
+	approved, _ := service.CreateReview(1, 1, &ReviewRequest{Rating: 4})
+	service.CreateReview(2, 1, &ReviewRequest{Rating: 1})
+	service.Moderate(approved.ID, StatusApproved)
+
+	reviews, total, err := service.ListProductReviews(1, 1, 10, "helpful")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, approved.ID, reviews[0].ID)
+
+	reply, err := service.Reply(approved.ID, "Thanks!")
+	assert.NoError(t, err)
+	assert.NotNil(t, reply.RepliedAt)
+
+	pending, total, err := service.ListReviews(StatusPending, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, 1, pending[0].Rating)
+}
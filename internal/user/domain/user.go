@@ -1,7 +1,16 @@
 package domain
 
 import (
+	"net/http"
 	"time"
+
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+)
+
+var (
+	ErrUserNotFound       = apperrors.New(http.StatusNotFound, "user_not_found", "user not found")
+	ErrUserExists         = apperrors.New(http.StatusConflict, "email_taken", "user already exists")
+	ErrInvalidCredentials = apperrors.New(http.StatusUnauthorized, "invalid_credentials", "invalid credentials")
 )
 
 type User struct {
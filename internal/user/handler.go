@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
 	"github.com/oguzhan/e-commerce/pkg/models"
 )
 
@@ -19,19 +20,19 @@ func NewHandler(service *Service) *Handler {
 func (h *Handler) GetUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		middleware.WriteError(c, ErrInvalidUserID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	if uint(id) != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		middleware.WriteError(c, ErrNotAccountOwner)
 		return
 	}
 
 	user, err := h.service.GetUserByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -41,24 +42,24 @@ func (h *Handler) GetUser(c *gin.Context) {
 func (h *Handler) UpdateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		middleware.WriteError(c, ErrInvalidUserID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	if uint(id) != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		middleware.WriteError(c, ErrNotAccountOwner)
 		return
 	}
 
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.UpdateUser(uint(id), &user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -68,18 +69,18 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 func (h *Handler) DeleteUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		middleware.WriteError(c, ErrInvalidUserID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	if uint(id) != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		middleware.WriteError(c, ErrNotAccountOwner)
 		return
 	}
 
 	if err := h.service.DeleteUser(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -99,7 +100,7 @@ func (h *Handler) ListUsers(c *gin.Context) {
 
 	users, total, err := h.service.ListUsers(page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -114,13 +115,13 @@ func (h *Handler) ListUsers(c *gin.Context) {
 func (h *Handler) ChangePassword(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		middleware.WriteError(c, ErrInvalidUserID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	if uint(id) != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		middleware.WriteError(c, ErrNotAccountOwner)
 		return
 	}
 
@@ -130,12 +131,42 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.ChangePassword(uint(id), request.OldPassword, request.NewPassword); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.WriteError(c, ErrInvalidUserID)
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if uint(id) != userID {
+		middleware.WriteError(c, ErrNotAccountOwner)
+		return
+	}
+
+	var request struct {
+		CartRemindersOptOut *bool `json:"cart_reminders_opt_out" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+
+	if err := h.service.SetCartRemindersOptOut(uint(id), *request.CartRemindersOptOut); err != nil {
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -146,12 +177,12 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 func (h *Handler) DeactivateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		middleware.WriteError(c, ErrInvalidUserID)
 		return
 	}
 
 	if err := h.service.DeactivateUser(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -161,12 +192,12 @@ func (h *Handler) DeactivateUser(c *gin.Context) {
 func (h *Handler) ActivateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		middleware.WriteError(c, ErrInvalidUserID)
 		return
 	}
 
 	if err := h.service.ActivateUser(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -176,7 +207,7 @@ func (h *Handler) ActivateUser(c *gin.Context) {
 func (h *Handler) UpdateUserRole(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		middleware.WriteError(c, ErrInvalidUserID)
 		return
 	}
 
@@ -185,12 +216,12 @@ func (h *Handler) UpdateUserRole(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.UpdateUserRole(uint(id), request.Role); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -200,7 +231,7 @@ func (h *Handler) UpdateUserRole(c *gin.Context) {
 func (h *Handler) ResetPassword(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		middleware.WriteError(c, ErrInvalidUserID)
 		return
 	}
 
@@ -209,12 +240,12 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	if err := h.service.ResetPassword(uint(id), request.NewPassword); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -226,13 +257,13 @@ func (h *Handler) CreateAddress(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	var address models.Address
 	if err := c.ShouldBindJSON(&address); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	address.UserID = userID
 	if err := h.service.CreateAddress(&address); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -243,7 +274,7 @@ func (h *Handler) GetAddresses(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	addresses, err := h.service.GetAddresses(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -253,20 +284,20 @@ func (h *Handler) GetAddresses(c *gin.Context) {
 func (h *Handler) UpdateAddress(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address ID"})
+		middleware.WriteError(c, ErrInvalidAddressID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	var address models.Address
 	if err := c.ShouldBindJSON(&address); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	address.UserID = userID
 	if err := h.service.UpdateAddress(uint(id), &address); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -276,12 +307,12 @@ func (h *Handler) UpdateAddress(c *gin.Context) {
 func (h *Handler) DeleteAddress(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address ID"})
+		middleware.WriteError(c, ErrInvalidAddressID)
 		return
 	}
 
 	if err := h.service.DeleteAddress(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -293,13 +324,13 @@ func (h *Handler) CreateContact(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	var contact models.Contact
 	if err := c.ShouldBindJSON(&contact); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	contact.UserID = userID
 	if err := h.service.CreateContact(&contact); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -310,7 +341,7 @@ func (h *Handler) GetContacts(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	contacts, err := h.service.GetContacts(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -320,20 +351,20 @@ func (h *Handler) GetContacts(c *gin.Context) {
 func (h *Handler) UpdateContact(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid contact ID"})
+		middleware.WriteError(c, ErrInvalidContactID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 	var contact models.Contact
 	if err := c.ShouldBindJSON(&contact); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
 	contact.UserID = userID
 	if err := h.service.UpdateContact(uint(id), &contact); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -343,12 +374,12 @@ func (h *Handler) UpdateContact(c *gin.Context) {
 func (h *Handler) DeleteContact(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid contact ID"})
+		middleware.WriteError(c, ErrInvalidContactID)
 		return
 	}
 
 	if err := h.service.DeleteContact(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.WriteError(c, err)
 		return
 	}
 
@@ -7,8 +7,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/oguzhan/e-commerce/internal/user/domain"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 )
 
+var ErrInvalidUserID = apperrors.New(http.StatusBadRequest, "invalid_user_id", "invalid user ID")
+
 type UserHandler struct {
 	service domain.UserService
 }
@@ -20,12 +23,12 @@ func NewUserHandler(service domain.UserService) *UserHandler {
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var user domain.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
 	if err := h.service.Register(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -40,13 +43,13 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
 	token, err := h.service.Login(credentials.Email, credentials.Password)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -57,13 +60,13 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, ErrInvalidUserID)
 		return
 	}
 
 	user, err := h.service.GetUserByID(uint(id))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -74,19 +77,19 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, ErrInvalidUserID)
 		return
 	}
 
 	var user domain.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
 	user.ID = uint(id)
 	if err := h.service.UpdateUser(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
@@ -97,12 +100,12 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apperrors.WriteProblem(w, r, ErrInvalidUserID)
 		return
 	}
 
 	if err := h.service.DeleteUser(uint(id)); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperrors.WriteProblem(w, r, err)
 		return
 	}
 
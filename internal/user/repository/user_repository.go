@@ -2,7 +2,6 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
 	"time"
 
 	"github.com/oguzhan/e-commerce/internal/user/domain"
@@ -58,7 +57,7 @@ func (r *userRepository) GetByID(id uint) (*domain.User, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, domain.ErrUserNotFound
 	}
 
 	return user, err
@@ -79,7 +78,7 @@ func (r *userRepository) GetByEmail(email string) (*domain.User, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, domain.ErrUserNotFound
 	}
 
 	return user, err
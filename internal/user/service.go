@@ -2,13 +2,24 @@ package user
 
 import (
 	"errors"
+	"net/http"
 	"time"
 
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 	"github.com/oguzhan/e-commerce/pkg/models"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrUserNotFound     = apperrors.New(http.StatusNotFound, "user_not_found", "user not found")
+	ErrNotAccountOwner  = apperrors.New(http.StatusForbidden, "user_forbidden", "you can only manage your own account")
+	ErrInvalidPassword  = apperrors.New(http.StatusBadRequest, "invalid_old_password", "invalid old password")
+	ErrInvalidUserID    = apperrors.New(http.StatusBadRequest, "invalid_user_id", "invalid user ID")
+	ErrInvalidAddressID = apperrors.New(http.StatusBadRequest, "invalid_address_id", "invalid address ID")
+	ErrInvalidContactID = apperrors.New(http.StatusBadRequest, "invalid_contact_id", "invalid contact ID")
+)
+
 type Service struct {
 	db *gorm.DB
 }
@@ -24,29 +35,36 @@ func (s *Service) CreateUser(user *models.User) error {
 func (s *Service) GetUserByID(id uint) (*models.User, error) {
 	var user models.User
 	if err := s.db.First(&user, id).Error; err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 	return &user, nil
 }
 
+// notFound turns a missing record into ErrUserNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound.Wrap(err)
+	}
+	return err
+}
+
 func (s *Service) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
 	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
+		return nil, notFound(err)
 	}
 	return &user, nil
 }
 
+// UpdateUser updates the user's profile. The role, the active flag and the
+// password have their own admin and password endpoints and are left alone.
 func (s *Service) UpdateUser(id uint, user *models.User) error {
 	var existingUser models.User
 	if err := s.db.First(&existingUser, id).Error; err != nil {
-		return err
+		return notFound(err)
 	}
 
-	return s.db.Model(&existingUser).Updates(user).Error
+	return s.db.Model(&existingUser).Omit("role", "is_active", "password", "last_login").Updates(user).Error
 }
 
 func (s *Service) DeleteUser(id uint) error {
@@ -69,11 +87,11 @@ func (s *Service) ListUsers(page, limit int) ([]models.User, int64, error) {
 func (s *Service) ChangePassword(id uint, oldPassword, newPassword string) error {
 	var user models.User
 	if err := s.db.First(&user, id).Error; err != nil {
-		return err
+		return notFound(err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
-		return errors.New("invalid old password")
+		return ErrInvalidPassword
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
@@ -92,6 +110,10 @@ func (s *Service) ActivateUser(id uint) error {
 	return s.db.Model(&models.User{}).Where("id = ?", id).Update("is_active", true).Error
 }
 
+func (s *Service) SetCartRemindersOptOut(id uint, optOut bool) error {
+	return s.db.Model(&models.User{}).Where("id = ?", id).Update("cart_reminders_opt_out", optOut).Error
+}
+
 func (s *Service) UpdateUserRole(id uint, role string) error {
 	return s.db.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
 }
@@ -1,7 +1,6 @@
 package service
 
 import (
-	"errors"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -25,7 +24,7 @@ func (s *userService) Register(user *domain.User) error {
 	// Check if user already exists
 	existingUser, err := s.repo.GetByEmail(user.Email)
 	if err == nil && existingUser != nil {
-		return errors.New("user already exists")
+		return domain.ErrUserExists
 	}
 
 	// Set default role if not provided
@@ -39,13 +38,13 @@ func (s *userService) Register(user *domain.User) error {
 func (s *userService) Login(email, password string) (string, error) {
 	user, err := s.repo.GetByEmail(email)
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		return "", domain.ErrInvalidCredentials
 	}
 
 	// Compare passwords
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		return "", domain.ErrInvalidCredentials
 	}
 
 	// Generate JWT token
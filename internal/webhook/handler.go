@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func parseID(c *gin.Context, param string, invalid error) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		middleware.WriteError(c, invalid)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *Handler) CreateSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	subscription, secret, err := h.service.CreateSubscription(&req)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	response := subscription.ToResponse()
+	response.Secret = secret
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.service.ListSubscriptions()
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	responses := make([]SubscriptionResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		responses = append(responses, subscriptions[i].ToResponse())
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+func (h *Handler) GetSubscription(c *gin.Context) {
+	id, ok := parseID(c, "id", ErrInvalidSubscriptionID)
+	if !ok {
+		return
+	}
+	subscription, err := h.service.GetSubscription(id)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription.ToResponse())
+}
+
+func (h *Handler) UpdateSubscription(c *gin.Context) {
+	id, ok := parseID(c, "id", ErrInvalidSubscriptionID)
+	if !ok {
+		return
+	}
+	var req UpdateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	subscription, err := h.service.UpdateSubscription(id, &req)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription.ToResponse())
+}
+
+func (h *Handler) DeleteSubscription(c *gin.Context) {
+	id, ok := parseID(c, "id", ErrInvalidSubscriptionID)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSubscription(id); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	id, ok := parseID(c, "id", ErrInvalidSubscriptionID)
+	if !ok {
+		return
+	}
+	if _, err := h.service.GetSubscription(id); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	deliveries, total, err := h.service.ListDeliveries(id, page, limit)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+func (h *Handler) GetDelivery(c *gin.Context) {
+	id, ok := parseID(c, "id", ErrInvalidSubscriptionID)
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseID(c, "deliveryId", ErrInvalidDeliveryID)
+	if !ok {
+		return
+	}
+	delivery, attempts, err := h.service.GetDelivery(id, deliveryID)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"delivery": delivery,
+		"attempts": attempts,
+	})
+}
+
+func (h *Handler) Redeliver(c *gin.Context) {
+	id, ok := parseID(c, "id", ErrInvalidSubscriptionID)
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseID(c, "deliveryId", ErrInvalidDeliveryID)
+	if !ok {
+		return
+	}
+	delivery, err := h.service.Redeliver(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
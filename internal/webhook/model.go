@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"strings"
+	"time"
+)
+
+// Event types that subscriptions can filter on.
+const (
+	EventOrderPlaced     = "order.placed"
+	EventOrderPaid       = "order.paid"
+	EventOrderShipped    = "order.shipped"
+	EventOrderDelivered  = "order.delivered"
+	EventOrderCancelled  = "order.cancelled"
+	EventPaymentRefunded = "payment.refunded"
+
+	// EventAll subscribes to every event type.
+	EventAll = "*"
+)
+
+var EventTypes = []string{
+	EventOrderPlaced,
+	EventOrderPaid,
+	EventOrderShipped,
+	EventOrderDelivered,
+	EventOrderCancelled,
+	EventPaymentRefunded,
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Subscription is an endpoint registered by an admin to receive events.
+type Subscription struct {
+	ID                  uint       `json:"id" gorm:"primaryKey"`
+	URL                 string     `json:"url" gorm:"not null"`
+	Secret              string     `json:"-" gorm:"not null"`
+	Events              string     `json:"-" gorm:"not null"`
+	Description         string     `json:"description"`
+	Active              bool       `json:"active" gorm:"default:true"`
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"default:0"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func (Subscription) TableName() string { return "webhook_subscriptions" }
+
+// EventList returns the subscription's event filter.
+func (s *Subscription) EventList() []string {
+	if s.Events == "" {
+		return nil
+	}
+	return strings.Split(s.Events, ",")
+}
+
+// Wants reports whether the subscription's filter matches eventType.
+func (s *Subscription) Wants(eventType string) bool {
+	for _, event := range s.EventList() {
+		if event == EventAll || event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionResponse is a subscription as returned by the API. Secret is
+// only set in the response to creating the subscription.
+type SubscriptionResponse struct {
+	Subscription
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+func (s *Subscription) ToResponse() SubscriptionResponse {
+	return SubscriptionResponse{Subscription: *s, Events: s.EventList()}
+}
+
+// Delivery is one event queued for one subscription.
+type Delivery struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint           `json:"subscription_id" gorm:"not null;uniqueIndex:idx_webhook_deliveries_event"`
+	EventID        uint           `json:"event_id" gorm:"not null;uniqueIndex:idx_webhook_deliveries_event"`
+	Event          string         `json:"event" gorm:"not null"`
+	Payload        string         `json:"payload" gorm:"type:text;not null"`
+	Status         DeliveryStatus `json:"status" gorm:"type:varchar(20);default:'pending';index"`
+	Attempts       int            `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at" gorm:"index"`
+	LastAttemptAt  *time.Time     `json:"last_attempt_at,omitempty"`
+	ResponseStatus int            `json:"response_status,omitempty"`
+	ResponseBody   string         `json:"response_body,omitempty" gorm:"type:text"`
+	Error          string         `json:"error,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+func (Delivery) TableName() string { return "webhook_deliveries" }
+
+// DeliveryAttempt logs a single HTTP request made for a delivery.
+type DeliveryAttempt struct {
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	DeliveryID     uint          `json:"delivery_id" gorm:"not null;index"`
+	Attempt        int           `json:"attempt"`
+	Manual         bool          `json:"manual"`
+	ResponseStatus int           `json:"response_status,omitempty"`
+	ResponseBody   string        `json:"response_body,omitempty" gorm:"type:text"`
+	Error          string        `json:"error,omitempty"`
+	Duration       time.Duration `json:"duration"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+func (DeliveryAttempt) TableName() string { return "webhook_delivery_attempts" }
+
+// Models returns the webhook tables for schema migration.
+func Models() []interface{} {
+	return []interface{}{&Subscription{}, &Delivery{}, &DeliveryAttempt{}}
+}
@@ -0,0 +1,481 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oguzhan/e-commerce/internal/events"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSubscriptionNotFound  = apperrors.New(http.StatusNotFound, "subscription_not_found", "webhook subscription not found")
+	ErrDeliveryNotFound      = apperrors.New(http.StatusNotFound, "delivery_not_found", "webhook delivery not found")
+	ErrInvalidURL            = apperrors.New(http.StatusBadRequest, "invalid_webhook_url", "webhook URL must be an absolute http or https URL")
+	ErrInvalidEvents         = apperrors.New(http.StatusBadRequest, "invalid_webhook_events", "webhook events must be known event types or *")
+	ErrInvalidSubscriptionID = apperrors.New(http.StatusBadRequest, "invalid_subscription_id", "invalid subscription ID")
+	ErrInvalidDeliveryID     = apperrors.New(http.StatusBadRequest, "invalid_delivery_id", "invalid delivery ID")
+)
+
+// maxResponseBody is how much of a receiver's response is kept in the log.
+const maxResponseBody = 4096
+
+type Options struct {
+	// Timeout bounds each HTTP request to a receiver.
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it is marked
+	// failed.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; it doubles with each
+	// further attempt up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// DisableAfter is the number of consecutive failed requests after which
+	// a subscription is disabled.
+	DisableAfter int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   6 * time.Hour,
+		DisableAfter: 20,
+	}
+}
+
+type SubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Description string   `json:"description"`
+}
+
+type UpdateSubscriptionRequest struct {
+	URL         *string  `json:"url"`
+	Events      []string `json:"events"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// Message is the JSON body posted to receivers.
+type Message struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type Service struct {
+	db      *gorm.DB
+	client  *http.Client
+	options Options
+	now     func() time.Time
+}
+
+func NewService(db *gorm.DB) *Service {
+	return NewServiceWithOptions(db, DefaultOptions())
+}
+
+func NewServiceWithOptions(db *gorm.DB, options Options) *Service {
+	defaults := DefaultOptions()
+	if options.Timeout <= 0 {
+		options.Timeout = defaults.Timeout
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaults.MaxAttempts
+	}
+	if options.BaseBackoff <= 0 {
+		options.BaseBackoff = defaults.BaseBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaults.MaxBackoff
+	}
+	if options.DisableAfter <= 0 {
+		options.DisableAfter = defaults.DisableAfter
+	}
+	return &Service{
+		db:      db,
+		client:  &http.Client{Timeout: options.Timeout},
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// CreateSubscription registers an endpoint. When no secret is given one is
+// generated; the secret is only ever returned from this call.
+func (s *Service) CreateSubscription(req *SubscriptionRequest) (*Subscription, string, error) {
+	if err := validateURL(req.URL); err != nil {
+		return nil, "", err
+	}
+	filter, err := eventFilter(req.Events)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	subscription := Subscription{
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      filter,
+		Description: req.Description,
+		Active:      true,
+	}
+	if err := s.db.Create(&subscription).Error; err != nil {
+		return nil, "", err
+	}
+	return &subscription, secret, nil
+}
+
+func (s *Service) ListSubscriptions() ([]Subscription, error) {
+	var subscriptions []Subscription
+	err := s.db.Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (s *Service) GetSubscription(id uint) (*Subscription, error) {
+	var subscription Subscription
+	err := s.db.First(&subscription, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// UpdateSubscription changes a subscription. Re-activating a disabled
+// subscription clears its failure count.
+func (s *Service) UpdateSubscription(id uint, req *UpdateSubscriptionRequest) (*Subscription, error) {
+	subscription, err := s.GetSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL != nil {
+		if err := validateURL(*req.URL); err != nil {
+			return nil, err
+		}
+		subscription.URL = *req.URL
+	}
+	if req.Events != nil {
+		filter, err := eventFilter(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		subscription.Events = filter
+	}
+	if req.Description != nil {
+		subscription.Description = *req.Description
+	}
+	if req.Active != nil {
+		if *req.Active && !subscription.Active {
+			subscription.ConsecutiveFailures = 0
+			subscription.DisabledAt = nil
+			subscription.DisabledReason = ""
+		}
+		subscription.Active = *req.Active
+	}
+	if err := s.db.Save(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *Service) DeleteSubscription(id uint) error {
+	subscription, err := s.GetSubscription(id)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		deliveries := tx.Model(&Delivery{}).Select("id").Where("subscription_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&DeliveryAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", id).Delete(&Delivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(subscription).Error
+	})
+}
+
+func (s *Service) ListDeliveries(subscriptionID uint, page, limit int) ([]Delivery, int64, error) {
+	var deliveries []Delivery
+	var total int64
+	query := s.db.Model(&Delivery{}).Where("subscription_id = ?", subscriptionID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+func (s *Service) GetDelivery(subscriptionID, id uint) (*Delivery, []DeliveryAttempt, error) {
+	var delivery Delivery
+	err := s.db.Where("id = ? AND subscription_id = ?", id, subscriptionID).First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var attempts []DeliveryAttempt
+	if err := s.db.Where("delivery_id = ?", id).Order("id").Find(&attempts).Error; err != nil {
+		return nil, nil, err
+	}
+	return &delivery, attempts, nil
+}
+
+// HandleEvent is an events subscriber that queues a delivery for every
+// active subscription interested in the event. It is safe to call more than
+// once for the same event.
+func (s *Service) HandleEvent(ctx context.Context, envelope events.Envelope) error {
+	eventType, ok := eventType(envelope)
+	if !ok {
+		return nil
+	}
+
+	var subscriptions []Subscription
+	if err := s.db.WithContext(ctx).Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	var deliveries []Delivery
+	for _, subscription := range subscriptions {
+		if !subscription.Wants(eventType) {
+			continue
+		}
+		payload, err := json.Marshal(Message{
+			ID:        "evt_" + strconv.FormatUint(uint64(envelope.ID), 10),
+			Event:     eventType,
+			CreatedAt: envelope.OccurredAt.UTC(),
+			Data:      envelope.Payload,
+		})
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, Delivery{
+			SubscriptionID: subscription.ID,
+			EventID:        envelope.ID,
+			Event:          eventType,
+			Payload:        string(payload),
+			Status:         DeliveryPending,
+			NextAttemptAt:  s.now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+// eventType maps a domain event onto the webhook event type receivers
+// subscribe to.
+func eventType(envelope events.Envelope) (string, bool) {
+	switch envelope.Name {
+	case events.NameOrderPlaced:
+		return EventOrderPlaced, true
+	case events.NamePaymentCaptured:
+		return EventOrderPaid, true
+	case events.NamePaymentRefunded:
+		return EventPaymentRefunded, true
+	case events.NameOrderStatusChanged:
+		var changed events.OrderStatusChanged
+		if err := envelope.Decode(&changed); err != nil {
+			return "", false
+		}
+		switch changed.To {
+		case models.OrderStatusShipped:
+			return EventOrderShipped, true
+		case models.OrderStatusDelivered:
+			return EventOrderDelivered, true
+		case models.OrderStatusCancelled:
+			return EventOrderCancelled, true
+		}
+	}
+	return "", false
+}
+
+// Redeliver sends a delivery again right away, regardless of its status or
+// whether its subscription is active, and returns the updated delivery.
+func (s *Service) Redeliver(ctx context.Context, subscriptionID, id uint) (*Delivery, error) {
+	delivery, _, err := s.GetDelivery(subscriptionID, id)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := s.GetSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attempt(ctx, subscription, delivery, true); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// attempt posts the delivery to the subscription's URL and records the
+// outcome: the attempt log, the delivery's status and retry schedule, and
+// the subscription's failure count.
+func (s *Service) attempt(ctx context.Context, subscription *Subscription, delivery *Delivery, manual bool) error {
+	started := s.now()
+	status, body, sendErr := s.send(ctx, subscription, delivery, started)
+	now := s.now()
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.Error = ""
+
+	succeeded := sendErr == nil && status >= 200 && status < 300
+	switch {
+	case succeeded:
+		delivery.Status = DeliverySucceeded
+	case sendErr != nil:
+		delivery.Error = sendErr.Error()
+	default:
+		delivery.Error = fmt.Sprintf("receiver responded with status %d", status)
+	}
+	if !succeeded {
+		if manual || delivery.Attempts >= s.options.MaxAttempts {
+			delivery.Status = DeliveryFailed
+		} else {
+			delivery.Status = DeliveryPending
+			delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&DeliveryAttempt{
+			DeliveryID:     delivery.ID,
+			Attempt:        delivery.Attempts,
+			Manual:         manual,
+			ResponseStatus: status,
+			ResponseBody:   body,
+			Error:          delivery.Error,
+			Duration:       now.Sub(started),
+			CreatedAt:      now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(delivery).Error; err != nil {
+			return err
+		}
+		return s.recordOutcome(tx, subscription, succeeded, now)
+	})
+}
+
+// recordOutcome tracks consecutive failures and disables the subscription
+// once they reach the configured limit.
+func (s *Service) recordOutcome(tx *gorm.DB, subscription *Subscription, succeeded bool, now time.Time) error {
+	if succeeded {
+		subscription.ConsecutiveFailures = 0
+		return tx.Model(subscription).Update("consecutive_failures", 0).Error
+	}
+
+	subscription.ConsecutiveFailures++
+	updates := map[string]interface{}{"consecutive_failures": subscription.ConsecutiveFailures}
+	if subscription.Active && subscription.ConsecutiveFailures >= s.options.DisableAfter {
+		subscription.Active = false
+		subscription.DisabledAt = &now
+		subscription.DisabledReason = fmt.Sprintf("disabled after %d consecutive failed deliveries", subscription.ConsecutiveFailures)
+		updates["active"] = false
+		updates["disabled_at"] = now
+		updates["disabled_reason"] = subscription.DisabledReason
+	}
+	return tx.Model(subscription).Updates(updates).Error
+}
+
+func (s *Service) send(ctx context.Context, subscription *Subscription, delivery *Delivery, timestamp time.Time) (int, string, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "e-commerce-webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(IDHeader, "evt_"+strconv.FormatUint(uint64(delivery.EventID), 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(response), nil
+}
+
+func (s *Service) backoff(attempts int) time.Duration {
+	delay := s.options.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= s.options.MaxBackoff {
+			return s.options.MaxBackoff
+		}
+	}
+	return delay
+}
+
+func validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+func eventFilter(eventTypes []string) (string, error) {
+	if len(eventTypes) == 0 {
+		return "", ErrInvalidEvents
+	}
+	for _, eventType := range eventTypes {
+		if eventType == EventAll {
+			continue
+		}
+		known := false
+		for _, candidate := range EventTypes {
+			if candidate == eventType {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", ErrInvalidEvents
+		}
+	}
+	return strings.Join(eventTypes, ","), nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oguzhan/e-commerce/internal/events"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// receiver is an httptest server that verifies signatures and answers with
+// a configurable status.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	messages []Message
+	invalid  int
+}
+
+func newReceiver(t *testing.T, secret string) *receiver {
+	r := &receiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err := Verify(secret, req.Header.Get(SignatureHeader), req.Header.Get(TimestampHeader), body, 5*time.Minute); err != nil {
+			r.invalid++
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var message Message
+		json.Unmarshal(body, &message)
+		r.messages = append(r.messages, message)
+		w.WriteHeader(r.status)
+		w.Write([]byte(`{"received":true}`))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) respondWith(status int) {
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+}
+
+func setupTestService(t *testing.T, options Options) *Service {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(Models()...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return NewServiceWithOptions(db, options)
+}
+
+func envelope(t *testing.T, id uint, event events.Event) events.Envelope {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.Envelope{ID: id, Name: event.EventName(), Payload: payload, OccurredAt: time.Now()}
+}
+
+func TestSignature_RoundTrip(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"event":"order.placed"}`)
+	signature := Sign("secret", now, body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	assert.NoError(t, Verify("secret", signature, timestamp, body, time.Minute))
+	assert.ErrorIs(t, Verify("other", signature, timestamp, body, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", signature, timestamp, []byte(`{}`), time.Minute), ErrInvalidSignature)
+
+	old := now.Add(-time.Hour)
+	assert.ErrorIs(t, Verify("secret", Sign("secret", old, body), strconv.FormatInt(old.Unix(), 10), body, time.Minute), ErrInvalidSignature)
+}
+
+func TestHandleEvent_FiltersAndDelivers(t *testing.T) {
+	service := setupTestService(t, Options{})
+	ctx := context.Background()
+
+	recv := newReceiver(t, "shh")
+	_, _, err := service.CreateSubscription(&SubscriptionRequest{
+		URL: recv.URL, Secret: "shh", Events: []string{EventOrderShipped, EventOrderPaid},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, service.HandleEvent(ctx, envelope(t, 1, events.OrderPlaced{OrderID: 9})))
+	assert.NoError(t, service.HandleEvent(ctx, envelope(t, 2, events.OrderStatusChanged{
+		OrderID: 9, From: models.OrderStatusProcessing, To: models.OrderStatusShipped,
+	})))
+	// The same event handled twice is queued once.
+	assert.NoError(t, service.HandleEvent(ctx, envelope(t, 2, events.OrderStatusChanged{
+		OrderID: 9, From: models.OrderStatusProcessing, To: models.OrderStatusShipped,
+	})))
+
+	attempted, err := NewWorker(service, zap.NewNop()).RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	assert.Equal(t, 0, recv.invalid)
+	assert.Len(t, recv.messages, 1)
+	assert.Equal(t, EventOrderShipped, recv.messages[0].Event)
+	assert.Equal(t, "evt_2", recv.messages[0].ID)
+	assert.JSONEq(t, `{"order_id":9,"from":"processing","to":"shipped"}`, string(recv.messages[0].Data))
+
+	deliveries, _, _ := service.ListDeliveries(1, 1, 10)
+	assert.Equal(t, DeliverySucceeded, deliveries[0].Status)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseStatus)
+	assert.Equal(t, `{"received":true}`, deliveries[0].ResponseBody)
+}
+
+func TestWorker_RetriesWithBackoffAndRedelivers(t *testing.T) {
+	service := setupTestService(t, Options{MaxAttempts: 2, BaseBackoff: time.Minute})
+	ctx := context.Background()
+	worker := NewWorker(service, zap.NewNop())
+
+	recv := newReceiver(t, "shh")
+	recv.respondWith(http.StatusInternalServerError)
+	subscription, _, _ := service.CreateSubscription(&SubscriptionRequest{
+		URL: recv.URL, Secret: "shh", Events: []string{EventAll},
+	})
+	service.HandleEvent(ctx, envelope(t, 1, events.OrderPlaced{OrderID: 1}))
+
+	now := time.Now()
+	service.now = func() time.Time { return now }
+	worker.RunOnce(ctx)
+
+	delivery, attempts, err := service.GetDelivery(subscription.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, DeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+	assert.WithinDuration(t, now.Add(time.Minute), delivery.NextAttemptAt, time.Second)
+	assert.Len(t, attempts, 1)
+
+	// Not due yet.
+	attempted, _ := worker.RunOnce(ctx)
+	assert.Equal(t, 0, attempted)
+
+	now = now.Add(time.Minute)
+	worker.RunOnce(ctx)
+	delivery, attempts, _ = service.GetDelivery(subscription.ID, 1)
+	assert.Equal(t, DeliveryFailed, delivery.Status)
+	assert.Len(t, attempts, 2)
+
+	recv.respondWith(http.StatusOK)
+	delivery, err = service.Redeliver(ctx, subscription.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, DeliverySucceeded, delivery.Status)
+	_, attempts, _ = service.GetDelivery(subscription.ID, 1)
+	assert.True(t, attempts[2].Manual)
+
+	subscription, _ = service.GetSubscription(subscription.ID)
+	assert.Equal(t, 0, subscription.ConsecutiveFailures)
+}
+
+func TestWorker_DisablesSubscriptionAfterRepeatedFailures(t *testing.T) {
+	service := setupTestService(t, Options{DisableAfter: 3})
+	ctx := context.Background()
+
+	recv := newReceiver(t, "shh")
+	recv.respondWith(http.StatusBadGateway)
+	subscription, _, _ := service.CreateSubscription(&SubscriptionRequest{
+		URL: recv.URL, Secret: "shh", Events: []string{EventOrderPlaced},
+	})
+	for id := uint(1); id <= 5; id++ {
+		service.HandleEvent(ctx, envelope(t, id, events.OrderPlaced{OrderID: id}))
+	}
+
+	attempted, err := NewWorker(service, zap.NewNop()).RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempted)
+
+	subscription, _ = service.GetSubscription(subscription.ID)
+	assert.False(t, subscription.Active)
+	assert.NotNil(t, subscription.DisabledAt)
+
+	// Disabled subscriptions receive no new deliveries.
+	service.HandleEvent(ctx, envelope(t, 6, events.OrderPlaced{OrderID: 6}))
+	_, total, _ := service.ListDeliveries(subscription.ID, 1, 10)
+	assert.Equal(t, int64(5), total)
+
+	active := true
+	subscription, err = service.UpdateSubscription(subscription.ID, &UpdateSubscriptionRequest{Active: &active})
+	assert.NoError(t, err)
+	assert.True(t, subscription.Active)
+	assert.Equal(t, 0, subscription.ConsecutiveFailures)
+}
+
+func TestCreateSubscription_Validates(t *testing.T) {
+	service := setupTestService(t, Options{})
+
+	_, _, err := service.CreateSubscription(&SubscriptionRequest{URL: "ftp://erp", Events: []string{EventAll}})
+	assert.ErrorIs(t, err, ErrInvalidURL)
+
+	_, _, err = service.CreateSubscription(&SubscriptionRequest{URL: "https://erp.example.com", Events: []string{"order.eaten"}})
+	assert.ErrorIs(t, err, ErrInvalidEvents)
+
+	subscription, secret, err := service.CreateSubscription(&SubscriptionRequest{URL: "https://erp.example.com", Events: []string{EventAll}})
+	assert.NoError(t, err)
+	assert.Contains(t, secret, "whsec_")
+	assert.Equal(t, secret, subscription.Secret)
+}
+
+func TestClaimLease_OutlastsTimeout(t *testing.T) {
+	assert.Equal(t, minClaimLease, NewServiceWithOptions(nil, Options{Timeout: 10 * time.Second}).claimLease())
+	assert.Equal(t, 6*time.Minute, NewServiceWithOptions(nil, Options{Timeout: 5 * time.Minute}).claimLease())
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// A claimed delivery is hidden from other workers while it is being sent:
+// for the HTTP timeout plus claimMargin, and never less than minClaimLease.
+const (
+	minClaimLease = 2 * time.Minute
+	claimMargin   = time.Minute
+)
+
+// Worker sends due deliveries to their receivers.
+type Worker struct {
+	service   *Service
+	logger    *zap.Logger
+	batchSize int
+}
+
+func NewWorker(service *Service, logger *zap.Logger) *Worker {
+	return &Worker{service: service, logger: logger, batchSize: 50}
+}
+
+// Run sends due deliveries every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.RunOnce(ctx); err != nil {
+				w.logger.Error("webhook delivery run failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RunOnce attempts every pending delivery that is due and whose subscription
+// is active, and returns how many were attempted.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	s := w.service
+	now := s.now()
+
+	var due []Delivery
+	if err := s.db.WithContext(ctx).
+		Joins("JOIN webhook_subscriptions ON webhook_subscriptions.id = webhook_deliveries.subscription_id AND webhook_subscriptions.active = ?", true).
+		Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", DeliveryPending, now).
+		Order("webhook_deliveries.id").
+		Limit(w.batchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			return attempted, ctx.Err()
+		}
+		delivery := &due[i]
+
+		claim := s.db.Model(&Delivery{}).
+			Where("id = ? AND next_attempt_at = ?", delivery.ID, delivery.NextAttemptAt).
+			Update("next_attempt_at", now.Add(s.claimLease()))
+		if claim.Error != nil {
+			return attempted, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		// Reload the subscription: an earlier delivery in this batch may
+		// have disabled it.
+		subscription, err := s.GetSubscription(delivery.SubscriptionID)
+		if err != nil {
+			return attempted, err
+		}
+		if !subscription.Active {
+			continue
+		}
+
+		if err := s.attempt(ctx, subscription, delivery, false); err != nil {
+			return attempted, err
+		}
+		attempted++
+		if delivery.Status != DeliverySucceeded {
+			w.logger.Warn("webhook delivery failed",
+				zap.Uint("delivery_id", delivery.ID),
+				zap.Uint("subscription_id", subscription.ID),
+				zap.Int("attempt", delivery.Attempts),
+				zap.String("error", delivery.Error))
+		}
+	}
+	return attempted, nil
+}
+
+// claimLease outlasts the request to the receiver, so the delivery cannot be
+// claimed again while it is still being sent.
+func (s *Service) claimLease() time.Duration {
+	return max(minClaimLease, s.options.Timeout+claimMargin)
+}
@@ -0,0 +1,238 @@
+package wishlist
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oguzhan/e-commerce/pkg/middleware"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func parseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		middleware.WriteError(c, ErrInvalidID.WithMessage("invalid "+param))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *Handler) writeView(c *gin.Context, status int, list *Wishlist) {
+	view, err := h.service.View(list)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(status, view)
+}
+
+func (h *Handler) ListWishlists(c *gin.Context) {
+	lists, err := h.service.ListWishlists(c.GetUint("user_id"))
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	views := make([]*View, 0, len(lists))
+	for i := range lists {
+		view, err := h.service.View(&lists[i])
+		if err != nil {
+			middleware.WriteError(c, err)
+			return
+		}
+		views = append(views, view)
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *Handler) CreateWishlist(c *gin.Context) {
+	var req struct {
+		Name     string `json:"name" binding:"required"`
+		IsShared bool   `json:"is_shared"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	list, err := h.service.CreateWishlist(c.GetUint("user_id"), req.Name, req.IsShared)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	h.writeView(c, http.StatusCreated, list)
+}
+
+func (h *Handler) GetWishlist(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	list, err := h.service.GetWishlist(c.GetUint("user_id"), id)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	h.writeView(c, http.StatusOK, list)
+}
+
+func (h *Handler) UpdateWishlist(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req struct {
+		Name     *string `json:"name"`
+		IsShared *bool   `json:"is_shared"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	list, err := h.service.UpdateWishlist(c.GetUint("user_id"), id, req.Name, req.IsShared)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	h.writeView(c, http.StatusOK, list)
+}
+
+func (h *Handler) DeleteWishlist(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteWishlist(c.GetUint("user_id"), id); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedWishlist serves a shared list to anyone holding its link, without
+// exposing the owner.
+func (h *Handler) GetSharedWishlist(c *gin.Context) {
+	list, err := h.service.GetSharedWishlist(c.Param("token"))
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	view, err := h.service.View(list)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	view.UserID = 0
+	view.ShareToken = ""
+	c.JSON(http.StatusOK, view)
+}
+
+type itemRequest struct {
+	Quantity          int  `json:"quantity" binding:"omitempty,min=1"`
+	NotifyBackInStock bool `json:"notify_back_in_stock"`
+	NotifyPriceDrop   bool `json:"notify_price_drop"`
+}
+
+func (r itemRequest) options() ItemOptions {
+	return ItemOptions{
+		Quantity:          r.Quantity,
+		NotifyBackInStock: r.NotifyBackInStock,
+		NotifyPriceDrop:   r.NotifyPriceDrop,
+	}
+}
+
+func (h *Handler) AddItem(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	var req struct {
+		ProductID uint `json:"product_id" binding:"required"`
+		itemRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	item, err := h.service.AddItem(c.GetUint("user_id"), id, req.ProductID, req.options())
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *Handler) UpdateItem(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := parseID(c, "itemId")
+	if !ok {
+		return
+	}
+	var req itemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	item, err := h.service.UpdateItem(c.GetUint("user_id"), id, itemID, req.options())
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) RemoveItem(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := parseID(c, "itemId")
+	if !ok {
+		return
+	}
+	if err := h.service.RemoveItem(c.GetUint("user_id"), id, itemID); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) MoveToCart(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := parseID(c, "itemId")
+	if !ok {
+		return
+	}
+	if err := h.service.MoveToCart(c.GetUint("user_id"), id, itemID); err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SaveForLater moves a cart line, named by the :id path parameter, onto the
+// user's saved-for-later list.
+func (h *Handler) SaveForLater(c *gin.Context) {
+	cartItemID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	item, err := h.service.SaveForLater(c.GetUint("user_id"), cartItemID)
+	if err != nil {
+		middleware.WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
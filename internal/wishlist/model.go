@@ -0,0 +1,42 @@
+package wishlist
+
+import (
+	"time"
+)
+
+type Kind string
+
+const (
+	KindWishlist      Kind = "wishlist"
+	KindSavedForLater Kind = "saved_for_later"
+)
+
+type Wishlist struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	UserID     uint           `json:"user_id" gorm:"not null;index"`
+	Name       string         `json:"name" gorm:"not null"`
+	Kind       Kind           `json:"kind" gorm:"type:varchar(20);default:'wishlist'"`
+	IsShared   bool           `json:"is_shared" gorm:"default:false"`
+	ShareToken string         `json:"share_token,omitempty" gorm:"uniqueIndex:idx_wishlists_share_token,where:share_token <> ''"`
+	Items      []WishlistItem `json:"items" gorm:"foreignKey:WishlistID"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+type WishlistItem struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	WishlistID        uint      `json:"wishlist_id" gorm:"uniqueIndex:idx_wishlist_items_product"`
+	ProductID         uint      `json:"product_id" gorm:"uniqueIndex:idx_wishlist_items_product"`
+	Quantity          int       `json:"quantity" gorm:"default:1"`
+	PriceAtAdd        float64   `json:"price_at_add"`
+	InStockAtAdd      bool      `json:"in_stock_at_add"`
+	NotifyBackInStock bool      `json:"notify_back_in_stock"`
+	NotifyPriceDrop   bool      `json:"notify_price_drop"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Models returns the wishlist tables for schema migration.
+func Models() []interface{} {
+	return []interface{}{&Wishlist{}, &WishlistItem{}}
+}
@@ -0,0 +1,287 @@
+package wishlist
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/oguzhan/e-commerce/internal/cart"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWishlistNotFound = apperrors.New(http.StatusNotFound, "wishlist_not_found", "wishlist not found")
+	ErrItemNotFound     = apperrors.New(http.StatusNotFound, "wishlist_item_not_found", "wishlist item not found")
+	ErrProductNotFound  = apperrors.New(http.StatusNotFound, "product_not_found", "product not found")
+	ErrNameRequired     = apperrors.New(http.StatusBadRequest, "name_required", "wishlist name is required")
+	ErrInvalidID        = apperrors.New(http.StatusBadRequest, "invalid_id", "invalid ID")
+)
+
+const savedForLaterName = "Saved for later"
+
+type ItemOptions struct {
+	Quantity          int
+	NotifyBackInStock bool
+	NotifyPriceDrop   bool
+}
+
+type Service struct {
+	db    *gorm.DB
+	carts *cart.Service
+}
+
+func NewService(db *gorm.DB, carts *cart.Service) *Service {
+	return &Service{db: db, carts: carts}
+}
+
+func (s *Service) CreateWishlist(userID uint, name string, shared bool) (*Wishlist, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	list := Wishlist{UserID: userID, Name: name, Kind: KindWishlist}
+	if err := setShared(&list, shared); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&list).Error; err != nil {
+		return nil, err
+	}
+	list.Items = []WishlistItem{}
+	return &list, nil
+}
+
+func (s *Service) ListWishlists(userID uint) ([]Wishlist, error) {
+	var lists []Wishlist
+	err := s.db.Preload("Items").Where("user_id = ?", userID).Order("id").Find(&lists).Error
+	return lists, err
+}
+
+func (s *Service) GetWishlist(userID, id uint) (*Wishlist, error) {
+	var list Wishlist
+	err := s.db.Preload("Items").Where("id = ? AND user_id = ?", id, userID).First(&list).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWishlistNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetSharedWishlist loads a wishlist by its share token. Lists that are no
+// longer shared are reported as not found.
+func (s *Service) GetSharedWishlist(token string) (*Wishlist, error) {
+	if token == "" {
+		return nil, ErrWishlistNotFound
+	}
+	var list Wishlist
+	err := s.db.Preload("Items").Where("share_token = ? AND is_shared = ?", token, true).First(&list).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWishlistNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// UpdateWishlist renames a list and turns sharing on or off. Turning sharing
+// off drops the token, so re-sharing later produces a new link.
+func (s *Service) UpdateWishlist(userID, id uint, name *string, shared *bool) (*Wishlist, error) {
+	list, err := s.GetWishlist(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		trimmed := strings.TrimSpace(*name)
+		if trimmed == "" {
+			return nil, ErrNameRequired
+		}
+		list.Name = trimmed
+	}
+	if shared != nil {
+		if err := setShared(list, *shared); err != nil {
+			return nil, err
+		}
+	}
+	err = s.db.Model(list).Select("name", "is_shared", "share_token").Updates(list).Error
+	return list, err
+}
+
+func (s *Service) DeleteWishlist(userID, id uint) error {
+	list, err := s.GetWishlist(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("wishlist_id = ?", list.ID).Delete(&WishlistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(list).Error
+	})
+}
+
+// AddItem puts a product on the list, or updates its options when the
+// product is already there.
+func (s *Service) AddItem(userID, wishlistID, productID uint, options ItemOptions) (*WishlistItem, error) {
+	list, err := s.GetWishlist(userID, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+	return s.addItem(s.db, list, productID, options)
+}
+
+func (s *Service) addItem(tx *gorm.DB, list *Wishlist, productID uint, options ItemOptions) (*WishlistItem, error) {
+	var product models.Product
+	err := tx.First(&product, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if options.Quantity <= 0 {
+		options.Quantity = 1
+	}
+
+	item := WishlistItem{
+		WishlistID:        list.ID,
+		ProductID:         productID,
+		Quantity:          options.Quantity,
+		PriceAtAdd:        product.Price,
+		InStockAtAdd:      product.IsActive && product.Stock > 0,
+		NotifyBackInStock: options.NotifyBackInStock,
+		NotifyPriceDrop:   options.NotifyPriceDrop,
+	}
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "wishlist_id"}, {Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity", "notify_back_in_stock", "notify_price_drop", "updated_at"}),
+	}).Create(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Where("wishlist_id = ? AND product_id = ?", list.ID, productID).First(&item).Error
+	return &item, err
+}
+
+func (s *Service) UpdateItem(userID, wishlistID, itemID uint, options ItemOptions) (*WishlistItem, error) {
+	item, err := s.getItem(userID, wishlistID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if options.Quantity > 0 {
+		item.Quantity = options.Quantity
+	}
+	item.NotifyBackInStock = options.NotifyBackInStock
+	item.NotifyPriceDrop = options.NotifyPriceDrop
+	err = s.db.Model(item).Select("quantity", "notify_back_in_stock", "notify_price_drop").Updates(item).Error
+	return item, err
+}
+
+func (s *Service) RemoveItem(userID, wishlistID, itemID uint) error {
+	item, err := s.getItem(userID, wishlistID, itemID)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(item).Error
+}
+
+func (s *Service) getItem(userID, wishlistID, itemID uint) (*WishlistItem, error) {
+	if _, err := s.GetWishlist(userID, wishlistID); err != nil {
+		return nil, err
+	}
+	var item WishlistItem
+	err := s.db.Where("id = ? AND wishlist_id = ?", itemID, wishlistID).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// MoveToCart adds the entry to the user's cart and takes it off the list.
+// Cart errors such as insufficient stock leave the entry in place.
+func (s *Service) MoveToCart(userID, wishlistID, itemID uint) error {
+	item, err := s.getItem(userID, wishlistID, itemID)
+	if err != nil {
+		return err
+	}
+	if err := s.carts.AddItem(userID, item.ProductID, item.Quantity); err != nil {
+		return err
+	}
+	return s.db.Delete(item).Error
+}
+
+// SaveForLater moves a line of the user's cart onto their saved-for-later
+// list, creating the list on first use.
+func (s *Service) SaveForLater(userID, cartItemID uint) (*WishlistItem, error) {
+	userCart, err := s.carts.GetCartByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var line *cart.CartItem
+	for i := range userCart.Items {
+		if userCart.Items[i].ID == cartItemID {
+			line = &userCart.Items[i]
+			break
+		}
+	}
+	if line == nil {
+		return nil, cart.ErrItemNotFound
+	}
+
+	list, err := s.SavedForLater(userID)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.addItem(s.db, list, line.ProductID, ItemOptions{Quantity: line.Quantity})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.carts.RemoveCartItem(userCart, line.ID); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// SavedForLater returns the user's saved-for-later list, creating it if needed.
+func (s *Service) SavedForLater(userID uint) (*Wishlist, error) {
+	list := Wishlist{UserID: userID, Kind: KindSavedForLater}
+	err := s.db.Preload("Items").
+		Where(Wishlist{UserID: userID, Kind: KindSavedForLater}).
+		Attrs(Wishlist{Name: savedForLaterName}).
+		FirstOrCreate(&list).Error
+	return &list, err
+}
+
+func setShared(list *Wishlist, shared bool) error {
+	list.IsShared = shared
+	if !shared {
+		list.ShareToken = ""
+		return nil
+	}
+	if list.ShareToken != "" {
+		return nil
+	}
+	token, err := newShareToken()
+	if err != nil {
+		return err
+	}
+	list.ShareToken = token
+	return nil
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
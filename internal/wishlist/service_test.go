@@ -0,0 +1,125 @@
+package wishlist
+
+import (
+	"testing"
+
+	"github.com/oguzhan/e-commerce/internal/cart"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	tables := append(append(Models(), cart.Models()...), &models.Product{})
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, product := range []models.Product{
+		{Name: "Keyboard", Price: 50, Stock: 10, SKU: "KB-1", IsActive: true},
+		{Name: "Mouse", Price: 20, Stock: 0, SKU: "MS-1", IsActive: true},
+	} {
+		if err := db.Create(&product).Error; err != nil {
+			t.Fatalf("Failed to create test product: %v", err)
+		}
+	}
+	return NewService(db, cart.NewService(db)), db
+}
+
+func TestWishlist_SharingUsesUnguessableToken(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	private, err := service.CreateWishlist(1, "Birthday", false)
+	assert.NoError(t, err)
+	assert.Empty(t, private.ShareToken)
+
+	shared := true
+	list, err := service.UpdateWishlist(1, private.ID, nil, &shared)
+	assert.NoError(t, err)
+	assert.Len(t, list.ShareToken, 32)
+
+	found, err := service.GetSharedWishlist(list.ShareToken)
+	assert.NoError(t, err)
+	assert.Equal(t, list.ID, found.ID)
+
+	shared = false
+	_, err = service.UpdateWishlist(1, private.ID, nil, &shared)
+	assert.NoError(t, err)
+	_, err = service.GetSharedWishlist(list.ShareToken)
+	assert.ErrorIs(t, err, ErrWishlistNotFound)
+
+	_, err = service.GetWishlist(2, private.ID)
+	assert.ErrorIs(t, err, ErrWishlistNotFound)
+}
+
+func TestWishlist_FlagsBackInStockAndPriceDrop(t *testing.T) {
+	service, db := setupTestService(t)
+	list, _ := service.CreateWishlist(1, "Desk", false)
+
+	_, err := service.AddItem(1, list.ID, 1, ItemOptions{NotifyPriceDrop: true})
+	assert.NoError(t, err)
+	_, err = service.AddItem(1, list.ID, 2, ItemOptions{NotifyBackInStock: true})
+	assert.NoError(t, err)
+
+	db.Model(&models.Product{}).Where("id = ?", 1).Update("price", 40)
+	db.Model(&models.Product{}).Where("id = ?", 2).Update("stock", 5)
+
+	list, _ = service.GetWishlist(1, list.ID)
+	view, err := service.View(list)
+	assert.NoError(t, err)
+	assert.Len(t, view.Items, 2)
+
+	keyboard, mouse := view.Items[0], view.Items[1]
+	assert.True(t, keyboard.PriceDropped)
+	assert.False(t, keyboard.BackInStock)
+	assert.Equal(t, 50.0, keyboard.PriceAtAdd)
+	assert.Equal(t, 40.0, keyboard.Price)
+	assert.True(t, mouse.BackInStock)
+	assert.True(t, mouse.NotifyBackInStock)
+}
+
+func TestWishlist_MoveToCartAndSaveForLater(t *testing.T) {
+	service, db := setupTestService(t)
+	list, _ := service.CreateWishlist(1, "Desk", false)
+	item, _ := service.AddItem(1, list.ID, 1, ItemOptions{Quantity: 2})
+
+	assert.NoError(t, service.MoveToCart(1, list.ID, item.ID))
+	userCart, _ := service.carts.GetCartByUserID(1)
+	assert.Len(t, userCart.Items, 1)
+	assert.Equal(t, 2, userCart.Items[0].Quantity)
+
+	var remaining int64
+	db.Model(&WishlistItem{}).Where("wishlist_id = ?", list.ID).Count(&remaining)
+	assert.Equal(t, int64(0), remaining)
+
+	saved, err := service.SaveForLater(1, userCart.Items[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, saved.Quantity)
+
+	userCart, _ = service.carts.GetCartByUserID(1)
+	assert.Empty(t, userCart.Items)
+
+	later, err := service.SavedForLater(1)
+	assert.NoError(t, err)
+	assert.Equal(t, KindSavedForLater, later.Kind)
+	assert.Len(t, later.Items, 1)
+
+	_, err = service.SaveForLater(1, 999)
+	assert.ErrorIs(t, err, cart.ErrItemNotFound)
+}
+
+func TestWishlist_MoveToCartKeepsEntryWhenOutOfStock(t *testing.T) {
+	service, _ := setupTestService(t)
+	list, _ := service.CreateWishlist(1, "Desk", false)
+	item, _ := service.AddItem(1, list.ID, 2, ItemOptions{})
+
+	err := service.MoveToCart(1, list.ID, item.ID)
+	assert.ErrorIs(t, err, cart.ErrInsufficientStock)
+
+	list, _ = service.GetWishlist(1, list.ID)
+	assert.Len(t, list.Items, 1)
+}
@@ -0,0 +1,90 @@
+package wishlist
+
+import (
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/models"
+)
+
+// View is a wishlist joined with live product data.
+type View struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id,omitempty"`
+	Name       string     `json:"name"`
+	Kind       Kind       `json:"kind"`
+	IsShared   bool       `json:"is_shared"`
+	ShareToken string     `json:"share_token,omitempty"`
+	Items      []ItemView `json:"items"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type ItemView struct {
+	ID                uint    `json:"id"`
+	ProductID         uint    `json:"product_id"`
+	Name              string  `json:"name"`
+	ImageURL          string  `json:"image_url"`
+	Quantity          int     `json:"quantity"`
+	Price             float64 `json:"price"`
+	PriceAtAdd        float64 `json:"price_at_add"`
+	Available         bool    `json:"available"`
+	InStock           bool    `json:"in_stock"`
+	BackInStock       bool    `json:"back_in_stock"`
+	PriceDropped      bool    `json:"price_dropped"`
+	NotifyBackInStock bool    `json:"notify_back_in_stock"`
+	NotifyPriceDrop   bool    `json:"notify_price_drop"`
+}
+
+// View prices the wishlist against the current catalog and flags entries
+// whose product came back in stock or dropped in price since it was added.
+func (s *Service) View(list *Wishlist) (*View, error) {
+	view := &View{
+		ID:         list.ID,
+		UserID:     list.UserID,
+		Name:       list.Name,
+		Kind:       list.Kind,
+		IsShared:   list.IsShared,
+		ShareToken: list.ShareToken,
+		Items:      make([]ItemView, 0, len(list.Items)),
+		CreatedAt:  list.CreatedAt,
+		UpdatedAt:  list.UpdatedAt,
+	}
+	if len(list.Items) == 0 {
+		return view, nil
+	}
+
+	productIDs := make([]uint, 0, len(list.Items))
+	for _, item := range list.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	var products []models.Product
+	if err := s.db.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for _, item := range list.Items {
+		line := ItemView{
+			ID:                item.ID,
+			ProductID:         item.ProductID,
+			Quantity:          item.Quantity,
+			PriceAtAdd:        item.PriceAtAdd,
+			NotifyBackInStock: item.NotifyBackInStock,
+			NotifyPriceDrop:   item.NotifyPriceDrop,
+		}
+		if product, ok := byID[item.ProductID]; ok {
+			line.Name = product.Name
+			line.ImageURL = product.ImageURL
+			line.Price = product.Price
+			line.Available = product.IsActive
+			line.InStock = product.IsActive && product.Stock > 0
+			line.BackInStock = line.InStock && !item.InStockAtAdd
+			line.PriceDropped = product.Price < item.PriceAtAdd
+		}
+		view.Items = append(view.Items, line)
+	}
+	return view, nil
+}
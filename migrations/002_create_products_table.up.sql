@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS products (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name TEXT NOT NULL,
+    description TEXT,
+    price DECIMAL NOT NULL,
+    stock BIGINT NOT NULL,
+    category TEXT,
+    image_url TEXT,
+    sku TEXT,
+    is_active BOOLEAN DEFAULT true,
+    rating_average DECIMAL DEFAULT 0,
+    rating_count BIGINT DEFAULT 0
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products (sku);
+CREATE INDEX IF NOT EXISTS idx_products_rating_average ON products (rating_average);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS contacts;
+DROP TABLE IF EXISTS addresses;
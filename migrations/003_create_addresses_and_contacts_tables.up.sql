@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS addresses (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    title TEXT NOT NULL,
+    address_line TEXT NOT NULL,
+    city TEXT NOT NULL,
+    state TEXT,
+    country TEXT NOT NULL,
+    postal_code TEXT NOT NULL,
+    is_default BOOLEAN DEFAULT false,
+    CONSTRAINT fk_users_addresses FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_addresses_deleted_at ON addresses (deleted_at);
+
+CREATE TABLE IF NOT EXISTS contacts (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    title TEXT NOT NULL,
+    phone_number TEXT NOT NULL,
+    is_default BOOLEAN DEFAULT false,
+    CONSTRAINT fk_users_contacts FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_contacts_deleted_at ON contacts (deleted_at);
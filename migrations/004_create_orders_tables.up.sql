@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id BIGINT NOT NULL,
+    guest_email TEXT,
+    status VARCHAR(20) DEFAULT 'pending',
+    total_amount DECIMAL NOT NULL,
+    shipping_address TEXT NOT NULL,
+    billing_address TEXT NOT NULL,
+    shipping_source_address_id BIGINT,
+    shipping_title TEXT,
+    shipping_address_line TEXT,
+    shipping_city TEXT,
+    shipping_state TEXT,
+    shipping_country TEXT,
+    shipping_postal_code TEXT,
+    billing_source_address_id BIGINT,
+    billing_title TEXT,
+    billing_address_line TEXT,
+    billing_city TEXT,
+    billing_state TEXT,
+    billing_country TEXT,
+    billing_postal_code TEXT,
+    payment_method TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_orders_guest_email ON orders (guest_email);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    order_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    price DECIMAL NOT NULL,
+    CONSTRAINT fk_orders_order_items FOREIGN KEY (order_id) REFERENCES orders (id),
+    CONSTRAINT fk_order_items_product FOREIGN KEY (product_id) REFERENCES products (id)
+);
+CREATE INDEX IF NOT EXISTS idx_order_items_deleted_at ON order_items (deleted_at);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    order_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    amount DECIMAL NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending',
+    payment_method TEXT NOT NULL,
+    transaction_id TEXT,
+    payment_date TIMESTAMPTZ,
+    CONSTRAINT fk_payments_order FOREIGN KEY (order_id) REFERENCES orders (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_transaction_id ON payments (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_payments_deleted_at ON payments (deleted_at);
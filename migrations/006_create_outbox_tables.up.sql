@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts BIGINT DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_name ON outbox_events (name);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_next_attempt_at ON outbox_events (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    outbox_id BIGINT,
+    name TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts BIGINT,
+    last_error TEXT,
+    occurred_at TIMESTAMPTZ,
+    failed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_name ON dead_letters (name);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_outbox_id ON dead_letters (outbox_id);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS carts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT,
+    guest_id TEXT,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_carts_user ON carts (user_id) WHERE user_id <> 0;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_carts_guest ON carts (guest_id) WHERE guest_id <> '';
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id BIGSERIAL PRIMARY KEY,
+    cart_id BIGINT,
+    product_id BIGINT,
+    quantity BIGINT,
+    unit_price DECIMAL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_carts_items FOREIGN KEY (cart_id) REFERENCES carts (id)
+);
+
+CREATE TABLE IF NOT EXISTS cart_reminders (
+    id BIGSERIAL PRIMARY KEY,
+    cart_id BIGINT,
+    stage TEXT,
+    idle_since TIMESTAMPTZ,
+    user_id BIGINT,
+    sent_at TIMESTAMPTZ,
+    recovered_order_id BIGINT,
+    recovered_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cart_reminders_stage ON cart_reminders (cart_id, stage, idle_since);
+CREATE INDEX IF NOT EXISTS idx_cart_reminders_user_id ON cart_reminders (user_id);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS wishlists (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    kind VARCHAR(20) DEFAULT 'wishlist',
+    is_shared BOOLEAN DEFAULT false,
+    share_token TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_wishlists_user_id ON wishlists (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlists_share_token ON wishlists (share_token) WHERE share_token <> '';
+
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    id BIGSERIAL PRIMARY KEY,
+    wishlist_id BIGINT,
+    product_id BIGINT,
+    quantity BIGINT DEFAULT 1,
+    price_at_add DECIMAL,
+    in_stock_at_add BOOLEAN,
+    notify_back_in_stock BOOLEAN,
+    notify_price_drop BOOLEAN,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_wishlists_items FOREIGN KEY (wishlist_id) REFERENCES wishlists (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlist_items_product ON wishlist_items (wishlist_id, product_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS helpful_votes;
+DROP TABLE IF EXISTS reviews;
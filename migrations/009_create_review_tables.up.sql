@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    rating BIGINT NOT NULL,
+    title TEXT,
+    body TEXT,
+    status VARCHAR(20) DEFAULT 'pending',
+    helpful_count BIGINT DEFAULT 0,
+    reply TEXT,
+    replied_at TIMESTAMPTZ,
+    moderated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_product_user ON reviews (product_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews (status);
+
+CREATE TABLE IF NOT EXISTS helpful_votes (
+    id BIGSERIAL PRIMARY KEY,
+    review_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_helpful_votes_review_user ON helpful_votes (review_id, user_id);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    event TEXT NOT NULL,
+    title TEXT,
+    body TEXT,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    email_enabled BOOLEAN NOT NULL,
+    sms_enabled BOOLEAN NOT NULL,
+    in_app_enabled BOOLEAN NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    contact_id BIGINT,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_preferences_user_id ON notification_preferences (user_id);
@@ -0,0 +1,3 @@
+-- On a fresh database these columns belong to migrations 001-007, so they
+-- are dropped with those tables rather than here.
+SELECT 1;
@@ -0,0 +1,9 @@
+// Package migrations embeds the versioned SQL schema migrations applied by
+// pkg/migrate. Add new ones with `go run ./cmd/migrate create <name>` and
+// never edit a migration that has been applied anywhere.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
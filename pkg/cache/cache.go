@@ -0,0 +1,86 @@
+// Package cache provides read-through caching over Redis or an in-process
+// LRU, with singleflight so concurrent misses for a key load it once.
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrMiss is returned by Get when the key is not cached.
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores JSON-encoded values by key.
+type Cache interface {
+	Get(ctx context.Context, key string, dest interface{}) error
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Key names a cached value of type T, so a key can only be read back as
+// the type it was written with.
+type Key[T any] string
+
+// ReadThrough loads values on a miss and stores them for TTL plus a random
+// share of Jitter, so entries written together do not expire together.
+type ReadThrough struct {
+	name   string
+	cache  Cache
+	ttl    time.Duration
+	jitter time.Duration
+	group  singleflight.Group
+}
+
+// NewReadThrough wraps cache. name labels the hit and miss metrics and the
+// spans recorded around cache calls.
+func NewReadThrough(name string, cache Cache, ttl, jitter time.Duration) *ReadThrough {
+	return &ReadThrough{name: name, cache: tracedCache{name: name, cache: cache}, ttl: ttl, jitter: jitter}
+}
+
+// Fetch returns the cached value for key, or calls load and caches its
+// result. Cache errors are treated as misses so an unavailable cache only
+// costs a database read.
+func Fetch[T any](ctx context.Context, rt *ReadThrough, key Key[T], load func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	if err := rt.cache.Get(ctx, string(key), &value); err == nil {
+		metrics.RecordCacheHit(rt.name)
+		return value, nil
+	}
+	metrics.RecordCacheMiss(rt.name)
+
+	result, err, _ := rt.group.Do(string(key), func() (interface{}, error) {
+		loaded, err := load(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		_ = rt.cache.Set(ctx, string(key), loaded, rt.expiration())
+		return loaded, nil
+	})
+	if err != nil {
+		return value, err
+	}
+	return result.(T), nil
+}
+
+// Invalidate removes keys. Callers should invalidate after the write has
+// committed, so a concurrent read cannot cache the old value again.
+func (rt *ReadThrough) Invalidate(ctx context.Context, keys ...string) error {
+	return rt.cache.Delete(ctx, keys...)
+}
+
+// Cache returns the underlying cache, for values managed outside Fetch.
+func (rt *ReadThrough) Cache() Cache {
+	return rt.cache
+}
+
+func (rt *ReadThrough) expiration() time.Duration {
+	if rt.jitter <= 0 {
+		return rt.ttl
+	}
+	return rt.ttl + time.Duration(rand.Int63n(int64(rt.jitter)))
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache_EvictsAndExpires(t *testing.T) {
+	c := NewLRUCache(2)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assert.NoError(t, c.Set(ctx, "a", 1, 0))
+	assert.NoError(t, c.Set(ctx, "b", 2, time.Minute))
+	var value int
+	assert.NoError(t, c.Get(ctx, "a", &value)) // a is now the most recent
+	assert.NoError(t, c.Set(ctx, "c", 3, 0))
+
+	assert.ErrorIs(t, c.Get(ctx, "b", &value), ErrMiss)
+	assert.NoError(t, c.Get(ctx, "a", &value))
+	assert.Equal(t, 1, value)
+
+	assert.NoError(t, c.Set(ctx, "a", 10, time.Second))
+	now = now.Add(time.Second)
+	assert.ErrorIs(t, c.Get(ctx, "a", &value), ErrMiss)
+	assert.Equal(t, 1, c.Len())
+
+	assert.NoError(t, c.Delete(ctx, "c", "missing"))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestFetch_LoadsOncePerKey(t *testing.T) {
+	rt := NewReadThrough("test", NewLRUCache(10), time.Minute, time.Second)
+	ctx := context.Background()
+	key := Key[[]string]("names")
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) ([]string, error) {
+		loads.Add(1)
+		<-release
+		return []string{"a", "b"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names, err := Fetch(ctx, rt, key, load)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"a", "b"}, names)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load())
+
+	names, err := Fetch(ctx, rt, key, load)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+	assert.Equal(t, int32(1), loads.Load())
+
+	// Errors are returned and not cached.
+	failing := Key[int]("failing")
+	boom := errors.New("boom")
+	_, err = Fetch(ctx, rt, failing, func(context.Context) (int, error) { return 0, boom })
+	assert.ErrorIs(t, err, boom)
+	n, err := Fetch(ctx, rt, failing, func(context.Context) (int, error) { return 7, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 7, n)
+
+	assert.NoError(t, rt.Invalidate(ctx, string(key)))
+	_, _ = Fetch(ctx, rt, key, func(context.Context) ([]string, error) { loads.Add(1); return nil, nil })
+	assert.Equal(t, int32(2), loads.Load())
+}
@@ -3,24 +3,34 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// RedisCache prefixes every key with its namespace, so several caches and
+// other Redis users can share a database.
 type RedisCache struct {
-	client *redis.Client
+	client    *redis.Client
+	namespace string
 }
 
-func NewRedisCache(addr, password string, db int) *RedisCache {
+func NewRedisCache(addr, password string, db int, namespace string) *RedisCache {
 	client := redis.NewClient(&redis.Options{
 		Addr:     addr,
 		Password: password,
 		DB:       db,
 	})
+	return NewRedisCacheWithClient(client, namespace)
+}
 
+// NewRedisCacheWithClient uses a client shared with other Redis users; the
+// owner of the client is responsible for closing it.
+func NewRedisCacheWithClient(client *redis.Client, namespace string) *RedisCache {
 	return &RedisCache{
-		client: client,
+		client:    client,
+		namespace: namespace,
 	}
 }
 
@@ -30,11 +40,14 @@ func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, exp
 		return err
 	}
 
-	return c.client.Set(ctx, key, json, expiration).Err()
+	return c.client.Set(ctx, c.namespace+key, json, expiration).Err()
 }
 
 func (c *RedisCache) Get(ctx context.Context, key string, dest interface{}) error {
-	val, err := c.client.Get(ctx, key).Result()
+	val, err := c.client.Get(ctx, c.namespace+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrMiss
+	}
 	if err != nil {
 		return err
 	}
@@ -42,12 +55,40 @@ func (c *RedisCache) Get(ctx context.Context, key string, dest interface{}) erro
 	return json.Unmarshal([]byte(val), dest)
 }
 
-func (c *RedisCache) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	namespaced := make([]string, len(keys))
+	for i, key := range keys {
+		namespaced[i] = c.namespace + key
+	}
+	return c.client.Del(ctx, namespaced...).Err()
 }
 
+// Clear deletes the keys in this cache's namespace only.
 func (c *RedisCache) Clear(ctx context.Context) error {
-	return c.client.FlushAll(ctx).Err()
+	if c.namespace == "" {
+		return errors.New("cache: refusing to clear a cache without a namespace")
+	}
+	iter := c.client.Scan(ctx, 0, c.namespace+"*", 500).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			if err := c.client.Del(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return c.client.Del(ctx, batch...).Err()
+	}
+	return nil
 }
 
 func (c *RedisCache) Close() error {
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/tracing"
+)
+
+// tracedCache records a span around every call to the wrapped cache.
+type tracedCache struct {
+	name  string
+	cache Cache
+}
+
+func (c tracedCache) start(ctx context.Context, op string, keys ...string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "cache."+op)
+	span.SetAttribute("cache.name", c.name)
+	if len(keys) == 1 {
+		span.SetAttribute("cache.key", keys[0])
+	} else {
+		span.SetAttribute("cache.keys", len(keys))
+	}
+	return ctx, span
+}
+
+func (c tracedCache) Get(ctx context.Context, key string, dest interface{}) error {
+	ctx, span := c.start(ctx, "get", key)
+	defer span.End()
+	err := c.cache.Get(ctx, key, dest)
+	span.SetAttribute("cache.hit", err == nil)
+	if err != nil && !errors.Is(err, ErrMiss) {
+		span.RecordError(err)
+	}
+	return err
+}
+
+func (c tracedCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	ctx, span := c.start(ctx, "set", key)
+	defer span.End()
+	err := c.cache.Set(ctx, key, value, expiration)
+	span.RecordError(err)
+	return err
+}
+
+func (c tracedCache) Delete(ctx context.Context, keys ...string) error {
+	ctx, span := c.start(ctx, "delete", keys...)
+	defer span.End()
+	err := c.cache.Delete(ctx, keys...)
+	span.RecordError(err)
+	return err
+}
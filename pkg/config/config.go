@@ -1,18 +1,45 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultFile is the configuration file read when neither --config nor
+// CONFIG_FILE names one. It is optional; a missing file is skipped.
+const DefaultFile = "config.yaml"
+
 type Config struct {
 	ServerPort string
 	Env        string
+	// APIMode is "monolith" to serve every route from cmd/api, or "gateway"
+	// to proxy the user, product, order and payment routes to their services.
+	APIMode string
+	// TrustedProxies lists the proxies whose X-Forwarded-For is believed
+	// when resolving client IPs. Empty means the peer address is used.
+	TrustedProxies []string
+
+	// Zero disables a timeout. WriteTimeout must outlast the slowest
+	// handler, including gateway route timeouts.
+	ServerReadTimeout       time.Duration
+	ServerReadHeaderTimeout time.Duration
+	ServerWriteTimeout      time.Duration
+	ServerIdleTimeout       time.Duration
+	// ServerDrainPeriod keeps serving after SIGTERM while readiness fails;
+	// ServerShutdownTimeout then bounds the rest of the shutdown.
+	ServerDrainPeriod     time.Duration
+	ServerShutdownTimeout time.Duration
 
+	// DBDriver is "postgres" or "sqlite". SQLite is meant for local
+	// development and tests; DBPath names its file, or ":memory:".
+	DBDriver   string
+	DBPath     string
 	DBHost     string
 	DBPort     int
 	DBUser     string
@@ -20,6 +47,26 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 
+	// DBSlowQueryThreshold logs queries that take longer; zero disables
+	// the log. Bind values are only logged with DBLogQueryValues.
+	DBSlowQueryThreshold time.Duration
+	DBLogQueryValues     bool
+	// DBMigrateOnStart applies pending SQL migrations when a service
+	// starts. Turn it off to run cmd/migrate as a separate deploy step.
+	DBMigrateOnStart bool
+
+	// Connection pool limits, applied to the primary and every replica.
+	// Zero leaves a limit off.
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	// DBReplicas are read replicas of the primary: hosts, optionally with a
+	// port, sharing its credentials, or file names with sqlite. Reads that
+	// opt in are spread over the replicas that passed their last check,
+	// run every DBReplicaCheckInterval.
+	DBReplicas             []string
+	DBReplicaCheckInterval time.Duration
+
 	RedisHost     string
 	RedisPort     int
 	RedisPassword string
@@ -28,91 +75,293 @@ type Config struct {
 	JWTSecret     string
 	JWTExpiration time.Duration
 
+	CartTokenSecret string
+	CartGuestTTL    time.Duration
+	CartMergePolicy string
+	// CartSecureCookie marks the guest cart cookie Secure even when the
+	// request reached the service over plain HTTP, as it does behind a
+	// proxy that terminates TLS.
+	CartSecureCookie bool
+
+	CartRemindersEnabled bool
+	CartReminderStages   []time.Duration
+	CartReminderInterval time.Duration
+
+	EventsPollInterval time.Duration
+	EventsMaxAttempts  int
+
+	WebhookTimeout      time.Duration
+	WebhookPollInterval time.Duration
+	WebhookMaxAttempts  int
+	WebhookDisableAfter int
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	SMSProviderURL string
+	SMSAPIKey      string
+	SMSFrom        string
+
 	PaymentServiceURL string
 	PaymentAPIKey     string
 
 	OrderServiceURL   string
 	ProductServiceURL string
+	ProductAPIKey     string
 	UserServiceURL    string
 
+	ServiceTimeout    time.Duration
+	ServiceMaxRetries int
+
+	GatewayRouteTimeouts  map[string]time.Duration
+	GatewayHealthInterval time.Duration
+
+	// MetricsPort serves /metrics apart from the API; 0 serves it on
+	// ServerPort.
 	EnableMetrics bool
 	MetricsPort   int
 
+	// HealthTimeout bounds each readiness check; results are reused for
+	// HealthCacheTTL.
+	HealthTimeout  time.Duration
+	HealthCacheTTL time.Duration
+
 	LogLevel  string
 	LogFormat string
-}
 
-func LoadConfig() (*Config, error) {
-	// Load .env file if it exists
-	_ = godotenv.Load()
+	// TracingExporter is "none", "stdout" or "file"; the file exporter
+	// appends JSON spans to TracingFile.
+	TracingExporter    string
+	TracingFile        string
+	TracingServiceName string
 
-	// Parse JWT expiration duration
-	jwtExpiration, err := time.ParseDuration(getEnv("JWT_EXPIRATION", "24h"))
-	if err != nil {
-		return nil, fmt.Errorf("error parsing JWT expiration: %v", err)
-	}
+	// CORSAllowedOrigins may hold exact origins, wildcard subdomains such
+	// as "https://*.example.com", or "*".
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	// CORSMaxAge is how long, in seconds, browsers may cache a preflight.
+	CORSMaxAge int
+
+	// CacheStore is "memory" for an in-process LRU of CacheLRUSize entries
+	// or "redis" to share cached reads across instances.
+	CacheStore     string
+	CacheTTL       time.Duration
+	CacheJitter    time.Duration
+	CacheLRUSize   int
+	CacheNamespace string
+
+	// RateLimitStore is "memory" for per-instance limits or "redis" to
+	// share them across instances.
+	RateLimitStore string
+	// RateLimitDefault and the RateLimitRoutes values are "limit/window"
+	// policies such as "100/1m". Routes are keyed by "METHOD /path" or
+	// "/path".
+	RateLimitDefault   string
+	RateLimitRoutes    map[string]string
+	RateLimitAllowList []string
+
+	SecurityHSTSMaxAge            time.Duration
+	SecurityHSTSIncludeSubdomains bool
+	SecurityContentSecurityPolicy string
+	SecurityFrameOptions          string
+	SecurityReferrerPolicy        string
+}
 
+// Default returns the configuration used when nothing overrides it.
+func Default() *Config {
 	return &Config{
-		ServerPort: getEnv("SERVER_PORT", "8081"),
-		Env:        getEnv("ENV", "development"),
+		ServerPort: "8081",
+		Env:        "development",
+		APIMode:    "monolith",
 
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnvAsInt("DB_PORT", 5432),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "ecommerce"),
-		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
+		ServerReadTimeout:       15 * time.Second,
+		ServerReadHeaderTimeout: 5 * time.Second,
+		ServerWriteTimeout:      60 * time.Second,
+		ServerIdleTimeout:       2 * time.Minute,
+		ServerDrainPeriod:       5 * time.Second,
+		ServerShutdownTimeout:   30 * time.Second,
 
-		RedisHost:     getEnv("REDIS_HOST", "localhost"),
-		RedisPort:     getEnvAsInt("REDIS_PORT", 6379),
-		RedisPassword: getEnv("REDIS_PASSWORD", ""),
-		RedisDB:       getEnvAsInt("REDIS_DB", 0),
+		DBDriver:   "postgres",
+		DBPath:     "ecommerce.db",
+		DBHost:     "localhost",
+		DBPort:     5432,
+		DBUser:     "postgres",
+		DBPassword: "postgres",
+		DBName:     "ecommerce",
+		DBSSLMode:  "disable",
 
-		JWTSecret:     getEnv("JWT_SECRET", "your-secret-key"),
-		JWTExpiration: jwtExpiration,
+		DBSlowQueryThreshold: 200 * time.Millisecond,
+		DBMigrateOnStart:     true,
 
-		PaymentServiceURL: getEnv("PAYMENT_SERVICE_URL", "http://localhost:8084"),
-		PaymentAPIKey:     getEnv("PAYMENT_API_KEY", ""),
+		DBMaxOpenConns:         25,
+		DBMaxIdleConns:         10,
+		DBConnMaxLifetime:      30 * time.Minute,
+		DBReplicaCheckInterval: 5 * time.Second,
 
-		OrderServiceURL:   getEnv("ORDER_SERVICE_URL", "http://localhost:8082"),
-		ProductServiceURL: getEnv("PRODUCT_SERVICE_URL", "http://localhost:8083"),
-		UserServiceURL:    getEnv("USER_SERVICE_URL", "http://localhost:8081"),
+		RedisHost: "localhost",
+		RedisPort: 6379,
 
-		EnableMetrics: getEnvAsBool("ENABLE_METRICS", true),
-		MetricsPort:   getEnvAsInt("METRICS_PORT", 9090),
+		JWTSecret:     "your-secret-key",
+		JWTExpiration: 24 * time.Hour,
 
-		LogLevel:  getEnv("LOG_LEVEL", "debug"),
-		LogFormat: getEnv("LOG_FORMAT", "json"),
-	}, nil
-}
+		CartGuestTTL:    168 * time.Hour,
+		CartMergePolicy: "sum",
+
+		CartRemindersEnabled: true,
+		CartReminderStages:   []time.Duration{time.Hour, 24 * time.Hour, 72 * time.Hour},
+		CartReminderInterval: 15 * time.Minute,
+
+		EventsPollInterval: time.Second,
+		EventsMaxAttempts:  8,
+
+		WebhookTimeout:      10 * time.Second,
+		WebhookPollInterval: 5 * time.Second,
+		WebhookMaxAttempts:  8,
+		WebhookDisableAfter: 20,
+
+		SMTPPort: 587,
+		SMTPFrom: "no-reply@example.com",
+
+		PaymentServiceURL: "http://localhost:8084",
+		OrderServiceURL:   "http://localhost:8082",
+		ProductServiceURL: "http://localhost:8083",
+		UserServiceURL:    "http://localhost:8081",
+
+		ServiceTimeout:    5 * time.Second,
+		ServiceMaxRetries: 2,
 
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+		GatewayRouteTimeouts: map[string]time.Duration{
+			"users":    10 * time.Second,
+			"products": 10 * time.Second,
+			"orders":   30 * time.Second,
+			"payments": 30 * time.Second,
+		},
+		GatewayHealthInterval: 10 * time.Second,
+
+		EnableMetrics: true,
+		MetricsPort:   9090,
+
+		HealthTimeout:  2 * time.Second,
+		HealthCacheTTL: 2 * time.Second,
+
+		LogLevel:  "debug",
+		LogFormat: "json",
+
+		TracingExporter:    "none",
+		TracingFile:        "logs/traces.jsonl",
+		TracingServiceName: "api",
+
+		CORSAllowedOrigins: []string{"*"},
+		CORSAllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		CORSAllowedHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "traceparent", "X-Consistency"},
+		CORSExposedHeaders: []string{"X-Request-ID", "traceparent"},
+		CORSMaxAge:         300,
+
+		CacheStore:     "memory",
+		CacheTTL:       time.Minute,
+		CacheJitter:    15 * time.Second,
+		CacheLRUSize:   10000,
+		CacheNamespace: "ecommerce:cache:",
+
+		RateLimitStore:   "memory",
+		RateLimitDefault: "100/1m",
+		RateLimitRoutes: map[string]string{
+			"POST /api/v1/auth/login":    "5/1m",
+			"POST /api/v1/auth/register": "10/1h",
+			"GET /api/v1/products":       "300/1m",
+		},
+
+		SecurityHSTSMaxAge:            365 * 24 * time.Hour,
+		SecurityHSTSIncludeSubdomains: true,
+		SecurityContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+		SecurityFrameOptions:          "DENY",
+		SecurityReferrerPolicy:        "no-referrer",
 	}
-	return defaultValue
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
-	}
-	value, err := strconv.Atoi(valueStr)
+// LoadConfig builds the configuration from the defaults, then the YAML file,
+// then environment variables (including a .env file), then command line
+// flags, and validates the result. With --print-config it writes the
+// effective configuration, secrets redacted, to stdout and exits.
+func LoadConfig() (*Config, error) {
+	cfg, _, err := LoadConfigArgs()
+	return cfg, err
+}
+
+// LoadConfigArgs is LoadConfig for commands that take arguments after the
+// flags, such as cmd/migrate. It also returns those arguments.
+func LoadConfigArgs() (*Config, []string, error) {
+	_ = godotenv.Load()
+
+	cfg, printConfig, args, err := parse(os.Args[1:], os.LookupEnv)
 	if err != nil {
-		return defaultValue
+		return nil, nil, err
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			return nil, nil, err
+		}
+		os.Exit(0)
 	}
-	return value
+	return cfg, args, nil
 }
 
-func getEnvAsBool(key string, defaultValue bool) bool {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, bool, error) {
+	cfg, printConfig, _, err := parse(args, lookupEnv)
+	return cfg, printConfig, err
+}
+
+func parse(args []string, lookupEnv func(string) (string, bool)) (*Config, bool, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", "", "path to the YAML configuration file")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	for _, s := range settings {
+		fs.String(s.key, "", "overrides "+s.key)
 	}
-	value, err := strconv.ParseBool(valueStr)
-	if err != nil {
-		return defaultValue
+	if err := fs.Parse(args); err != nil {
+		return nil, false, nil, fmt.Errorf("config: %w", err)
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultFile
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := applyYAML(settings, data); err != nil {
+			return nil, false, nil, fmt.Errorf("config: %s: %w", path, err)
+		}
+	case explicit || !errors.Is(err, os.ErrNotExist):
+		return nil, false, nil, fmt.Errorf("config: %w", err)
+	}
+
+	if err := applyEnv(settings, lookupEnv); err != nil {
+		return nil, false, nil, fmt.Errorf("config: %w", err)
+	}
+	if err := applyFlags(settings, fs); err != nil {
+		return nil, false, nil, fmt.Errorf("config: %w", err)
+	}
+
+	if cfg.CartTokenSecret == "" {
+		cfg.CartTokenSecret = cfg.JWTSecret
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, false, nil, fmt.Errorf("config: %w", err)
 	}
-	return value
+	return cfg, *printConfig, fs.Args(), nil
 }
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func envFrom(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func TestLoad_LayersFileEnvAndFlags(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 8080
+database:
+  host: db.internal
+  port: 5433
+services:
+  payment:
+    api_key: from-file
+cart:
+  reminders:
+    stages: [30m, 2h]
+gateway:
+  route_timeouts:
+    orders: 45s
+cors:
+  allowed_origins:
+    - https://shop.example.com
+    - https://admin.example.com
+  max_age: 600
+`)
+
+	cfg, printConfig, err := load(
+		[]string{"--config", path, "--database.port", "6000"},
+		envFrom(map[string]string{"DB_PORT": "5999", "DB_HOST": "db.env", "JWT_SECRET": "env-secret"}),
+	)
+	assert.NoError(t, err)
+	assert.False(t, printConfig)
+	assert.Equal(t, "8080", cfg.ServerPort)
+	assert.Equal(t, "db.env", cfg.DBHost)
+	assert.Equal(t, 6000, cfg.DBPort)
+	assert.Equal(t, "from-file", cfg.PaymentAPIKey)
+	assert.Equal(t, []time.Duration{30 * time.Minute, 2 * time.Hour}, cfg.CartReminderStages)
+	assert.Equal(t, map[string]time.Duration{"orders": 45 * time.Second}, cfg.GatewayRouteTimeouts)
+	assert.Equal(t, []string{"https://shop.example.com", "https://admin.example.com"}, cfg.CORSAllowedOrigins)
+	assert.Equal(t, 600, cfg.CORSMaxAge)
+	// Untouched settings keep their defaults and the cart secret follows the JWT one.
+	assert.Equal(t, "ecommerce", cfg.DBName)
+	assert.Equal(t, "env-secret", cfg.CartTokenSecret)
+}
+
+func TestLoad_RejectsInvalidValues(t *testing.T) {
+	_, _, err := load(nil, envFrom(map[string]string{"CONFIG_FILE": "", "DB_PORT": "abc"}))
+	assert.ErrorContains(t, err, `DB_PORT: invalid integer "abc"`)
+
+	path := writeFile(t, "config.yaml", "database:\n  hots: localhost\n")
+	_, _, err = load([]string{"--config", path}, envFrom(nil))
+	assert.ErrorContains(t, err, "database.hots: unknown key")
+
+	_, _, err = load([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")}, envFrom(nil))
+	assert.Error(t, err)
+
+	_, _, err = load(nil, envFrom(map[string]string{
+		"API_MODE":               "proxy",
+		"REDIS_PORT":             "70000",
+		"ORDER_SERVICE_URL":      "localhost:8082",
+		"ENV":                    "production",
+		"SERVICE_MAX_RETRIES":    "-1",
+		"CORS_ALLOW_CREDENTIALS": "true",
+		"DB_DRIVER":              "mysql",
+		"DB_MAX_OPEN_CONNS":      "-1",
+		"DB_REPLICAS":            "replica-1:5432,replica-2:99999",
+	}))
+	assert.ErrorContains(t, err, "server.mode")
+	assert.ErrorContains(t, err, "redis.port")
+	assert.ErrorContains(t, err, "services.order.url")
+	assert.ErrorContains(t, err, "jwt.secret")
+	assert.ErrorContains(t, err, "services.max_retries")
+	assert.ErrorContains(t, err, "cors.allowed_origins")
+	assert.ErrorContains(t, err, "database.driver")
+	assert.ErrorContains(t, err, "database.max_open_conns")
+	assert.ErrorContains(t, err, `database.replicas: "replica-2:99999" has an invalid port`)
+	assert.NotContains(t, err.Error(), "replica-1")
+}
+
+func TestLoad_RejectsDefaultSecretsOutsideDevelopment(t *testing.T) {
+	_, _, err := load(nil, envFrom(map[string]string{"CONFIG_FILE": "", "ENV": "staging"}))
+	assert.ErrorContains(t, err, "jwt.secret: the default secret may only be used in development")
+
+	_, _, err = load(nil, envFrom(map[string]string{"CONFIG_FILE": "", "ENV": "staging", "JWT_SECRET": "s3cret", "CART_TOKEN_SECRET": "your-secret-key"}))
+	assert.ErrorContains(t, err, "cart.token_secret")
+	assert.NotContains(t, err.Error(), "jwt.secret")
+
+	_, _, err = load(nil, envFrom(map[string]string{"CONFIG_FILE": "", "ENV": "staging", "JWT_SECRET": "s3cret"}))
+	assert.NoError(t, err)
+}
+
+func TestLoad_SecretsFromFiles(t *testing.T) {
+	dbPassword := writeFile(t, "db_password", "s3cret\n")
+	apiKey := writeFile(t, "api_key", "key-from-file")
+	path := writeFile(t, "config.yaml", "services:\n  payment:\n    api_key_file: "+apiKey+"\n")
+
+	cfg, _, err := load([]string{"--config", path}, envFrom(map[string]string{"DB_PASSWORD_FILE": dbPassword}))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.DBPassword)
+	assert.Equal(t, "key-from-file", cfg.PaymentAPIKey)
+
+	_, _, err = load(nil, envFrom(map[string]string{"JWT_SECRET_FILE": filepath.Join(t.TempDir(), "nope")}))
+	assert.ErrorContains(t, err, "JWT_SECRET_FILE")
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg, printConfig, err := load([]string{"--print-config"}, envFrom(map[string]string{"DB_PASSWORD": "hunter2", "REDIS_PASSWORD": ""}))
+	assert.NoError(t, err)
+	assert.True(t, printConfig)
+
+	var out bytes.Buffer
+	assert.NoError(t, cfg.Print(&out))
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.Contains(t, out.String(), "password: '[REDACTED]'")
+	assert.Contains(t, out.String(), "  payment:\n    url: http://localhost:8084")
+
+	// The printed configuration loads back to the same values.
+	path := writeFile(t, "printed.yaml", out.String())
+	reloaded, _, err := load([]string{"--config", path}, envFrom(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.GatewayRouteTimeouts, reloaded.GatewayRouteTimeouts)
+	assert.Equal(t, cfg.CORSAllowedHeaders, reloaded.CORSAllowedHeaders)
+}
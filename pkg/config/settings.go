@@ -0,0 +1,495 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// setting ties a Config field to its dotted YAML key, its environment
+// variable and the flag of the same name as the key.
+type setting struct {
+	key    string
+	env    string
+	secret bool
+	value  value
+}
+
+// value is a Config field that can be set from its text form.
+type value interface {
+	Set(string) error
+	String() string
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "server.port", env: "SERVER_PORT", value: (*stringValue)(&c.ServerPort)},
+		{key: "server.env", env: "ENV", value: (*stringValue)(&c.Env)},
+		{key: "server.mode", env: "API_MODE", value: (*stringValue)(&c.APIMode)},
+		{key: "server.trusted_proxies", env: "TRUSTED_PROXIES", value: (*stringsValue)(&c.TrustedProxies)},
+		{key: "server.read_timeout", env: "SERVER_READ_TIMEOUT", value: (*durationValue)(&c.ServerReadTimeout)},
+		{key: "server.read_header_timeout", env: "SERVER_READ_HEADER_TIMEOUT", value: (*durationValue)(&c.ServerReadHeaderTimeout)},
+		{key: "server.write_timeout", env: "SERVER_WRITE_TIMEOUT", value: (*durationValue)(&c.ServerWriteTimeout)},
+		{key: "server.idle_timeout", env: "SERVER_IDLE_TIMEOUT", value: (*durationValue)(&c.ServerIdleTimeout)},
+		{key: "server.drain_period", env: "SERVER_DRAIN_PERIOD", value: (*durationValue)(&c.ServerDrainPeriod)},
+		{key: "server.shutdown_timeout", env: "SERVER_SHUTDOWN_TIMEOUT", value: (*durationValue)(&c.ServerShutdownTimeout)},
+
+		{key: "database.driver", env: "DB_DRIVER", value: (*stringValue)(&c.DBDriver)},
+		{key: "database.path", env: "DB_PATH", value: (*stringValue)(&c.DBPath)},
+		{key: "database.host", env: "DB_HOST", value: (*stringValue)(&c.DBHost)},
+		{key: "database.port", env: "DB_PORT", value: (*intValue)(&c.DBPort)},
+		{key: "database.user", env: "DB_USER", value: (*stringValue)(&c.DBUser)},
+		{key: "database.password", env: "DB_PASSWORD", secret: true, value: (*stringValue)(&c.DBPassword)},
+		{key: "database.name", env: "DB_NAME", value: (*stringValue)(&c.DBName)},
+		{key: "database.sslmode", env: "DB_SSL_MODE", value: (*stringValue)(&c.DBSSLMode)},
+		{key: "database.slow_query_threshold", env: "DB_SLOW_QUERY_THRESHOLD", value: (*durationValue)(&c.DBSlowQueryThreshold)},
+		{key: "database.log_query_values", env: "DB_LOG_QUERY_VALUES", value: (*boolValue)(&c.DBLogQueryValues)},
+		{key: "database.migrate_on_start", env: "DB_MIGRATE_ON_START", value: (*boolValue)(&c.DBMigrateOnStart)},
+		{key: "database.max_open_conns", env: "DB_MAX_OPEN_CONNS", value: (*intValue)(&c.DBMaxOpenConns)},
+		{key: "database.max_idle_conns", env: "DB_MAX_IDLE_CONNS", value: (*intValue)(&c.DBMaxIdleConns)},
+		{key: "database.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", value: (*durationValue)(&c.DBConnMaxLifetime)},
+		{key: "database.replicas", env: "DB_REPLICAS", value: (*stringsValue)(&c.DBReplicas)},
+		{key: "database.replica_check_interval", env: "DB_REPLICA_CHECK_INTERVAL", value: (*durationValue)(&c.DBReplicaCheckInterval)},
+
+		{key: "redis.host", env: "REDIS_HOST", value: (*stringValue)(&c.RedisHost)},
+		{key: "redis.port", env: "REDIS_PORT", value: (*intValue)(&c.RedisPort)},
+		{key: "redis.password", env: "REDIS_PASSWORD", secret: true, value: (*stringValue)(&c.RedisPassword)},
+		{key: "redis.db", env: "REDIS_DB", value: (*intValue)(&c.RedisDB)},
+
+		{key: "jwt.secret", env: "JWT_SECRET", secret: true, value: (*stringValue)(&c.JWTSecret)},
+		{key: "jwt.expiration", env: "JWT_EXPIRATION", value: (*durationValue)(&c.JWTExpiration)},
+
+		{key: "cart.token_secret", env: "CART_TOKEN_SECRET", secret: true, value: (*stringValue)(&c.CartTokenSecret)},
+		{key: "cart.guest_ttl", env: "CART_GUEST_TTL", value: (*durationValue)(&c.CartGuestTTL)},
+		{key: "cart.merge_policy", env: "CART_MERGE_POLICY", value: (*stringValue)(&c.CartMergePolicy)},
+		{key: "cart.secure_cookie", env: "CART_SECURE_COOKIE", value: (*boolValue)(&c.CartSecureCookie)},
+		{key: "cart.reminders.enabled", env: "CART_REMINDERS_ENABLED", value: (*boolValue)(&c.CartRemindersEnabled)},
+		{key: "cart.reminders.stages", env: "CART_REMINDER_STAGES", value: (*durationsValue)(&c.CartReminderStages)},
+		{key: "cart.reminders.interval", env: "CART_REMINDER_INTERVAL", value: (*durationValue)(&c.CartReminderInterval)},
+
+		{key: "events.poll_interval", env: "EVENTS_POLL_INTERVAL", value: (*durationValue)(&c.EventsPollInterval)},
+		{key: "events.max_attempts", env: "EVENTS_MAX_ATTEMPTS", value: (*intValue)(&c.EventsMaxAttempts)},
+
+		{key: "webhooks.timeout", env: "WEBHOOK_TIMEOUT", value: (*durationValue)(&c.WebhookTimeout)},
+		{key: "webhooks.poll_interval", env: "WEBHOOK_POLL_INTERVAL", value: (*durationValue)(&c.WebhookPollInterval)},
+		{key: "webhooks.max_attempts", env: "WEBHOOK_MAX_ATTEMPTS", value: (*intValue)(&c.WebhookMaxAttempts)},
+		{key: "webhooks.disable_after", env: "WEBHOOK_DISABLE_AFTER", value: (*intValue)(&c.WebhookDisableAfter)},
+
+		{key: "notifications.smtp.host", env: "SMTP_HOST", value: (*stringValue)(&c.SMTPHost)},
+		{key: "notifications.smtp.port", env: "SMTP_PORT", value: (*intValue)(&c.SMTPPort)},
+		{key: "notifications.smtp.username", env: "SMTP_USERNAME", value: (*stringValue)(&c.SMTPUsername)},
+		{key: "notifications.smtp.password", env: "SMTP_PASSWORD", secret: true, value: (*stringValue)(&c.SMTPPassword)},
+		{key: "notifications.smtp.from", env: "SMTP_FROM", value: (*stringValue)(&c.SMTPFrom)},
+		{key: "notifications.sms.url", env: "SMS_PROVIDER_URL", value: (*stringValue)(&c.SMSProviderURL)},
+		{key: "notifications.sms.api_key", env: "SMS_API_KEY", secret: true, value: (*stringValue)(&c.SMSAPIKey)},
+		{key: "notifications.sms.from", env: "SMS_FROM", value: (*stringValue)(&c.SMSFrom)},
+
+		{key: "services.payment.url", env: "PAYMENT_SERVICE_URL", value: (*stringValue)(&c.PaymentServiceURL)},
+		{key: "services.payment.api_key", env: "PAYMENT_API_KEY", secret: true, value: (*stringValue)(&c.PaymentAPIKey)},
+		{key: "services.order.url", env: "ORDER_SERVICE_URL", value: (*stringValue)(&c.OrderServiceURL)},
+		{key: "services.product.url", env: "PRODUCT_SERVICE_URL", value: (*stringValue)(&c.ProductServiceURL)},
+		{key: "services.product.api_key", env: "PRODUCT_API_KEY", secret: true, value: (*stringValue)(&c.ProductAPIKey)},
+		{key: "services.user.url", env: "USER_SERVICE_URL", value: (*stringValue)(&c.UserServiceURL)},
+		{key: "services.timeout", env: "SERVICE_TIMEOUT", value: (*durationValue)(&c.ServiceTimeout)},
+		{key: "services.max_retries", env: "SERVICE_MAX_RETRIES", value: (*intValue)(&c.ServiceMaxRetries)},
+
+		{key: "gateway.route_timeouts", env: "GATEWAY_ROUTE_TIMEOUTS", value: (*durationMapValue)(&c.GatewayRouteTimeouts)},
+		{key: "gateway.health_interval", env: "GATEWAY_HEALTH_INTERVAL", value: (*durationValue)(&c.GatewayHealthInterval)},
+
+		{key: "metrics.enabled", env: "ENABLE_METRICS", value: (*boolValue)(&c.EnableMetrics)},
+		{key: "metrics.port", env: "METRICS_PORT", value: (*intValue)(&c.MetricsPort)},
+
+		{key: "health.timeout", env: "HEALTH_TIMEOUT", value: (*durationValue)(&c.HealthTimeout)},
+		{key: "health.cache_ttl", env: "HEALTH_CACHE_TTL", value: (*durationValue)(&c.HealthCacheTTL)},
+
+		{key: "logging.level", env: "LOG_LEVEL", value: (*stringValue)(&c.LogLevel)},
+		{key: "logging.format", env: "LOG_FORMAT", value: (*stringValue)(&c.LogFormat)},
+
+		{key: "tracing.exporter", env: "TRACING_EXPORTER", value: (*stringValue)(&c.TracingExporter)},
+		{key: "tracing.file", env: "TRACING_FILE", value: (*stringValue)(&c.TracingFile)},
+		{key: "tracing.service_name", env: "TRACING_SERVICE_NAME", value: (*stringValue)(&c.TracingServiceName)},
+
+		{key: "cors.allowed_origins", env: "CORS_ALLOWED_ORIGINS", value: (*stringsValue)(&c.CORSAllowedOrigins)},
+		{key: "cors.allowed_methods", env: "CORS_ALLOWED_METHODS", value: (*stringsValue)(&c.CORSAllowedMethods)},
+		{key: "cors.allowed_headers", env: "CORS_ALLOWED_HEADERS", value: (*stringsValue)(&c.CORSAllowedHeaders)},
+		{key: "cors.exposed_headers", env: "CORS_EXPOSED_HEADERS", value: (*stringsValue)(&c.CORSExposedHeaders)},
+		{key: "cors.allow_credentials", env: "CORS_ALLOW_CREDENTIALS", value: (*boolValue)(&c.CORSAllowCredentials)},
+		{key: "cors.max_age", env: "CORS_MAX_AGE", value: (*intValue)(&c.CORSMaxAge)},
+
+		{key: "cache.store", env: "CACHE_STORE", value: (*stringValue)(&c.CacheStore)},
+		{key: "cache.ttl", env: "CACHE_TTL", value: (*durationValue)(&c.CacheTTL)},
+		{key: "cache.jitter", env: "CACHE_JITTER", value: (*durationValue)(&c.CacheJitter)},
+		{key: "cache.lru_size", env: "CACHE_LRU_SIZE", value: (*intValue)(&c.CacheLRUSize)},
+		{key: "cache.namespace", env: "CACHE_NAMESPACE", value: (*stringValue)(&c.CacheNamespace)},
+
+		{key: "ratelimit.store", env: "RATE_LIMIT_STORE", value: (*stringValue)(&c.RateLimitStore)},
+		{key: "ratelimit.default", env: "RATE_LIMIT_DEFAULT", value: (*stringValue)(&c.RateLimitDefault)},
+		{key: "ratelimit.routes", env: "RATE_LIMIT_ROUTES", value: (*stringMapValue)(&c.RateLimitRoutes)},
+		{key: "ratelimit.allow_list", env: "RATE_LIMIT_ALLOW_LIST", value: (*stringsValue)(&c.RateLimitAllowList)},
+
+		{key: "security.hsts_max_age", env: "SECURITY_HSTS_MAX_AGE", value: (*durationValue)(&c.SecurityHSTSMaxAge)},
+		{key: "security.hsts_include_subdomains", env: "SECURITY_HSTS_INCLUDE_SUBDOMAINS", value: (*boolValue)(&c.SecurityHSTSIncludeSubdomains)},
+		{key: "security.content_security_policy", env: "SECURITY_CONTENT_SECURITY_POLICY", value: (*stringValue)(&c.SecurityContentSecurityPolicy)},
+		{key: "security.frame_options", env: "SECURITY_FRAME_OPTIONS", value: (*stringValue)(&c.SecurityFrameOptions)},
+		{key: "security.referrer_policy", env: "SECURITY_REFERRER_POLICY", value: (*stringValue)(&c.SecurityReferrerPolicy)},
+	}
+}
+
+// applyYAML sets every key present in the file. Unknown keys are rejected
+// so a typo does not silently leave the default in place. A secret may be
+// given as "<key>_file" to read it from a file instead.
+func applyYAML(settings []setting, data []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	var problems []string
+	var walk func(prefix string, node *yaml.Node)
+	walk = func(prefix string, node *yaml.Node) {
+		if node.Tag == "!!null" {
+			return
+		}
+		if s, ok := byKey[prefix]; ok {
+			text, err := nodeText(node)
+			if err == nil {
+				err = s.value.Set(text)
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", prefix, err))
+			}
+			return
+		}
+		if s, ok := byKey[strings.TrimSuffix(prefix, "_file")]; ok && s.secret && strings.HasSuffix(prefix, "_file") {
+			if err := setFromFile(s, node.Value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", prefix, err))
+			}
+			return
+		}
+		if node.Kind != yaml.MappingNode {
+			problems = append(problems, fmt.Sprintf("%s: unknown key", prefix))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			walk(key, node.Content[i+1])
+		}
+	}
+	walk("", root.Content[0])
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// nodeText flattens a YAML value into the text form accepted by Set: lists
+// are joined with commas and maps become name=value pairs.
+func nodeText(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, nil
+	case yaml.SequenceNode:
+		parts := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("expected a list of values")
+			}
+			parts = append(parts, item.Value)
+		}
+		return strings.Join(parts, ","), nil
+	case yaml.MappingNode:
+		parts := make([]string, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i+1].Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("expected a map of values")
+			}
+			parts = append(parts, node.Content[i].Value+"="+node.Content[i+1].Value)
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value")
+}
+
+func setFromFile(s setting, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return s.value.Set(strings.TrimSpace(string(data)))
+}
+
+// applyEnv sets every variable that is present. For secrets, NAME_FILE
+// points at a file holding the value, as used with Docker and Kubernetes
+// secrets; NAME itself wins when both are set.
+func applyEnv(settings []setting, lookupEnv func(string) (string, bool)) error {
+	var problems []string
+	for _, s := range settings {
+		if s.secret {
+			if path, ok := lookupEnv(s.env + "_FILE"); ok {
+				if err := setFromFile(s, path); err != nil {
+					problems = append(problems, fmt.Sprintf("%s_FILE: %v", s.env, err))
+				}
+			}
+		}
+		if text, ok := lookupEnv(s.env); ok {
+			if err := s.value.Set(text); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", s.env, err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func applyFlags(settings []setting, fs *flag.FlagSet) error {
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	var problems []string
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byKey[f.Name]
+		if !ok {
+			return
+		}
+		if err := s.value.Set(f.Value.String()); err != nil {
+			problems = append(problems, fmt.Sprintf("--%s: %v", f.Name, err))
+		}
+	})
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Print writes the configuration as YAML in the same layout as
+// config.example.yaml, with secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		parent := root
+		parts := strings.Split(s.key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			parent = childMapping(parent, part)
+		}
+
+		var node *yaml.Node
+		switch v := s.value.(type) {
+		case *stringsValue:
+			node = sequenceNode(*v)
+		case *durationsValue:
+			items := make([]string, len(*v))
+			for i, d := range *v {
+				items[i] = d.String()
+			}
+			node = sequenceNode(items)
+		case *durationMapValue:
+			node = &yaml.Node{Kind: yaml.MappingNode}
+			for _, name := range sortedKeys(*v) {
+				node.Content = append(node.Content, scalarNode(name), scalarNode((*v)[name].String()))
+			}
+		case *stringMapValue:
+			node = &yaml.Node{Kind: yaml.MappingNode}
+			for _, name := range sortedKeys(*v) {
+				node.Content = append(node.Content, scalarNode(name), scalarNode((*v)[name]))
+			}
+		default:
+			text := s.value.String()
+			if s.secret && text != "" {
+				text = redacted
+			}
+			node = scalarNode(text)
+		}
+		parent.Content = append(parent.Content, scalarNode(parts[len(parts)-1]), node)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func childMapping(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, scalarNode(key), child)
+	return child
+}
+
+func scalarNode(text string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: text}
+}
+
+func sequenceNode(items []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, item := range items {
+		node.Content = append(node.Content, scalarNode(item))
+	}
+	return node
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+type durationsValue []time.Duration
+
+func (v *durationsValue) Set(s string) error {
+	var durations []time.Duration
+	for _, part := range splitList(s) {
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", part)
+		}
+		durations = append(durations, d)
+	}
+	*v = durations
+	return nil
+}
+
+func (v *durationsValue) String() string {
+	parts := make([]string, len(*v))
+	for i, d := range *v {
+		parts[i] = d.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// durationMapValue parses a list of name=duration pairs, such as
+// "orders=30s,products=10s".
+type durationMapValue map[string]time.Duration
+
+func (v *durationMapValue) Set(s string) error {
+	durations := make(map[string]time.Duration)
+	for _, part := range splitList(s) {
+		name, text, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("expected name=duration, got %q", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", text)
+		}
+		durations[strings.TrimSpace(name)] = d
+	}
+	*v = durations
+	return nil
+}
+
+func (v *durationMapValue) String() string {
+	parts := make([]string, 0, len(*v))
+	for name, d := range *v {
+		parts = append(parts, name+"="+d.String())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// stringMapValue parses a list of name=value pairs.
+type stringMapValue map[string]string
+
+func (v *stringMapValue) Set(s string) error {
+	values := make(map[string]string)
+	for _, part := range splitList(s) {
+		name, text, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("expected name=value, got %q", part)
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(text)
+	}
+	*v = values
+	return nil
+}
+
+func (v *stringMapValue) String() string {
+	parts := make([]string, 0, len(*v))
+	for _, name := range sortedKeys(*v) {
+		parts = append(parts, name+"="+(*v)[name])
+	}
+	return strings.Join(parts, ",")
+}
+
+type stringsValue []string
+
+func (v *stringsValue) Set(s string) error { *v = splitList(s); return nil }
+func (v *stringsValue) String() string     { return strings.Join(*v, ",") }
+
+func splitList(s string) []string {
+	var items []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/ratelimit"
+)
+
+// Validate reports every invalid setting at once, so a bad deployment fails
+// at startup with the full list instead of one problem per restart.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.ServerPort)
+	check(err == nil && validPort(port), "server.port: %q is not a valid port", c.ServerPort)
+	check(validPort(c.DBPort), "database.port: %d is not a valid port", c.DBPort)
+	check(validPort(c.RedisPort), "redis.port: %d is not a valid port", c.RedisPort)
+	check(validPort(c.SMTPPort), "notifications.smtp.port: %d is not a valid port", c.SMTPPort)
+	check(c.MetricsPort == 0 || validPort(c.MetricsPort), "metrics.port: %d is not a valid port", c.MetricsPort)
+	check(c.RedisDB >= 0, "redis.db: must not be negative")
+
+	check(oneOf(c.APIMode, "monolith", "gateway"), "server.mode: must be monolith or gateway, got %q", c.APIMode)
+	check(oneOf(c.CartMergePolicy, "sum", "newest"), "cart.merge_policy: must be sum or newest, got %q", c.CartMergePolicy)
+	check(oneOf(c.LogLevel, "debug", "info", "warn", "error"), "logging.level: must be debug, info, warn or error, got %q", c.LogLevel)
+	check(oneOf(c.LogFormat, "json", "console"), "logging.format: must be json or console, got %q", c.LogFormat)
+	check(oneOf(c.TracingExporter, "none", "stdout", "file"), "tracing.exporter: must be none, stdout or file, got %q", c.TracingExporter)
+	check(c.TracingExporter != "file" || c.TracingFile != "", "tracing.file: is required when tracing.exporter is file")
+
+	check(oneOf(c.DBDriver, "postgres", "sqlite"), "database.driver: must be postgres or sqlite, got %q", c.DBDriver)
+	if c.DBDriver == "sqlite" {
+		check(c.DBPath != "", "database.path: must be set for sqlite")
+	} else {
+		check(c.DBHost != "", "database.host: must be set")
+		check(c.DBName != "", "database.name: must be set")
+	}
+	check(c.DBSlowQueryThreshold >= 0, "database.slow_query_threshold: must not be negative")
+	check(c.DBMaxOpenConns >= 0, "database.max_open_conns: must not be negative")
+	check(c.DBMaxIdleConns >= 0, "database.max_idle_conns: must not be negative")
+	check(c.DBConnMaxLifetime >= 0, "database.conn_max_lifetime: must not be negative")
+	for _, replica := range c.DBReplicas {
+		if _, port, err := net.SplitHostPort(replica); err == nil && c.DBDriver != "sqlite" {
+			n, err := strconv.Atoi(port)
+			check(err == nil && validPort(n), "database.replicas: %q has an invalid port", replica)
+		}
+	}
+	check(c.JWTSecret != "", "jwt.secret: must be set")
+	check(c.Env == "development" || c.JWTSecret != Default().JWTSecret, "jwt.secret: the default secret may only be used in development")
+	check(c.Env == "development" || c.CartTokenSecret != Default().JWTSecret, "cart.token_secret: the default secret may only be used in development")
+
+	durations := map[string]time.Duration{
+		"jwt.expiration":                  c.JWTExpiration,
+		"cart.guest_ttl":                  c.CartGuestTTL,
+		"cart.reminders.interval":         c.CartReminderInterval,
+		"events.poll_interval":            c.EventsPollInterval,
+		"webhooks.timeout":                c.WebhookTimeout,
+		"webhooks.poll_interval":          c.WebhookPollInterval,
+		"services.timeout":                c.ServiceTimeout,
+		"gateway.health_interval":         c.GatewayHealthInterval,
+		"database.replica_check_interval": c.DBReplicaCheckInterval,
+		"health.timeout":                  c.HealthTimeout,
+		"cache.ttl":                       c.CacheTTL,
+	}
+	for _, key := range sortedKeys(durations) {
+		check(durations[key] > 0, "%s: must be positive", key)
+	}
+	for _, d := range c.CartReminderStages {
+		check(d > 0, "cart.reminders.stages: %s must be positive", d)
+	}
+	for _, name := range sortedKeys(c.GatewayRouteTimeouts) {
+		check(c.GatewayRouteTimeouts[name] > 0, "gateway.route_timeouts: %s must be positive", name)
+		check(c.ServerWriteTimeout == 0 || c.ServerWriteTimeout > c.GatewayRouteTimeouts[name],
+			"server.write_timeout: must be longer than gateway.route_timeouts.%s", name)
+	}
+	serverTimeouts := map[string]time.Duration{
+		"server.read_timeout":        c.ServerReadTimeout,
+		"server.read_header_timeout": c.ServerReadHeaderTimeout,
+		"server.write_timeout":       c.ServerWriteTimeout,
+		"server.idle_timeout":        c.ServerIdleTimeout,
+		"server.drain_period":        c.ServerDrainPeriod,
+		"server.shutdown_timeout":    c.ServerShutdownTimeout,
+	}
+	for _, key := range sortedKeys(serverTimeouts) {
+		check(serverTimeouts[key] >= 0, "%s: must not be negative", key)
+	}
+
+	check(c.EventsMaxAttempts > 0, "events.max_attempts: must be positive")
+	check(c.WebhookMaxAttempts > 0, "webhooks.max_attempts: must be positive")
+	check(c.WebhookDisableAfter > 0, "webhooks.disable_after: must be positive")
+	check(c.ServiceMaxRetries >= 0, "services.max_retries: must not be negative")
+	check(c.CORSMaxAge >= 0, "cors.max_age: must not be negative")
+	check(oneOf(c.CacheStore, "memory", "redis"), "cache.store: must be memory or redis, got %q", c.CacheStore)
+	check(c.HealthCacheTTL >= 0, "health.cache_ttl: must not be negative")
+	check(c.CacheJitter >= 0, "cache.jitter: must not be negative")
+	check(c.CacheLRUSize > 0, "cache.lru_size: must be positive")
+	check(c.CacheStore != "redis" || c.CacheNamespace != "", "cache.namespace: must be set for the redis store")
+	check(oneOf(c.RateLimitStore, "memory", "redis"), "ratelimit.store: must be memory or redis, got %q", c.RateLimitStore)
+	if _, err := ratelimit.ParsePolicy("default", c.RateLimitDefault); err != nil {
+		errs = append(errs, fmt.Errorf("ratelimit.default: %w", err))
+	}
+	for _, route := range sortedKeys(c.RateLimitRoutes) {
+		if _, err := ratelimit.ParsePolicy(route, c.RateLimitRoutes[route]); err != nil {
+			errs = append(errs, fmt.Errorf("ratelimit.routes: %s: %w", route, err))
+		}
+		method, path, ok := strings.Cut(route, " ")
+		if !ok {
+			path = method
+		}
+		check(strings.HasPrefix(path, "/"), "ratelimit.routes: %q must be \"METHOD /path\" or \"/path\"", route)
+	}
+	for _, entry := range c.RateLimitAllowList {
+		check(validIPOrCIDR(entry), "ratelimit.allow_list: %q is not an IP or CIDR", entry)
+	}
+	for _, entry := range c.TrustedProxies {
+		check(validIPOrCIDR(entry), "server.trusted_proxies: %q is not an IP or CIDR", entry)
+	}
+	check(c.SecurityHSTSMaxAge >= 0, "security.hsts_max_age: must not be negative")
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			check(!c.CORSAllowCredentials, "cors.allowed_origins: \"*\" cannot be combined with allow_credentials; list the origins instead")
+			continue
+		}
+		check(validURL(strings.Replace(origin, "*.", "", 1)), "cors.allowed_origins: %q is not an origin", origin)
+	}
+
+	urls := map[string]string{
+		"services.payment.url": c.PaymentServiceURL,
+		"services.order.url":   c.OrderServiceURL,
+		"services.product.url": c.ProductServiceURL,
+		"services.user.url":    c.UserServiceURL,
+	}
+	for _, key := range sortedKeys(urls) {
+		// Service URLs may list several instances for the gateway.
+		for _, raw := range strings.Split(urls[key], ",") {
+			check(validURL(strings.TrimSpace(raw)), "%s: %q is not an http(s) URL", key, raw)
+		}
+	}
+	check(c.SMSProviderURL == "" || validURL(c.SMSProviderURL), "notifications.sms.url: %q is not an http(s) URL", c.SMSProviderURL)
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func validIPOrCIDR(entry string) bool {
+	if _, _, err := net.ParseCIDR(entry); err == nil {
+		return true
+	}
+	return net.ParseIP(entry) != nil
+}
+
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,29 +1,151 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"strconv"
+	"time"
 
 	"github.com/oguzhan/e-commerce/pkg/config"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+// InitDB connects to the primary database and, if any are configured, to
+// its read replicas, which it registers as the Replicas plugin.
 func InitDB(cfg *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBName,
-		cfg.DBSSLMode,
-	)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	dialector, err := open(cfg, "")
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
+	if err := configurePool(cfg, db); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.DBReplicas) > 0 {
+		replicas, err := openReplicas(cfg)
+		if err == nil {
+			err = db.Use(replicas)
+		}
+		if err != nil {
+			Close(db)
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		replicas.CheckHealth(ctx)
+		cancel()
+		log.Printf("Using %d of %d database replicas", replicas.Healthy(), len(cfg.DBReplicas))
+	}
 
 	log.Println("Successfully connected to database")
 	return db, nil
 }
+
+// open returns the dialector for the primary, or for replica if it is set.
+// Replicas share the primary's credentials; with Postgres a replica is a
+// host with an optional port, with SQLite a file name.
+func open(cfg *config.Config, replica string) (gorm.Dialector, error) {
+	switch cfg.DBDriver {
+	case "", Postgres:
+		host, port := cfg.DBHost, cfg.DBPort
+		if replica != "" {
+			host = replica
+			if h, p, err := net.SplitHostPort(replica); err == nil {
+				host = h
+				if port, err = strconv.Atoi(p); err != nil {
+					return nil, fmt.Errorf("invalid port in database replica %q", replica)
+				}
+			}
+		}
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			host,
+			port,
+			cfg.DBUser,
+			cfg.DBPassword,
+			cfg.DBName,
+			cfg.DBSSLMode,
+		)
+		return postgres.Open(dsn), nil
+	case SQLite:
+		path := cfg.DBPath
+		if replica != "" {
+			path = replica
+		}
+		return sqlite.Open(path + "?_foreign_keys=1&_busy_timeout=5000"), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.DBDriver)
+	}
+}
+
+// openReplicas opens a pool per replica without connecting, so a replica
+// that is down at startup is only taken out of rotation.
+func openReplicas(cfg *config.Config) (*Replicas, error) {
+	replicas := NewReplicas()
+	for _, address := range cfg.DBReplicas {
+		sqlDB, err := openReplica(cfg, address)
+		if err != nil {
+			replicas.Close()
+			return nil, fmt.Errorf("failed to open database replica %s: %v", address, err)
+		}
+		replicas.Add(address, sqlDB)
+	}
+	return replicas, nil
+}
+
+func openReplica(cfg *config.Config, address string) (*sql.DB, error) {
+	dialector, err := open(cfg, address)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := configurePool(cfg, db); err != nil {
+		return nil, err
+	}
+	return db.DB()
+}
+
+func configurePool(cfg *config.Config, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if cfg.DBDriver == SQLite {
+		// SQLite allows one writer at a time, and every connection to
+		// :memory: would open a separate empty database.
+		sqlDB.SetMaxOpenConns(1)
+		return nil
+	}
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	return nil
+}
+
+// Close closes the connection pool behind db.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if replicas := ReplicasOf(db); replicas != nil {
+		return errors.Join(sqlDB.Close(), replicas.Close())
+	}
+	return sqlDB.Close()
+}
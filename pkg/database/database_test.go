@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oguzhan/e-commerce/pkg/config"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitDB_SQLite(t *testing.T) {
+	cfg := config.Default()
+	cfg.DBDriver = SQLite
+	cfg.DBPath = ":memory:"
+
+	db, err := InitDB(cfg)
+	assert.NoError(t, err)
+	defer Close(db)
+
+	checker, err := Migrate(context.Background(), cfg, db)
+	assert.NoError(t, err)
+	assert.NoError(t, checker.Check(context.Background()))
+
+	_, err = NewMigrator(db)
+	assert.ErrorIs(t, err, ErrNoSQLMigrations)
+
+	assert.NoError(t, db.Create(&models.Product{Name: "Claw Hammer", Price: 30, Stock: 1, SKU: "HAM-1"}).Error)
+	var products []models.Product
+	err = ForUpdate(db).Where(ILike(db, "name"), "%hammer%").Find(&products).Error
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+}
+
+func TestInitDB_UnknownDriver(t *testing.T) {
+	cfg := config.Default()
+	cfg.DBDriver = "mysql"
+	_, err := InitDB(cfg)
+	assert.EqualError(t, err, `unknown database driver "mysql"`)
+}
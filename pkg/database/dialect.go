@@ -0,0 +1,26 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ILike returns a condition matching column against a LIKE pattern
+// regardless of case. Postgres needs ILIKE; SQLite's LIKE already ignores
+// ASCII case and has no ILIKE.
+func ILike(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == Postgres {
+		return column + " ILIKE ?"
+	}
+	return column + " LIKE ?"
+}
+
+// ForUpdate locks the rows the query reads until the transaction ends.
+// SQLite has no row locks, and a transaction that writes already excludes
+// every other writer, so there it adds nothing.
+func ForUpdate(tx *gorm.DB) *gorm.DB {
+	if tx.Dialector.Name() == Postgres {
+		return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	return tx
+}
@@ -1,21 +1,90 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 
+	"github.com/oguzhan/e-commerce/migrations"
+	"github.com/oguzhan/e-commerce/pkg/config"
+	"github.com/oguzhan/e-commerce/pkg/health"
+	"github.com/oguzhan/e-commerce/pkg/migrate"
 	"github.com/oguzhan/e-commerce/pkg/models"
 	"gorm.io/gorm"
 )
 
-func AutoMigrate(db *gorm.DB) error {
-	models := []interface{}{
+// Models returns the models shared by every service.
+func Models() []interface{} {
+	return []interface{}{
 		&models.User{},
+		&models.Address{},
+		&models.Contact{},
 		&models.Product{},
 		&models.Order{},
 		&models.OrderItem{},
 		&models.Payment{},
 	}
+}
+
+// ErrNoSQLMigrations is returned for SQLite databases, whose schema always
+// comes from the models since the SQL migrations are written for Postgres.
+var ErrNoSQLMigrations = errors.New("SQL migrations are only run on postgres; sqlite databases are created from the models")
+
+// NewMigrator returns a migrator for the SQL migrations in migrations/.
+func NewMigrator(db *gorm.DB) (*migrate.Migrator, error) {
+	if db.Dialector.Name() != Postgres {
+		return nil, ErrNoSQLMigrations
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, db.Dialector.Name(), migrations.FS)
+}
+
+// Migrate brings the schema up to date at startup. It applies pending SQL
+// migrations unless database.migrate_on_start is off and, in the
+// development environment only, then auto-migrates the shared models plus
+// extra so model changes can be tried before their migration is written.
+// The returned checker fails readiness while migrations are pending.
+//
+// SQLite databases are always auto-migrated instead, and their checker
+// always passes.
+func Migrate(ctx context.Context, cfg *config.Config, db *gorm.DB, extra ...interface{}) (health.Checker, error) {
+	if db.Dialector.Name() == SQLite {
+		if err := AutoMigrate(db, extra...); err != nil {
+			return nil, err
+		}
+		return health.CheckerFunc(func(context.Context) error { return nil }), nil
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DBMigrateOnStart {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		for _, m := range applied {
+			log.Printf("Applied migration %03d_%s", m.Version, m.Name)
+		}
+	}
+	if cfg.Env == "development" {
+		if err := AutoMigrate(db, extra...); err != nil {
+			return nil, err
+		}
+	}
+	return migrator, nil
+}
+
+// AutoMigrate migrates the shared models plus any extra models owned by the
+// calling service. It never drops or renames anything, so outside
+// development the schema is owned by the SQL migrations.
+func AutoMigrate(db *gorm.DB, extra ...interface{}) error {
+	models := append(Models(), extra...)
 
 	for _, model := range models {
 		if err := db.AutoMigrate(model); err != nil {
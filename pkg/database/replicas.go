@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	replicasPlugin = "replicas"
+	readKey        = "replicas:read"
+)
+
+type primaryKey struct{}
+
+// Read marks the queries run through the returned db as safe to serve from
+// a read replica. Only use it where reading slightly stale data is fine.
+func Read(db *gorm.DB) *gorm.DB {
+	return db.Set(readKey, true)
+}
+
+// WithPrimary returns a context whose queries all go to the primary, even
+// those marked with Read. Requests that must see their own writes use it.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsesPrimary reports whether ctx was returned by WithPrimary.
+func UsesPrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// Replicas is a gorm plugin that sends queries marked with Read to the
+// read replicas in turn. Writes, transactions, locking reads, reads on a
+// WithPrimary context and reads while no replica is healthy go to the
+// primary.
+type Replicas struct {
+	primary  gorm.ConnPool
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// NewReplicas returns a plugin without replicas; add them before db.Use.
+func NewReplicas() *Replicas {
+	return &Replicas{}
+}
+
+// Add registers a replica connection pool. It is healthy until a check
+// says otherwise.
+func (r *Replicas) Add(name string, db *sql.DB) {
+	rep := &replica{name: name, db: db}
+	rep.healthy.Store(true)
+	r.replicas = append(r.replicas, rep)
+}
+
+// ReplicasOf returns the Replicas plugin used by db, or nil.
+func ReplicasOf(db *gorm.DB) *Replicas {
+	replicas, _ := db.Config.Plugins[replicasPlugin].(*Replicas)
+	return replicas
+}
+
+func (r *Replicas) Name() string {
+	return replicasPlugin
+}
+
+func (r *Replicas) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+	cb := db.Callback()
+	return errors.Join(
+		cb.Query().Before("gorm:query").Register("replicas:query", r.read),
+		cb.Row().Before("gorm:row").Register("replicas:row", r.read),
+		cb.Create().Before("*").Register("replicas:create", r.write),
+		cb.Update().Before("*").Register("replicas:update", r.write),
+		cb.Delete().Before("*").Register("replicas:delete", r.write),
+		cb.Raw().Before("*").Register("replicas:raw", r.write),
+	)
+}
+
+// read moves a marked query to a replica. A statement keeps its replica, so
+// a count and the page it belongs to see the same data.
+func (r *Replicas) read(db *gorm.DB) {
+	if _, ok := db.Get(readKey); !ok || r.isReplica(db.Statement.ConnPool) {
+		return
+	}
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		return
+	}
+	if _, locking := db.Statement.Clauses["FOR"]; locking || UsesPrimary(db.Statement.Context) {
+		return
+	}
+	if pool := r.pick(); pool != nil {
+		db.Statement.ConnPool = pool
+	}
+}
+
+// write moves a statement that already read from a replica back to the
+// primary.
+func (r *Replicas) write(db *gorm.DB) {
+	if r.isReplica(db.Statement.ConnPool) {
+		db.Statement.ConnPool = r.primary
+	}
+}
+
+func (r *Replicas) isReplica(pool gorm.ConnPool) bool {
+	for _, rep := range r.replicas {
+		if pool == gorm.ConnPool(rep.db) {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns the next healthy replica, or nil if there is none.
+func (r *Replicas) pick() gorm.ConnPool {
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep.db
+		}
+	}
+	return nil
+}
+
+// Healthy returns the number of replicas that passed their last check.
+func (r *Replicas) Healthy() int {
+	count := 0
+	for _, rep := range r.replicas {
+		if rep.healthy.Load() {
+			count++
+		}
+	}
+	return count
+}
+
+// CheckHealth pings every replica once, taking failing ones out of
+// rotation and putting recovered ones back.
+func (r *Replicas) CheckHealth(ctx context.Context) {
+	for _, rep := range r.replicas {
+		err := rep.db.PingContext(ctx)
+		if rep.healthy.Swap(err == nil) == (err == nil) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Database replica %s is unhealthy, reading from the others: %v", rep.name, err)
+		} else {
+			log.Printf("Database replica %s is healthy again", rep.name)
+		}
+	}
+}
+
+// RunHealthChecks checks the replicas each interval until ctx is cancelled.
+func (r *Replicas) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.CheckHealth(ctx)
+		}
+	}
+}
+
+// Close closes the replica connection pools.
+func (r *Replicas) Close() error {
+	var errs []error
+	for _, rep := range r.replicas {
+		errs = append(errs, rep.db.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/oguzhan/e-commerce/pkg/config"
+	"github.com/oguzhan/e-commerce/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupReplicated returns a database with one replica, each a SQLite file
+// holding a product named after it, so a query shows where it ran.
+func setupReplicated(t *testing.T) *gorm.DB {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DBDriver = SQLite
+	cfg.DBPath = filepath.Join(dir, "primary.db")
+	cfg.DBReplicas = []string{filepath.Join(dir, "replica.db")}
+
+	replica, err := gorm.Open(sqlite.Open(cfg.DBReplicas[0]), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, replica.AutoMigrate(&models.Product{}))
+	assert.NoError(t, replica.Create(&models.Product{Name: "replica", SKU: "SKU-1", Price: 1}).Error)
+	assert.NoError(t, Close(replica))
+
+	db, err := InitDB(cfg)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { Close(db) })
+	assert.NoError(t, db.AutoMigrate(&models.Product{}))
+	assert.NoError(t, db.Create(&models.Product{Name: "primary", SKU: "SKU-1", Price: 1}).Error)
+	return db
+}
+
+func source(t *testing.T, db *gorm.DB) string {
+	var product models.Product
+	assert.NoError(t, db.First(&product).Error)
+	return product.Name
+}
+
+func TestReplicas_RoutesMarkedReads(t *testing.T) {
+	db := setupReplicated(t)
+	ctx := context.Background()
+
+	assert.Equal(t, "replica", source(t, Read(db)))
+	assert.Equal(t, "replica", source(t, Read(db.WithContext(ctx))))
+	assert.Equal(t, "primary", source(t, db))
+	assert.Equal(t, "primary", source(t, Read(db.WithContext(WithPrimary(ctx)))))
+
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		assert.Equal(t, "primary", source(t, Read(tx)))
+		return nil
+	}))
+}
+
+func TestReplicas_WritesGoToThePrimary(t *testing.T) {
+	db := setupReplicated(t)
+
+	var product models.Product
+	query := Read(db)
+	assert.NoError(t, query.First(&product).Error)
+	assert.Equal(t, "replica", product.Name)
+	assert.NoError(t, query.Exec("UPDATE products SET stock = ?", 7).Error)
+
+	assert.NoError(t, db.First(&product).Error)
+	assert.Equal(t, 7, product.Stock)
+	assert.NoError(t, Read(db).First(&product).Error)
+	assert.Equal(t, 0, product.Stock)
+}
+
+func TestReplicas_EjectsUnhealthyReplicas(t *testing.T) {
+	db := setupReplicated(t)
+	replicas := ReplicasOf(db)
+	if !assert.NotNil(t, replicas) {
+		return
+	}
+	assert.Equal(t, 1, replicas.Healthy())
+
+	assert.NoError(t, replicas.replicas[0].db.Close())
+	replicas.CheckHealth(context.Background())
+	assert.Equal(t, 0, replicas.Healthy())
+	assert.Equal(t, "primary", source(t, Read(db)))
+}
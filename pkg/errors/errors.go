@@ -1,6 +1,19 @@
 package errors
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/oguzhan/e-commerce/pkg/resilience"
+	"gorm.io/gorm"
+)
 
 // Common error types
 var (
@@ -8,6 +21,7 @@ var (
 	ErrNotFound          = NewError("not_found", "The requested resource was not found")
 	ErrUnauthorized      = NewError("unauthorized", "You are not authorized to perform this action")
 	ErrForbidden         = NewError("forbidden", "You don't have permission to access this resource")
+	ErrConflict          = NewError("conflict", "The request conflicts with the current state of the resource")
 	ErrInternal          = NewError("internal_error", "An internal error occurred")
 	ErrDatabase          = NewError("database_error", "A database error occurred")
 	ErrValidation        = NewError("validation_error", "The provided data is invalid")
@@ -17,11 +31,41 @@ var (
 	ErrRateLimit         = NewError("rate_limit", "Too many requests, please try again later")
 	ErrPaymentFailed     = NewError("payment_failed", "The payment process failed")
 	ErrInsufficientFunds = NewError("insufficient_funds", "Insufficient funds for this operation")
+	ErrUnavailable       = NewError("service_unavailable", "A dependency is unavailable, please try again later")
+	ErrTimeout           = NewError("timeout", "A dependency did not answer in time")
 )
 
-// AppError represents an application error
+// statuses maps the common codes to HTTP status codes.
+var statuses = map[string]int{
+	"invalid_input":       http.StatusBadRequest,
+	"validation_error":    http.StatusBadRequest,
+	"not_found":           http.StatusNotFound,
+	"unauthorized":        http.StatusUnauthorized,
+	"invalid_token":       http.StatusUnauthorized,
+	"expired_token":       http.StatusUnauthorized,
+	"forbidden":           http.StatusForbidden,
+	"conflict":            http.StatusConflict,
+	"duplicate_error":     http.StatusConflict,
+	"rate_limit":          http.StatusTooManyRequests,
+	"payment_failed":      http.StatusPaymentRequired,
+	"insufficient_funds":  http.StatusPaymentRequired,
+	"service_unavailable": http.StatusServiceUnavailable,
+	"timeout":             http.StatusGatewayTimeout,
+}
+
+// AppError represents an application error. Code is stable and meant for
+// clients to branch on; Message is for people and may change.
 type AppError struct {
-	Code    string `json:"code"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Status  int          `json:"-"`
+	Fields  []FieldError `json:"fields,omitempty"`
+	cause   error
+}
+
+// FieldError describes why one request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
@@ -29,24 +73,201 @@ func (e *AppError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
-// NewError creates a new AppError
+// Unwrap returns the error wrapped with Wrap, if any.
+func (e *AppError) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors with the same code, so errors derived with WithMessage
+// or Wrap still match the variable they came from.
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
+// NewError creates a new AppError with the status of a common code, or 500
+// for other codes.
 func NewError(code, message string) *AppError {
-	return &AppError{
-		Code:    code,
-		Message: message,
+	status, ok := statuses[code]
+	if !ok {
+		status = http.StatusInternalServerError
 	}
+	return New(status, code, message)
+}
+
+// New creates an AppError with an explicit status, for domain errors such
+// as New(http.StatusNotFound, "order_not_found", "order not found").
+func New(status int, code, message string) *AppError {
+	return &AppError{Code: code, Message: message, Status: status}
+}
+
+// WithMessage returns a copy of e with a different message.
+func (e *AppError) WithMessage(message string) *AppError {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithFields returns a copy of e carrying per-field errors.
+func (e *AppError) WithFields(fields ...FieldError) *AppError {
+	c := *e
+	c.Fields = fields
+	return &c
+}
+
+// Wrap returns a copy of e that records err as its cause. The cause is
+// logged but never sent to clients.
+func (e *AppError) Wrap(err error) *AppError {
+	c := *e
+	c.cause = err
+	return &c
 }
 
 // IsAppError checks if an error is an AppError
 func IsAppError(err error) bool {
-	_, ok := err.(*AppError)
-	return ok
+	var appErr *AppError
+	return stderrors.As(err, &appErr)
 }
 
 // GetAppError returns the AppError if the error is an AppError
 func GetAppError(err error) *AppError {
-	if appErr, ok := err.(*AppError); ok {
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
 		return appErr
 	}
 	return ErrInternal
 }
+
+// From converts any error into an AppError. Binding, validation, "record
+// not found", timeout and circuit breaker errors get their own codes;
+// anything else becomes ErrInternal, so driver and library messages never
+// reach clients.
+func From(err error) *AppError {
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
+		return appErr
+	}
+
+	var validationErrs validator.ValidationErrors
+	if stderrors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{Field: fieldName(fe), Message: fieldMessage(fe)})
+		}
+		return ErrValidation.WithFields(fields...).Wrap(err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if stderrors.As(err, &typeErr) {
+		return ErrInvalidInput.WithMessage("The request body has a value of the wrong type").
+			WithFields(FieldError{Field: typeErr.Field, Message: "must be a " + typeName(typeErr.Type)}).Wrap(err)
+	}
+	var syntaxErr *json.SyntaxError
+	if stderrors.As(err, &syntaxErr) || stderrors.Is(err, io.EOF) || stderrors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrInvalidInput.WithMessage("The request body is not valid JSON").Wrap(err)
+	}
+
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound.Wrap(err)
+	}
+	if stderrors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate.Wrap(err)
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout.Wrap(err)
+	}
+	if stderrors.Is(err, resilience.ErrCircuitOpen) || stderrors.Is(err, resilience.ErrBulkheadFull) {
+		return ErrUnavailable.Wrap(err)
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// fieldName drops the top-level struct name from the field path, leaving
+// the JSON names when the validator reports them (see RegisterJSONNames).
+func fieldName(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		return rest
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required", "required_without", "required_with":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + param + " characters long"
+		}
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+			return "must contain at least " + param + " items"
+		}
+		return "must be at least " + param
+	case "max":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + param + " characters long"
+		}
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+			return "must contain at most " + param + " items"
+		}
+		return "must be at most " + param
+	case "len":
+		return "must have length " + param
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be greater than or equal to " + param
+	case "lt":
+		return "must be less than " + param
+	case "lte":
+		return "must be less than or equal to " + param
+	case "numeric", "number":
+		return "must be a number"
+	case "dive":
+		return "contains an invalid item"
+	}
+	return "is invalid"
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return "value of another type"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "whole number"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	}
+	return "object"
+}
+
+// RegisterJSONNames makes v report fields by their JSON names, so field
+// errors match the request body.
+func RegisterJSONNames(v *validator.Validate) {
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+}
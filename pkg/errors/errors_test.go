@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oguzhan/e-commerce/pkg/resilience"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestAppError_IsMatchesByCode(t *testing.T) {
+	errOrderNotFound := New(http.StatusNotFound, "order_not_found", "order not found")
+
+	derived := errOrderNotFound.WithMessage("order 7 not found").Wrap(gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, derived, errOrderNotFound)
+	assert.ErrorIs(t, fmt.Errorf("placing order: %w", derived), errOrderNotFound)
+	assert.ErrorIs(t, derived, gorm.ErrRecordNotFound)
+	assert.NotErrorIs(t, derived, ErrNotFound)
+	assert.Equal(t, "order not found", errOrderNotFound.Message)
+}
+
+func TestFrom_MapsKnownErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		code   string
+		status int
+	}{
+		{gorm.ErrRecordNotFound, "not_found", http.StatusNotFound},
+		{gorm.ErrDuplicatedKey, "duplicate_error", http.StatusConflict},
+		{fmt.Errorf("calling product service: %w", context.DeadlineExceeded), "timeout", http.StatusGatewayTimeout},
+		{resilience.ErrCircuitOpen, "service_unavailable", http.StatusServiceUnavailable},
+		{stderrors.New("dial tcp: connection refused"), "internal_error", http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		appErr := From(tc.err)
+		assert.Equal(t, tc.code, appErr.Code, tc.err.Error())
+		assert.Equal(t, tc.status, appErr.Status, tc.err.Error())
+		assert.ErrorIs(t, appErr, tc.err)
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/products/abc", nil)
+	req.Header.Set("X-Request-ID", "req-9")
+	w := httptest.NewRecorder()
+
+	WriteProblem(w, req, ErrValidation.WithFields(FieldError{Field: "name", Message: "is required"}))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "/problems/validation_error",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "The provided data is invalid",
+		"instance": "/products/abc",
+		"code": "validation_error",
+		"request_id": "req-9",
+		"errors": [{"field": "name", "message": "is required"}]
+	}`, w.Body.String())
+}
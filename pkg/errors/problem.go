@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 error bodies.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Code, RequestID and Errors
+// are extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Problem describes e for a response to the request at instance.
+func (e *AppError) Problem(instance, requestID string) Problem {
+	status := e.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	return Problem{
+		Type:      "/problems/" + e.Code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		RequestID: requestID,
+		Errors:    e.Fields,
+	}
+}
+
+// WriteProblem writes err as problem+json for handlers outside gin and
+// returns the AppError it was converted to.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) *AppError {
+	appErr := From(err)
+	requestID := w.Header().Get("X-Request-ID")
+	if requestID == "" {
+		requestID = r.Header.Get("X-Request-ID")
+	}
+	problem := appErr.Problem(r.URL.Path, requestID)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+	return appErr
+}
@@ -0,0 +1,192 @@
+// Package health serves the liveness and readiness endpoints. Liveness only
+// says the process is running; readiness runs a set of dependency checks
+// and fails while any of them fails, so load balancers stop sending traffic
+// to an instance that cannot serve it.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/resilience"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheTTL = 2 * time.Second
+)
+
+// Checker reports whether a dependency is usable. It should give up when
+// ctx is done.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report is the readiness answer.
+type Report struct {
+	Status    string            `json:"status"`
+	Timestamp time.Time         `json:"timestamp"`
+	Checks    map[string]Result `json:"checks"`
+	// CircuitBreakers reports the breaker state of each outbound
+	// dependency. An open breaker does not fail readiness, since this
+	// instance can still serve requests that do not need the dependency.
+	CircuitBreakers map[string]string `json:"circuit_breakers,omitempty"`
+}
+
+type check struct {
+	name    string
+	checker Checker
+}
+
+// Health runs the registered checks. Results are cached for CacheTTL so
+// frequent probes from several load balancers do not hammer dependencies.
+type Health struct {
+	// Timeout bounds each check.
+	Timeout  time.Duration
+	CacheTTL time.Duration
+
+	mu       sync.Mutex
+	checks   []check
+	cached   *Report
+	cachedAt time.Time
+	draining atomic.Bool
+}
+
+func New() *Health {
+	return &Health{Timeout: DefaultTimeout, CacheTTL: DefaultCacheTTL}
+}
+
+// Add registers a check under name.
+func (h *Health) Add(name string, checker Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name: name, checker: checker})
+	h.cached = nil
+}
+
+// Drain makes readiness fail from now on, bypassing the cache, so load
+// balancers stop sending requests before the server shuts down.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Check runs every check concurrently, or returns the cached report if it
+// is recent enough.
+func (h *Health) Check(ctx context.Context) Report {
+	if h.draining.Load() {
+		return Report{
+			Status:    StatusDown,
+			Timestamp: time.Now(),
+			Checks:    map[string]Result{"shutdown": {Status: StatusDown, Duration: "0s", Error: "shutting down"}},
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cached != nil && time.Since(h.cachedAt) < h.CacheTTL {
+		return *h.cached
+	}
+	// A prober that hangs up must not leave a failed report in the cache.
+	ctx = context.WithoutCancel(ctx)
+
+	report := Report{
+		Status:          StatusUp,
+		Timestamp:       time.Now(),
+		Checks:          make(map[string]Result, len(h.checks)),
+		CircuitBreakers: resilience.BreakerStates(),
+	}
+	results := make([]Result, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = h.run(ctx, c.checker)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range h.checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	h.cached = &report
+	h.cachedAt = time.Now()
+	return report
+}
+
+func (h *Health) run(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// The checker ignored its context; do not wait for it.
+		err = ctx.Err()
+	}
+
+	result := Result{Status: StatusUp, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler answers 200 as long as the process can serve HTTP. It
+// checks no dependencies, so a database outage does not get the process
+// restarted.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusUp})
+	})
+}
+
+// ReadinessHandler answers the report with 200 when every check passes and
+// 503 otherwise.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+		status := http.StatusOK
+		if report.Status != StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
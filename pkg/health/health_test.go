@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness_ReportsEveryCheck(t *testing.T) {
+	h := New()
+	h.Add("database", CheckerFunc(func(context.Context) error { return nil }))
+	h.Add("redis", CheckerFunc(func(context.Context) error { return errors.New("connection refused") }))
+
+	w := httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var report Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Checks["database"].Status)
+	assert.Equal(t, StatusDown, report.Checks["redis"].Status)
+	assert.Equal(t, "connection refused", report.Checks["redis"].Error)
+}
+
+func TestReadiness_TimesOutSlowChecks(t *testing.T) {
+	h := New()
+	h.Timeout = 20 * time.Millisecond
+	h.Add("stuck", CheckerFunc(func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	start := time.Now()
+	report := h.Check(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["stuck"].Error)
+}
+
+func TestReadiness_CachesResults(t *testing.T) {
+	var calls atomic.Int32
+	h := New()
+	h.CacheTTL = time.Hour
+	h.Add("database", CheckerFunc(func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+
+	h.Check(context.Background())
+	h.Check(context.Background())
+	assert.Equal(t, int32(1), calls.Load())
+
+	h.CacheTTL = 0
+	h.Check(context.Background())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestLiveness_IgnoresDependencies(t *testing.T) {
+	w := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"up"}`, w.Body.String())
+}
+
+func TestHTTP(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	assert.NoError(t, HTTP(nil, up.URL+"/healthz").Check(context.Background()))
+	assert.Error(t, HTTP(nil, down.URL+"/healthz").Check(context.Background()))
+}
+
+func TestReadiness_FailsWhileDraining(t *testing.T) {
+	h := New()
+	h.CacheTTL = time.Hour
+	h.Add("database", CheckerFunc(func(context.Context) error { return nil }))
+	assert.Equal(t, StatusUp, h.Check(context.Background()).Status)
+
+	h.Drain()
+	report := h.Check(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, "shutting down", report.Checks["shutdown"].Error)
+}
@@ -1,13 +1,33 @@
 package metrics
 
 import (
+	"errors"
+	"net/http"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// Registry holds every metric of the process. Metrics are registered here
+// instead of the global default registry so that each name is declared in
+// exactly one place.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
 var (
-	// HTTP Metrics
-	HttpRequestDuration = promauto.NewHistogramVec(
+	// HTTP Metrics, labelled with the route pattern rather than the raw path
+	HttpRequestDuration = factory.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "http_request_duration_seconds",
 			Help:    "Duration of HTTP requests in seconds",
@@ -16,7 +36,7 @@ var (
 		[]string{"method", "path", "status"},
 	)
 
-	HttpRequestsTotal = promauto.NewCounterVec(
+	HttpRequestsTotal = factory.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
 			Help: "Total number of HTTP requests",
@@ -24,22 +44,48 @@ var (
 		[]string{"method", "path", "status"},
 	)
 
+	HttpRequestsInProgress = factory.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_progress",
+			Help: "Number of HTTP requests in progress",
+		},
+		[]string{"method", "path"},
+	)
+
+	// Database Metrics
+	DBOperationsTotal = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_operations_total",
+			Help: "Total number of database operations by outcome",
+		},
+		[]string{"operation", "table", "status"},
+	)
+
+	DBOperationDuration = factory.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_operation_duration_seconds",
+			Help:    "Duration of database operations in seconds",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"operation", "table"},
+	)
+
 	// Business Metrics
-	OrdersCreated = promauto.NewCounter(
+	OrdersCreated = factory.NewCounter(
 		prometheus.CounterOpts{
 			Name: "orders_created_total",
 			Help: "Total number of orders created",
 		},
 	)
 
-	PaymentsProcessed = promauto.NewCounter(
+	PaymentsProcessed = factory.NewCounter(
 		prometheus.CounterOpts{
 			Name: "payments_processed_total",
 			Help: "Total number of payments processed",
 		},
 	)
 
-	PaymentAmount = promauto.NewHistogram(
+	PaymentAmount = factory.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "payment_amount",
 			Help:    "Distribution of payment amounts",
@@ -47,8 +93,106 @@ var (
 		},
 	)
 
+	Refunds = factory.NewCounter(
+		prometheus.CounterOpts{
+			Name: "refunds_total",
+			Help: "Total number of payments refunded",
+		},
+	)
+
+	RefundAmount = factory.NewCounter(
+		prometheus.CounterOpts{
+			Name: "refund_amount_total",
+			Help: "Total amount refunded",
+		},
+	)
+
+	CartAdds = factory.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cart_adds_total",
+			Help: "Total number of products added to carts",
+		},
+	)
+
+	// Checkout funnel: cart_add, order_placed, payment_created, payment_captured
+	CheckoutFunnel = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "checkout_funnel_total",
+			Help: "Total number of checkout steps reached by stage",
+		},
+		[]string{"stage"},
+	)
+
+	// Abandoned carts that received a reminder, by stage
+	CartsAbandoned = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cart_abandoned_total",
+			Help: "Total number of abandoned carts that were sent a reminder",
+		},
+		[]string{"stage"},
+	)
+
+	// Orders placed after a reminder, by the last stage sent before the order
+	CartRecoveredOrders = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cart_recovered_orders_total",
+			Help: "Total number of orders placed after an abandoned cart reminder",
+		},
+		[]string{"stage"},
+	)
+
+	// Resilience Metrics
+	CircuitBreakerState = factory.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state by dependency (0 closed, 1 half-open, 2 open)",
+		},
+		[]string{"dependency"},
+	)
+
+	CircuitBreakerTransitions = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "circuit_breaker_transitions_total",
+			Help: "Total number of circuit breaker state changes by dependency and new state",
+		},
+		[]string{"dependency", "state"},
+	)
+
+	BulkheadRejections = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bulkhead_rejections_total",
+			Help: "Total number of calls rejected because a dependency's bulkhead was full",
+		},
+		[]string{"dependency"},
+	)
+
+	OutboundRetries = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbound_retries_total",
+			Help: "Total number of retried outbound calls by dependency",
+		},
+		[]string{"dependency"},
+	)
+
+	// Cache Metrics
+	CacheHits = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Total number of cache hits by cache",
+		},
+		[]string{"cache"},
+	)
+
+	CacheMisses = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Total number of cache misses by cache",
+		},
+		[]string{"cache"},
+	)
+
 	// Error Metrics
-	ErrorsTotal = promauto.NewCounterVec(
+	ErrorsTotal = factory.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "errors_total",
 			Help: "Total number of errors by type",
@@ -57,20 +201,99 @@ var (
 	)
 )
 
+// Handler serves the metrics in Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// NewServeMux serves Handler at /metrics, for running metrics on their own
+// port away from the public API.
+func NewServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return mux
+}
+
+// Register adds a collector to Registry, replacing one already registered
+// with the same descriptors.
+func Register(collector prometheus.Collector) error {
+	err := Registry.Register(collector)
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		Registry.Unregister(registered.ExistingCollector)
+		err = Registry.Register(collector)
+	}
+	return err
+}
+
 func RecordHttpRequest(method, path, status string, duration float64) {
 	HttpRequestDuration.WithLabelValues(method, path, status).Observe(duration)
 	HttpRequestsTotal.WithLabelValues(method, path, status).Inc()
 }
 
+func RecordDBOperation(operation, table string, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	DBOperationsTotal.WithLabelValues(operation, table, status).Inc()
+	DBOperationDuration.WithLabelValues(operation, table).Observe(duration.Seconds())
+}
+
 func RecordOrderCreated() {
 	OrdersCreated.Inc()
+	CheckoutFunnel.WithLabelValues("order_placed").Inc()
+}
+
+func RecordPaymentCreated() {
+	CheckoutFunnel.WithLabelValues("payment_created").Inc()
 }
 
 func RecordPaymentProcessed(amount float64) {
 	PaymentsProcessed.Inc()
 	PaymentAmount.Observe(amount)
+	CheckoutFunnel.WithLabelValues("payment_captured").Inc()
+}
+
+func RecordRefund(amount float64) {
+	Refunds.Inc()
+	RefundAmount.Add(amount)
+}
+
+func RecordCartAdd() {
+	CartAdds.Inc()
+	CheckoutFunnel.WithLabelValues("cart_add").Inc()
+}
+
+func RecordCartAbandoned(stage string) {
+	CartsAbandoned.WithLabelValues(stage).Inc()
+}
+
+func RecordCartRecovered(stage string) {
+	CartRecoveredOrders.WithLabelValues(stage).Inc()
 }
 
 func RecordError(errorType, service string) {
 	ErrorsTotal.WithLabelValues(errorType, service).Inc()
 }
+
+func RecordCircuitBreakerState(dependency, state string, value int) {
+	CircuitBreakerState.WithLabelValues(dependency).Set(float64(value))
+	CircuitBreakerTransitions.WithLabelValues(dependency, state).Inc()
+}
+
+func RecordBulkheadRejection(dependency string) {
+	BulkheadRejections.WithLabelValues(dependency).Inc()
+}
+
+func RecordOutboundRetry(dependency string) {
+	OutboundRetries.WithLabelValues(dependency).Inc()
+}
+
+func RecordCacheHit(cache string) {
+	CacheHits.WithLabelValues(cache).Inc()
+}
+
+func RecordCacheMiss(cache string) {
+	CacheMisses.WithLabelValues(cache).Inc()
+}
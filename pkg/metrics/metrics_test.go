@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMetricsMiddleware_LabelsRoutePattern(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(HTTPMetricsMiddleware())
+	router.GET("/products/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	before := testutil.ToFloat64(HttpRequestsTotal.WithLabelValues("GET", "/products/:id", "200"))
+	unmatchedBefore := testutil.ToFloat64(HttpRequestsTotal.WithLabelValues("GET", "unmatched", "404"))
+	for _, path := range []string{"/products/1", "/products/2", "/nope"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, before+2, testutil.ToFloat64(HttpRequestsTotal.WithLabelValues("GET", "/products/:id", "200")))
+	assert.Equal(t, unmatchedBefore+1, testutil.ToFloat64(HttpRequestsTotal.WithLabelValues("GET", "unmatched", "404")))
+}
+
+func TestMiddleware_LabelsChiRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware(func(r *http.Request) string {
+		return chi.RouteContext(r.Context()).RoutePattern()
+	}))
+	r.Post("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	before := testutil.ToFloat64(HttpRequestsTotal.WithLabelValues("POST", "/users/{id}", "201"))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/7", nil))
+	assert.Equal(t, before+1, testutil.ToFloat64(HttpRequestsTotal.WithLabelValues("POST", "/users/{id}", "201")))
+}
+
+func TestHandler_ServesRegistry(t *testing.T) {
+	RecordRefund(12.5)
+	RecordCartAdd()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "refund_amount_total")
+	assert.Contains(t, w.Body.String(), `checkout_funnel_total{stage="cart_add"}`)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
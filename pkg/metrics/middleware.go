@@ -1,31 +1,66 @@
 package metrics
 
 import (
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// HTTPMetricsMiddleware records gin requests under their route pattern, so
+// "/products/123" and "/products/456" share the "/products/:id" series.
 func HTTPMetricsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
+		method := c.Request.Method
+		path := c.FullPath()
+		if path == "" {
+			path = unmatched
+		}
 
+		HttpRequestsInProgress.WithLabelValues(method, path).Inc()
 		c.Next()
+		HttpRequestsInProgress.WithLabelValues(method, path).Dec()
 
-		duration := time.Since(start).Seconds()
 		status := strconv.Itoa(c.Writer.Status())
+		RecordHttpRequest(method, path, status, time.Since(start).Seconds())
+	}
+}
+
+// Middleware records plain net/http requests, such as chi routes. route
+// returns the pattern that matched the request once the handler has run.
+func Middleware(route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
 
-		HttpRequestDuration.WithLabelValues(
-			c.Request.Method,
-			c.Request.URL.Path,
-			status,
-		).Observe(duration)
-
-		HttpRequestsTotal.WithLabelValues(
-			c.Request.Method,
-			c.Request.URL.Path,
-			status,
-		).Inc()
+			path := route(r)
+			if path == "" {
+				path = unmatched
+			}
+			status := strconv.Itoa(recorder.status)
+			RecordHttpRequest(r.Method, path, status, time.Since(start).Seconds())
+		})
 	}
 }
+
+// unmatched labels requests that matched no route, which would otherwise
+// add a series per probed URL.
+const unmatched = "unmatched"
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -2,11 +2,11 @@ package middleware
 
 import (
 	"errors"
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 )
 
 var secretKey = []byte("your-secret-key") // In production, use environment variable
@@ -15,7 +15,7 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+			WriteError(c, apperrors.ErrUnauthorized.WithMessage("authorization header is required"))
 			return
 		}
 
@@ -25,7 +25,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			WriteError(c, apperrors.ErrInvalidToken)
 			return
 		}
 
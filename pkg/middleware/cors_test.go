@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handlers...)
+	router.GET("/items", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return router
+}
+
+func serve(router *gin.Engine, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS_AllowListAndWildcardSubdomains(t *testing.T) {
+	router := newTestRouter(CORS(CORSConfig{
+		AllowedOrigins:   []string{"https://shop.example.com", "https://*.partner.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}))
+
+	w := serve(router, http.MethodGet, "/items", map[string]string{"Origin": "https://eu.partner.com"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://eu.partner.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+
+	for _, origin := range []string{"https://partner.com", "https://evil.com", "http://eu.partner.com", "https://a.partner.com.evil.com"} {
+		w = serve(router, http.MethodGet, "/items", map[string]string{"Origin": origin})
+		assert.Equal(t, http.StatusOK, w.Code, origin)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), origin)
+	}
+
+	w = serve(router, http.MethodGet, "/items", nil)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	router := newTestRouter(CORS(CORSConfig{
+		AllowedOrigins: []string{"https://shop.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         600,
+	}))
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		return serve(router, http.MethodOptions, "/items", map[string]string{
+			"Origin":                         origin,
+			"Access-Control-Request-Method":  method,
+			"Access-Control-Request-Headers": headers,
+		})
+	}
+
+	w := preflight("https://shop.example.com", "POST", "content-type, authorization")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://shop.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, w.Header().Values("Vary"), "Access-Control-Request-Method")
+
+	assert.Equal(t, http.StatusForbidden, preflight("https://evil.com", "POST", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("https://shop.example.com", "DELETE", "").Code)
+	assert.Equal(t, http.StatusForbidden, preflight("https://shop.example.com", "POST", "X-Custom").Code)
+
+	// "*" without credentials answers with a literal wildcard.
+	router = newTestRouter(CORSMiddleware())
+	w = serve(router, http.MethodGet, "/items", map[string]string{"Origin": "https://anywhere.test"})
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestSecurityHeaders_DefaultsAndRouteOverride(t *testing.T) {
+	router := newTestRouter(SecurityHeaders(DefaultSecurityHeaders()))
+	docs := DefaultSecurityHeaders()
+	docs.ContentSecurityPolicy = "default-src 'self'"
+	docs.FrameOptions = ""
+	router.GET("/docs", SecurityHeaders(docs), func(c *gin.Context) { c.String(http.StatusOK, "docs") })
+
+	w := serve(router, http.MethodGet, "/items", nil)
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+
+	w = serve(router, http.MethodGet, "/items", map[string]string{"X-Forwarded-Proto": "https"})
+	assert.Equal(t, "max-age=31536000; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+
+	w = serve(router, http.MethodGet, "/docs", nil)
+	assert.Equal(t, "default-src 'self'", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+}
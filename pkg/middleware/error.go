@@ -1,13 +1,26 @@
 package middleware
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
 	"github.com/oguzhan/e-commerce/pkg/logger"
+	"github.com/oguzhan/e-commerce/pkg/tracing"
 	"go.uber.org/zap"
 )
 
+func init() {
+	// Report binding errors by JSON field name rather than Go field name.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		apperrors.RegisterJSONNames(v)
+	}
+}
+
 // CustomError represents a structured error response
 type CustomError struct {
 	Code    int    `json:"code"`
@@ -19,32 +32,54 @@ func (e *CustomError) Error() string {
 	return e.Message
 }
 
-// ErrorHandler is a middleware for handling errors globally
+// WriteError answers with err as an RFC 7807 problem+json body and aborts
+// the request. Errors that are not AppErrors are converted with
+// apperrors.From, and the causes of server errors are logged rather than
+// sent to the client.
+func WriteError(c *gin.Context, err error) {
+	var appErr *apperrors.AppError
+	if customErr, ok := err.(*CustomError); ok {
+		code := strings.ToLower(strings.ReplaceAll(http.StatusText(customErr.Code), " ", "_"))
+		appErr = apperrors.New(customErr.Code, code, customErr.Message)
+	} else {
+		appErr = apperrors.From(err)
+	}
+	_ = c.Error(err)
+
+	requestID := c.Writer.Header().Get("X-Request-ID")
+	if requestID == "" {
+		requestID = c.GetHeader("X-Request-ID")
+	}
+	problem := appErr.Problem(c.Request.URL.Path, requestID)
+	if problem.Status >= http.StatusInternalServerError {
+		log := logger.Log
+		if log == nil {
+			log = zap.L()
+		}
+		fields := []zap.Field{
+			zap.String("path", c.Request.URL.Path),
+			zap.String("code", appErr.Code),
+			zap.Error(err),
+		}
+		if tracing.RequestID(c.Request.Context()) == "" {
+			fields = append(fields, zap.String("request_id", requestID))
+		}
+		log.Error("request failed", append(fields, tracing.Fields(c.Request.Context())...)...)
+	}
+
+	body, _ := json.Marshal(problem)
+	c.Abort()
+	c.Data(problem.Status, apperrors.ProblemContentType, body)
+}
+
+// ErrorHandler answers with the last error added with c.Error when the
+// handler did not write a response itself.
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
-		if len(c.Errors) > 0 {
-			err := c.Errors.Last()
-
-			// Log the error
-			logger.Log.Error("request error",
-				zap.String("path", c.Request.URL.Path),
-				zap.String("error", err.Error()),
-			)
-
-			// Check if it's a custom error
-			if customErr, ok := err.Err.(*CustomError); ok {
-				c.JSON(customErr.Code, gin.H{
-					"error": customErr.Message,
-				})
-				return
-			}
-
-			// Default error response
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "internal server error",
-			})
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			WriteError(c, c.Errors.Last().Err)
 		}
 	}
 }
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func serveProblem(t *testing.T, handler gin.HandlerFunc, body string) (*httptest.ResponseRecorder, apperrors.Problem) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/users", handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var problem apperrors.Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	return w, problem
+}
+
+func TestWriteError_ValidationFields(t *testing.T) {
+	bind := func(c *gin.Context) {
+		var req struct {
+			Email    string `json:"email" binding:"required,email"`
+			Password string `json:"password" binding:"required,min=8"`
+			Age      int    `json:"age"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			WriteError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+
+	w, problem := serveProblem(t, bind, `{"email": "nope", "password": "short"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, apperrors.ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "validation_error", problem.Code)
+	assert.Equal(t, "/problems/validation_error", problem.Type)
+	assert.Equal(t, "/users", problem.Instance)
+	assert.Equal(t, "req-1", problem.RequestID)
+	assert.Equal(t, []apperrors.FieldError{
+		{Field: "email", Message: "must be a valid email address"},
+		{Field: "password", Message: "must be at least 8 characters long"},
+	}, problem.Errors)
+
+	_, problem = serveProblem(t, bind, `{"email": "a@b.co", "password": "long enough", "age": "ten"}`)
+	assert.Equal(t, "invalid_input", problem.Code)
+	assert.Equal(t, []apperrors.FieldError{{Field: "age", Message: "must be a whole number"}}, problem.Errors)
+
+	_, problem = serveProblem(t, bind, `{"email":`)
+	assert.Equal(t, "invalid_input", problem.Code)
+	assert.Equal(t, "The request body is not valid JSON", problem.Detail)
+}
+
+func TestWriteError_MapsAndHidesInternalErrors(t *testing.T) {
+	w, problem := serveProblem(t, func(c *gin.Context) { WriteError(c, gorm.ErrRecordNotFound) }, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not_found", problem.Code)
+
+	orderNotFound := apperrors.New(http.StatusNotFound, "order_not_found", "order not found")
+	w, problem = serveProblem(t, func(c *gin.Context) { WriteError(c, orderNotFound.WithMessage("order 7 not found")) }, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "order_not_found", problem.Code)
+	assert.Equal(t, "order 7 not found", problem.Detail)
+
+	w, problem = serveProblem(t, func(c *gin.Context) {
+		WriteError(c, errors.New(`pq: relation "orders" does not exist`))
+	}, "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "internal_error", problem.Code)
+	assert.NotContains(t, w.Body.String(), "relation")
+}
@@ -1,10 +1,11 @@
 package middleware
 
 import (
-	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	apperrors "github.com/oguzhan/e-commerce/pkg/errors"
+	"github.com/oguzhan/e-commerce/pkg/ratelimit"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/time/rate"
 )
@@ -14,39 +15,29 @@ var limiter = rate.NewLimiter(rate.Every(time.Second), 100) // 100 requests per
 func RateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !limiter.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "too many requests",
-			})
+			WriteError(c, apperrors.ErrRateLimit)
 			return
 		}
 		c.Next()
 	}
 }
 
-// RedisRateLimiter is a more sophisticated rate limiter using Redis
+// RedisRateLimiter limits each client IP to 1000 requests in any hour,
+// counted in Redis so the limit holds across instances.
 func RedisRateLimiter(redisClient *redis.Client) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		key := c.ClientIP()
-		period := 1 * time.Hour
-		maxRequests := 1000 // requests per hour
+	store := ratelimit.NewRedisStore(redisClient, "")
+	policy := ratelimit.Policy{Name: "hourly", Limit: 1000, Window: time.Hour}
 
-		count, err := redisClient.Incr(c, key).Result()
+	return func(c *gin.Context) {
+		result, err := store.Allow(c.Request.Context(), policy.Name+":ip:"+c.ClientIP(), policy)
 		if err != nil {
 			c.Next() // In case of Redis error, let the request through
 			return
 		}
 
-		if count == 1 {
-			redisClient.Expire(c, key, period)
-		}
-
-		if count > int64(maxRequests) {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "too many requests",
-				"retry_after": time.Until(
-					time.Now().Add(period),
-				).Seconds(),
-			})
+		ratelimit.WriteHeaders(c.Writer.Header(), policy, result)
+		if !result.Allowed {
+			WriteError(c, apperrors.ErrRateLimit)
 			return
 		}
 
@@ -0,0 +1,383 @@
+// Package migrate applies numbered SQL migrations and records them in a
+// schema version table. Migrations are files named NNN_name.up.sql with an
+// optional NNN_name.down.sql, usually embedded with embed.FS.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+
+	// Table records the applied migrations.
+	Table = "schema_migrations"
+
+	// lockKey identifies the Postgres advisory lock held while migrating.
+	lockKey int64 = 7206145873
+)
+
+const (
+	StateApplied = "applied"
+	StatePending = "pending"
+	// StateChanged means the up file was edited after it was applied.
+	StateChanged = "changed"
+	// StateMissing means the database has a version no file describes,
+	// usually because a newer binary already migrated it.
+	StateMissing = "missing"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("migration changed after it was applied")
+	ErrNoDown           = errors.New("migration has no down file")
+	ErrUnknownDialect   = errors.New("unknown migration dialect")
+)
+
+var (
+	fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	nonWord  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Migration is one schema change.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status describes one migration known to the files or the database.
+type Status struct {
+	Version   int64
+	Name      string
+	State     string
+	AppliedAt time.Time
+}
+
+type record struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator applies the migrations in a directory to one database.
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New reads the migrations in fsys. dialect is Postgres or SQLite; with
+// Postgres every change runs under an advisory lock, so services starting
+// at the same time do not apply the same migration twice.
+func New(db *sql.DB, dialect string, fsys fs.FS) (*Migrator, error) {
+	if dialect != Postgres && dialect != SQLite {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDialect, dialect)
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Load reads and orders the migrations in the root of fsys.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+			sum := sha256.Sum256(data)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction, and returns the ones it applied. It stops at the first
+// applied migration whose file has changed.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	for _, migration := range m.migrations {
+		ran := false
+		err := m.locked(ctx, func(tx *sql.Tx) error {
+			records, err := m.records(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if r, ok := records[migration.Version]; ok {
+				if r.checksum != migration.Checksum {
+					return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+				}
+				return nil
+			}
+			if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			ran = true
+			_, err = tx.ExecContext(ctx,
+				m.bind("INSERT INTO "+Table+" (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+				migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return applied, err
+		}
+		if ran {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// Down reverts the n most recently applied migrations, newest first, and
+// returns the ones it reverted.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var reverted []Migration
+	for i := 0; i < n; i++ {
+		var migration *Migration
+		err := m.locked(ctx, func(tx *sql.Tx) error {
+			var version int64
+			err := tx.QueryRowContext(ctx, "SELECT version FROM "+Table+" ORDER BY version DESC LIMIT 1").Scan(&version)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			migration = m.find(version)
+			if migration == nil {
+				return fmt.Errorf("migration %d is applied but has no file", version)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDown, migration.Version, migration.Name)
+			}
+			if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			_, err = tx.ExecContext(ctx, m.bind("DELETE FROM "+Table+" WHERE version = ?"), version)
+			return err
+		})
+		if err != nil {
+			return reverted, err
+		}
+		if migration == nil {
+			break
+		}
+		reverted = append(reverted, *migration)
+	}
+	return reverted, nil
+}
+
+// Status lists every migration from the files and the database.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var records map[int64]record
+	err := m.locked(ctx, func(tx *sql.Tx) error {
+		var err error
+		records, err = m.records(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name, State: StatePending}
+		if r, ok := records[migration.Version]; ok {
+			status.State = StateApplied
+			status.AppliedAt = r.appliedAt
+			if r.checksum != migration.Checksum {
+				status.State = StateChanged
+			}
+			delete(records, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for version, r := range records {
+		statuses = append(statuses, Status{Version: version, Name: r.name, State: StateMissing, AppliedAt: r.appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Check fails while a migration is pending or has changed, so instances
+// started against an old schema do not receive traffic. It implements
+// health.Checker.
+func (m *Migrator) Check(ctx context.Context) error {
+	records, err := m.records(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, migration := range m.migrations {
+		r, ok := records[migration.Version]
+		if !ok {
+			pending++
+		} else if r.checksum != migration.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migrations pending", pending)
+	}
+	return nil
+}
+
+// locked runs fn in a transaction that holds the migration lock and sees
+// the version table.
+func (m *Migrator) locked(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if m.dialect == Postgres {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", lockKey); err != nil {
+			return fmt.Errorf("migration lock: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+Table+` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (m *Migrator) records(ctx context.Context, q querier) (map[int64]record, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+Table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make(map[int64]record)
+	for rows.Next() {
+		var version int64
+		var r record
+		if err := rows.Scan(&version, &r.name, &r.checksum, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		records[version] = r
+	}
+	return records, rows.Err()
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// bind rewrites ? placeholders for the dialect.
+func (m *Migrator) bind(query string) string {
+	if m.dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Create writes empty up and down files for a new migration to dir,
+// numbered after the newest one there, and returns their paths.
+func Create(dir, name string) (up, down string, err error) {
+	name = strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("migration name is empty")
+	}
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%03d_%s", version, name))
+	up, down = base+".up.sql", base+".down.sql"
+	for _, file := range []string{up, down} {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		f.Close()
+	}
+	return up, down, nil
+}
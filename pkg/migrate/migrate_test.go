@@ -0,0 +1,177 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/oguzhan/e-commerce/migrations"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDB(t *testing.T) *sql.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	// Every connection to :memory: is a separate database.
+	sqlDB.SetMaxOpenConns(1)
+	return sqlDB
+}
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"001_create_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY);")},
+		"001_create_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+		"002_add_widget_name.up.sql": {Data: []byte(`ALTER TABLE widgets ADD COLUMN name TEXT;
+CREATE INDEX idx_widgets_name ON widgets (name);`)},
+		"002_add_widget_name.down.sql": {Data: []byte("DROP INDEX idx_widgets_name;\nALTER TABLE widgets DROP COLUMN name;")},
+		"README.md":                    {Data: []byte("not a migration")},
+	}
+}
+
+func states(t *testing.T, m *Migrator) []string {
+	statuses, err := m.Status(context.Background())
+	assert.NoError(t, err)
+	var result []string
+	for _, s := range statuses {
+		result = append(result, s.Name+":"+s.State)
+	}
+	return result
+}
+
+func TestUp_AppliesPendingMigrationsOnce(t *testing.T) {
+	db := setupDB(t)
+	m, err := New(db, SQLite, testFS())
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	assert.EqualError(t, m.Check(ctx), "no such table: schema_migrations")
+
+	applied, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 2)
+	_, err = db.Exec("INSERT INTO widgets (name) VALUES ('gear')")
+	assert.NoError(t, err)
+
+	applied, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.NoError(t, m.Check(ctx))
+	assert.Equal(t, []string{"create_widgets:applied", "add_widget_name:applied"}, states(t, m))
+}
+
+func TestDown_RevertsNewestFirst(t *testing.T) {
+	db := setupDB(t)
+	m, err := New(db, SQLite, testFS())
+	assert.NoError(t, err)
+	ctx := context.Background()
+	_, err = m.Up(ctx)
+	assert.NoError(t, err)
+
+	reverted, err := m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	assert.Equal(t, int64(2), reverted[0].Version)
+	assert.Equal(t, []string{"create_widgets:applied", "add_widget_name:pending"}, states(t, m))
+	assert.EqualError(t, m.Check(ctx), "1 migrations pending")
+
+	reverted, err = m.Down(ctx, 5)
+	assert.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	_, err = db.Exec("SELECT 1 FROM widgets")
+	assert.Error(t, err)
+}
+
+func TestUp_RejectsChangedMigrations(t *testing.T) {
+	db := setupDB(t)
+	fsys := testFS()
+	m, err := New(db, SQLite, fsys)
+	assert.NoError(t, err)
+	ctx := context.Background()
+	_, err = m.Up(ctx)
+	assert.NoError(t, err)
+
+	fsys["001_create_widgets.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE widgets (id BIGINT PRIMARY KEY);")}
+	m, err = New(db, SQLite, fsys)
+	assert.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.ErrorIs(t, m.Check(ctx), ErrChecksumMismatch)
+	assert.Equal(t, []string{"create_widgets:changed", "add_widget_name:applied"}, states(t, m))
+}
+
+func TestUp_RollsBackFailedMigration(t *testing.T) {
+	db := setupDB(t)
+	fsys := testFS()
+	fsys["003_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE gadgets (id INTEGER);\nNOT SQL;")}
+	m, err := New(db, SQLite, fsys)
+	assert.NoError(t, err)
+
+	applied, err := m.Up(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, applied, 2)
+	assert.Equal(t, "broken:pending", states(t, m)[2])
+	_, err = db.Exec("SELECT 1 FROM gadgets")
+	assert.Error(t, err)
+}
+
+func TestStatus_ReportsVersionsWithoutFiles(t *testing.T) {
+	db := setupDB(t)
+	m, err := New(db, SQLite, testFS())
+	assert.NoError(t, err)
+	_, err = m.Up(context.Background())
+	assert.NoError(t, err)
+
+	older := testFS()
+	delete(older, "002_add_widget_name.up.sql")
+	delete(older, "002_add_widget_name.down.sql")
+	m, err = New(db, SQLite, older)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"create_widgets:applied", "add_widget_name:missing"}, states(t, m))
+	assert.NoError(t, m.Check(context.Background()))
+}
+
+func TestLoad(t *testing.T) {
+	_, err := Load(fstest.MapFS{"001_a.down.sql": {Data: []byte("SELECT 1;")}})
+	assert.EqualError(t, err, "migration 1_a has no up file")
+
+	_, err = Load(fstest.MapFS{
+		"001_a.up.sql": {Data: []byte("SELECT 1;")},
+		"001_b.up.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.Error(t, err)
+
+	embedded, err := Load(migrations.FS)
+	assert.NoError(t, err)
+	for i, m := range embedded {
+		assert.Equal(t, int64(i+1), m.Version, "migrations must be numbered without gaps")
+		assert.NotEmpty(t, m.Down, "migration %d_%s needs a down file", m.Version, m.Name)
+	}
+}
+
+func TestNew_RejectsUnknownDialect(t *testing.T) {
+	_, err := New(nil, "mysql", testFS())
+	assert.ErrorIs(t, err, ErrUnknownDialect)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "007_existing.up.sql"), []byte("SELECT 1;"), 0o644))
+
+	up, down, err := Create(dir, "Add order notes!")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "008_add_order_notes.up.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "008_add_order_notes.down.sql"), down)
+	assert.FileExists(t, up)
+	assert.FileExists(t, down)
+
+	_, _, err = Create(dir, "!!!")
+	assert.Error(t, err)
+}
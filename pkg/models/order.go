@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -18,15 +19,102 @@ const (
 
 type Order struct {
 	gorm.Model
-	UserID          uint        `gorm:"not null" json:"user_id"`
-	Status          OrderStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	TotalAmount     float64     `gorm:"not null" json:"total_amount"`
-	ShippingAddress string      `gorm:"not null" json:"shipping_address"`
-	BillingAddress  string      `gorm:"not null" json:"billing_address"`
-	PaymentMethod   string      `gorm:"not null" json:"payment_method"`
-	OrderItems      []OrderItem `json:"order_items"`
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
+	UserID          uint            `gorm:"not null" json:"user_id"`
+	GuestEmail      string          `gorm:"index" json:"guest_email,omitempty"`
+	Status          OrderStatus     `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	TotalAmount     float64         `gorm:"not null" json:"total_amount"`
+	ShippingAddress string          `gorm:"not null" json:"shipping_address"`
+	BillingAddress  string          `gorm:"not null" json:"billing_address"`
+	ShippingDetails AddressSnapshot `gorm:"embedded;embeddedPrefix:shipping_" json:"shipping_details"`
+	BillingDetails  AddressSnapshot `gorm:"embedded;embeddedPrefix:billing_" json:"billing_details"`
+	PaymentMethod   string          `gorm:"not null" json:"payment_method"`
+	OrderItems      []OrderItem     `json:"order_items"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// AddressSnapshot is a copy of an address taken when the order is placed.
+// It is stored on the order itself so later changes to the user's address
+// book never alter historical orders.
+type AddressSnapshot struct {
+	SourceAddressID *uint  `json:"source_address_id,omitempty"`
+	Title           string `json:"title"`
+	AddressLine     string `json:"address_line"`
+	City            string `json:"city"`
+	State           string `json:"state"`
+	Country         string `json:"country"`
+	PostalCode      string `json:"postal_code"`
+}
+
+// NewAddressSnapshot copies an address book entry into a snapshot.
+func NewAddressSnapshot(address *Address) AddressSnapshot {
+	id := address.ID
+	return AddressSnapshot{
+		SourceAddressID: &id,
+		Title:           address.Title,
+		AddressLine:     address.AddressLine,
+		City:            address.City,
+		State:           address.State,
+		Country:         address.Country,
+		PostalCode:      address.PostalCode,
+	}
+}
+
+// String renders the snapshot as a single line, matching the legacy
+// free-text ShippingAddress/BillingAddress columns.
+func (a AddressSnapshot) String() string {
+	parts := make([]string, 0, 5)
+	for _, part := range []string{a.AddressLine, a.City, a.State, a.PostalCode, a.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AddressInput is a structured address supplied inline with an order.
+type AddressInput struct {
+	Title       string `json:"title"`
+	AddressLine string `json:"address_line" binding:"required"`
+	City        string `json:"city" binding:"required"`
+	State       string `json:"state"`
+	Country     string `json:"country" binding:"required"`
+	PostalCode  string `json:"postal_code" binding:"required"`
+}
+
+// Snapshot converts the inline address into an order snapshot.
+func (a *AddressInput) Snapshot() AddressSnapshot {
+	return AddressSnapshot{
+		Title:       a.Title,
+		AddressLine: a.AddressLine,
+		City:        a.City,
+		State:       a.State,
+		Country:     a.Country,
+		PostalCode:  a.PostalCode,
+	}
+}
+
+// CreateOrderRequest is the payload for placing an order. Each address may be
+// given either as an address book ID or inline; when both are omitted the
+// user's default address is used.
+type CreateOrderRequest struct {
+	ShippingAddressID *uint         `json:"shipping_address_id"`
+	ShippingAddress   *AddressInput `json:"shipping_address"`
+	BillingAddressID  *uint         `json:"billing_address_id"`
+	BillingAddress    *AddressInput `json:"billing_address"`
+	PaymentMethod     string        `json:"payment_method" binding:"required"`
+	TotalAmount       float64       `json:"total_amount"`
+	OrderItems        []OrderItem   `json:"order_items"`
+}
+
+// GuestOrderRequest is the payload for checking out without an account.
+// The items are those of the guest cart. Guests have no address book, so
+// addresses must be given inline.
+type GuestOrderRequest struct {
+	Email           string        `json:"email" binding:"required,email"`
+	ShippingAddress *AddressInput `json:"shipping_address"`
+	BillingAddress  *AddressInput `json:"billing_address"`
+	PaymentMethod   string        `json:"payment_method" binding:"required"`
 }
 
 type OrderItem struct {
@@ -39,14 +127,17 @@ type OrderItem struct {
 }
 
 type OrderResponse struct {
-	ID              uint        `json:"id"`
-	UserID          uint        `json:"user_id"`
-	Status          OrderStatus `json:"status"`
-	TotalAmount     float64     `json:"total_amount"`
-	ShippingAddress string      `json:"shipping_address"`
-	BillingAddress  string      `json:"billing_address"`
-	PaymentMethod   string      `json:"payment_method"`
-	OrderItems      []OrderItem `json:"order_items"`
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
+	ID              uint            `json:"id"`
+	UserID          uint            `json:"user_id"`
+	GuestEmail      string          `json:"guest_email,omitempty"`
+	Status          OrderStatus     `json:"status"`
+	TotalAmount     float64         `json:"total_amount"`
+	ShippingAddress string          `json:"shipping_address"`
+	BillingAddress  string          `json:"billing_address"`
+	ShippingDetails AddressSnapshot `json:"shipping_details"`
+	BillingDetails  AddressSnapshot `json:"billing_details"`
+	PaymentMethod   string          `json:"payment_method"`
+	OrderItems      []OrderItem     `json:"order_items"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
 }
@@ -8,28 +8,58 @@ import (
 
 type Product struct {
 	gorm.Model
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `gorm:"not null" json:"price"`
-	Stock       int       `gorm:"not null" json:"stock"`
-	Category    string    `json:"category"`
-	ImageURL    string    `json:"image_url"`
-	SKU         string    `gorm:"uniqueIndex" json:"sku"`
-	IsActive    bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Name          string    `gorm:"not null" json:"name"`
+	Description   string    `json:"description"`
+	Price         float64   `gorm:"not null" json:"price"`
+	Stock         int       `gorm:"not null" json:"stock"`
+	Category      string    `json:"category"`
+	ImageURL      string    `json:"image_url"`
+	SKU           string    `gorm:"uniqueIndex" json:"sku"`
+	IsActive      bool      `gorm:"default:true" json:"is_active"`
+	RatingAverage float64   `gorm:"default:0;index" json:"rating_average"`
+	RatingCount   int       `gorm:"default:0" json:"rating_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type ProductResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	Stock       int       `json:"stock"`
-	Category    string    `json:"category"`
-	ImageURL    string    `json:"image_url"`
-	SKU         string    `json:"sku"`
-	IsActive    bool      `json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uint      `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Price         float64   `json:"price"`
+	Stock         int       `json:"stock"`
+	Category      string    `json:"category"`
+	ImageURL      string    `json:"image_url"`
+	SKU           string    `json:"sku"`
+	IsActive      bool      `json:"is_active"`
+	RatingAverage float64   `json:"rating_average"`
+	RatingCount   int       `json:"rating_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (p *Product) ToResponse() ProductResponse {
+	return ProductResponse{
+		ID:            p.ID,
+		Name:          p.Name,
+		Description:   p.Description,
+		Price:         p.Price,
+		Stock:         p.Stock,
+		Category:      p.Category,
+		ImageURL:      p.ImageURL,
+		SKU:           p.SKU,
+		IsActive:      p.IsActive,
+		RatingAverage: p.RatingAverage,
+		RatingCount:   p.RatingCount,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+}
+
+func NewProductResponses(products []Product) []ProductResponse {
+	responses := make([]ProductResponse, 0, len(products))
+	for i := range products {
+		responses = append(responses, products[i].ToResponse())
+	}
+	return responses
 }
@@ -22,15 +22,16 @@ const (
 
 type User struct {
 	gorm.Model
-	Email     string    `json:"email" gorm:"uniqueIndex;not null"`
-	Password  string    `json:"password" gorm:"not null"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Role      string    `json:"role" gorm:"default:user"`
-	LastLogin time.Time `json:"last_login"`
-	IsActive  bool      `json:"is_active" gorm:"default:true"`
-	Addresses []Address `json:"addresses"`
-	Contacts  []Contact `json:"contacts"`
+	Email               string    `json:"email" gorm:"uniqueIndex;not null"`
+	Password            string    `json:"password" gorm:"not null"`
+	FirstName           string    `json:"first_name"`
+	LastName            string    `json:"last_name"`
+	Role                string    `json:"role" gorm:"default:user"`
+	LastLogin           time.Time `json:"last_login"`
+	IsActive            bool      `json:"is_active" gorm:"default:true"`
+	CartRemindersOptOut bool      `json:"cart_reminders_opt_out" gorm:"default:false"`
+	Addresses           []Address `json:"addresses"`
+	Contacts            []Contact `json:"contacts"`
 }
 
 type Address struct {
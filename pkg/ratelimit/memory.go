@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps request timestamps in process. Limits are per instance,
+// so it suits development and single-instance deployments.
+type MemoryStore struct {
+	mu      sync.Mutex
+	hits    map[string][]time.Time
+	now     func() time.Time
+	calls   int
+	maxIdle time.Duration
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hits: make(map[string][]time.Time), now: time.Now}
+}
+
+func (s *MemoryStore) Allow(_ context.Context, key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if policy.Window > s.maxIdle {
+		s.maxIdle = policy.Window
+	}
+	s.calls++
+	if s.calls%1000 == 0 {
+		s.sweep(now)
+	}
+
+	hits := s.hits[key]
+	start := 0
+	for start < len(hits) && !hits[start].After(now.Add(-policy.Window)) {
+		start++
+	}
+	hits = hits[start:]
+
+	allowed := len(hits) < policy.Limit
+	if allowed {
+		hits = append(hits, now)
+	}
+	s.hits[key] = hits
+
+	reset := policy.Window
+	if len(hits) > 0 {
+		reset = hits[0].Add(policy.Window).Sub(now)
+	}
+	return Result{
+		Allowed:   allowed,
+		Limit:     policy.Limit,
+		Remaining: policy.Limit - len(hits),
+		Reset:     reset,
+	}, nil
+}
+
+// sweep drops keys whose newest request is older than the longest window
+// seen, so idle clients do not accumulate.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, hits := range s.hits {
+		if len(hits) == 0 || !hits[len(hits)-1].After(now.Add(-s.maxIdle)) {
+			delete(s.hits, key)
+		}
+	}
+}
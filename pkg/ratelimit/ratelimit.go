@@ -0,0 +1,77 @@
+// Package ratelimit implements sliding-window request limits backed by
+// memory for a single instance or Redis when limits are shared across
+// instances.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy allows Limit requests in any Window-long period.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// ParsePolicy parses "limit/window", such as "100/1m" or "5/15m".
+func ParsePolicy(name, text string) (Policy, error) {
+	limitText, windowText, ok := strings.Cut(strings.TrimSpace(text), "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("expected limit/window, got %q", text)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(limitText))
+	if err != nil || limit <= 0 {
+		return Policy{}, fmt.Errorf("invalid limit in %q", text)
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(windowText))
+	if err != nil || window <= 0 {
+		return Policy{}, fmt.Errorf("invalid window in %q", text)
+	}
+	return Policy{Name: name, Limit: limit, Window: window}, nil
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%d/%s", p.Limit, p.Window)
+}
+
+// Result describes a key's standing after a request was counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the oldest counted request leaves the window.
+	Reset time.Duration
+}
+
+// Store counts requests per key. Allow records the request only when it is
+// within the limit.
+type Store interface {
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// WriteHeaders sets the RateLimit-* headers, and Retry-After when the
+// request was refused.
+func WriteHeaders(header http.Header, policy Policy, result Result) {
+	reset := strconv.Itoa(seconds(result.Reset))
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", reset)
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, seconds(policy.Window)))
+	if !result.Allowed {
+		header.Set("Retry-After", reset)
+	}
+}
+
+// seconds rounds up so clients never retry before the window has moved.
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
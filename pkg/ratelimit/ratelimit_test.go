@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("login", "5/15m")
+	assert.NoError(t, err)
+	assert.Equal(t, Policy{Name: "login", Limit: 5, Window: 15 * time.Minute}, policy)
+
+	for _, text := range []string{"5", "0/1m", "x/1m", "5/soon", "5/-1m"} {
+		_, err := ParsePolicy("bad", text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	policy := Policy{Name: "test", Limit: 2, Window: time.Minute}
+	ctx := context.Background()
+
+	result, _ := store.Allow(ctx, "a", policy)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+
+	now = now.Add(40 * time.Second)
+	result, _ = store.Allow(ctx, "a", policy)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 20*time.Second, result.Reset)
+
+	result, _ = store.Allow(ctx, "a", policy)
+	assert.False(t, result.Allowed)
+
+	// Other keys are counted separately.
+	result, _ = store.Allow(ctx, "b", policy)
+	assert.True(t, result.Allowed)
+
+	// Once the first request leaves the window one slot frees up, unlike a
+	// fixed window that would reset both.
+	now = now.Add(21 * time.Second)
+	result, _ = store.Allow(ctx, "a", policy)
+	assert.True(t, result.Allowed)
+	result, _ = store.Allow(ctx, "a", policy)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 39*time.Second, result.Reset)
+}
+
+func TestWriteHeaders(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 10, Window: time.Minute}
+	header := http.Header{}
+	WriteHeaders(header, policy, Result{Allowed: true, Limit: 10, Remaining: 4, Reset: 1500 * time.Millisecond})
+	assert.Equal(t, "10", header.Get("RateLimit-Limit"))
+	assert.Equal(t, "4", header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", header.Get("RateLimit-Reset"))
+	assert.Equal(t, "10;w=60", header.Get("RateLimit-Policy"))
+	assert.Empty(t, header.Get("Retry-After"))
+
+	WriteHeaders(header, policy, Result{Limit: 10, Reset: 30 * time.Second})
+	assert.Equal(t, "30", header.Get("Retry-After"))
+}
@@ -0,0 +1,197 @@
+// Package resilience protects outbound calls with circuit breakers,
+// bulkheads and retries.
+package resilience
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the breaker.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before letting trial
+	// calls through.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of trial calls allowed while half-open.
+	// The breaker closes once all of them succeed and opens again on the
+	// first failure.
+	HalfOpenRequests int
+}
+
+func DefaultBreakerOptions() BreakerOptions {
+	return BreakerOptions{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+// Breaker is a circuit breaker for one dependency.
+type Breaker struct {
+	name    string
+	options BreakerOptions
+	now     func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	failures   int
+	openedAt   time.Time
+	trials     int
+	successes  int
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*Breaker)
+)
+
+// NewBreaker creates a breaker and registers it under name, so its state is
+// reported by BreakerStates. A later breaker with the same name replaces the
+// earlier one in the report.
+func NewBreaker(name string, options BreakerOptions) *Breaker {
+	defaults := DefaultBreakerOptions()
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = defaults.FailureThreshold
+	}
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = defaults.OpenTimeout
+	}
+	if options.HalfOpenRequests <= 0 {
+		options.HalfOpenRequests = defaults.HalfOpenRequests
+	}
+	b := &Breaker{name: name, options: options, now: time.Now}
+
+	registryMu.Lock()
+	registry[name] = b
+	registryMu.Unlock()
+	metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(StateClosed))
+	return b
+}
+
+// BreakerStates returns the current state of every registered breaker.
+func BreakerStates() map[string]string {
+	registryMu.Lock()
+	breakers := make([]*Breaker, 0, len(registry))
+	for _, b := range registry {
+		breakers = append(breakers, b)
+	}
+	registryMu.Unlock()
+
+	sort.Slice(breakers, func(i, j int) bool { return breakers[i].name < breakers[j].name })
+	states := make(map[string]string, len(breakers))
+	for _, b := range breakers {
+		states[b.name] = b.State().String()
+	}
+	return states
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Allow reports whether a call may proceed. When it may, the returned done
+// function must be called with the outcome of the call.
+func (b *Breaker) Allow() (func(success bool), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	switch b.state {
+	case StateOpen:
+		return nil, ErrCircuitOpen
+	case StateHalfOpen:
+		if b.trials >= b.options.HalfOpenRequests {
+			return nil, ErrCircuitOpen
+		}
+		b.trials++
+	}
+
+	generation := b.generation
+	return func(success bool) { b.record(generation, success) }, nil
+}
+
+func (b *Breaker) record(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Outcomes of calls started before the last state change say nothing
+	// about the current state.
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.options.FailureThreshold {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		if !success {
+			b.setState(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.options.HalfOpenRequests {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// advance moves an open breaker to half-open once its timeout has passed.
+func (b *Breaker) advance() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.options.OpenTimeout {
+		b.setState(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.trials = 0
+	b.successes = 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+	metrics.RecordCircuitBreakerState(b.name, state.String(), int(state))
+}
@@ -0,0 +1,66 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+)
+
+var ErrBulkheadFull = errors.New("too many concurrent calls to dependency")
+
+// Bulkhead limits the number of concurrent calls to one dependency, so a
+// slow dependency can only tie up a bounded number of request goroutines.
+type Bulkhead struct {
+	name    string
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead allows maxConcurrent calls at a time. A call that finds every
+// slot taken waits up to maxWait for one to free up.
+func NewBulkhead(name string, maxConcurrent int, maxWait time.Duration) *Bulkhead {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &Bulkhead{name: name, slots: make(chan struct{}, maxConcurrent), maxWait: maxWait}
+}
+
+// Acquire takes a slot. The returned release function gives it back and
+// may be called more than once.
+func (b *Bulkhead) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.releaser(), nil
+	default:
+	}
+
+	if b.maxWait > 0 {
+		timer := time.NewTimer(b.maxWait)
+		defer timer.Stop()
+		select {
+		case b.slots <- struct{}{}:
+			return b.releaser(), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	metrics.RecordBulkheadRejection(b.name)
+	return nil, ErrBulkheadFull
+}
+
+func (b *Bulkhead) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-b.slots })
+	}
+}
+
+// InUse returns the number of slots currently taken.
+func (b *Bulkhead) InUse() int {
+	return len(b.slots)
+}
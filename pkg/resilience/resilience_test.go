@@ -0,0 +1,117 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker_OpensHalfOpensAndCloses(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker("test-breaker", BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenRequests: 1})
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		done, err := b.Allow()
+		assert.NoError(t, err)
+		done(false)
+	}
+	assert.Equal(t, StateOpen, b.State())
+	_, err := b.Allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, "open", BreakerStates()["test-breaker"])
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, b.State())
+	trial, err := b.Allow()
+	assert.NoError(t, err)
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen, "only one trial call while half-open")
+
+	trial(false)
+	assert.Equal(t, StateOpen, b.State())
+
+	now = now.Add(time.Minute)
+	trial, err = b.Allow()
+	assert.NoError(t, err)
+	trial(true)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBulkhead_RejectsWhenFull(t *testing.T) {
+	b := NewBulkhead("test-bulkhead", 1, 10*time.Millisecond)
+	release, err := b.Acquire(context.Background())
+	assert.NoError(t, err)
+
+	_, err = b.Acquire(context.Background())
+	assert.ErrorIs(t, err, ErrBulkheadFull)
+
+	release()
+	release()
+	assert.Equal(t, 0, b.InUse())
+	_, err = b.Acquire(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestRetryPolicy_StopsOnPermanentErrorsAndBudget(t *testing.T) {
+	calls := 0
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond}
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 2 {
+			return Permanent(errors.New("bad request"))
+		}
+		return errors.New("unavailable")
+	})
+	assert.EqualError(t, err, "bad request")
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	policy.Budget = NewRetryBudget(0, 1)
+	policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("unavailable")
+	})
+	assert.Equal(t, 2, calls, "budget allows a single retry")
+}
+
+func TestTransport_RetriesIdempotentRequestsAndTripsBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	transport := NewTransport("test-transport", nil, Options{
+		Breaker:       BreakerOptions{FailureThreshold: 3, OpenTimeout: time.Minute},
+		MaxConcurrent: 2,
+		Retry:         RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	})
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp.Body.Close()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// POST is not retried; its failure opens the breaker.
+	resp, err = client.Post(server.URL, "application/json", strings.NewReader(`{}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, StateOpen, transport.Breaker().State())
+
+	_, err = client.Get(server.URL)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, 0, transport.bulkhead.InUse())
+}
@@ -0,0 +1,123 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy retries failed calls with exponential backoff and full
+// jitter. When a Budget is set, retries also stop once the budget is spent,
+// so an outage does not multiply the load on the failing dependency.
+type RetryPolicy struct {
+	// MaxAttempts includes the first call. Values below 2 disable retries.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Budget      *RetryBudget
+	// OnRetry, if set, is called before each retry.
+	OnRetry func(attempt int, err error)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, fails permanently, or the attempts or the
+// budget run out, and returns the last error. Errors marked with Permanent
+// are returned unwrapped.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.Budget != nil {
+		p.Budget.deposit()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if attempt >= p.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		if p.Budget != nil && !p.Budget.withdraw() {
+			return err
+		}
+
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt+1, err)
+		}
+	}
+}
+
+// delay returns a random wait between zero and the exponential backoff for
+// the attempt, capped at MaxDelay.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.BaseDelay
+	if backoff <= 0 {
+		return 0
+	}
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || backoff < p.MaxDelay); i++ {
+		backoff *= 2
+	}
+	if p.MaxDelay > 0 && backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// RetryBudget caps retries at a fraction of calls. Every call adds ratio
+// tokens, every retry spends one, and the balance never exceeds capacity.
+// The budget starts full so a quiet client can still retry.
+type RetryBudget struct {
+	mu       sync.Mutex
+	tokens   float64
+	ratio    float64
+	capacity float64
+}
+
+func NewRetryBudget(ratio float64, capacity int) *RetryBudget {
+	return &RetryBudget{tokens: float64(capacity), ratio: ratio, capacity: float64(capacity)}
+}
+
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,167 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/metrics"
+)
+
+type Options struct {
+	Breaker BreakerOptions
+	// MaxConcurrent and MaxWait configure the bulkhead.
+	MaxConcurrent int
+	MaxWait       time.Duration
+	// Retry applies to idempotent requests only.
+	Retry RetryPolicy
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Breaker:       DefaultBreakerOptions(),
+		MaxConcurrent: 50,
+		MaxWait:       100 * time.Millisecond,
+		Retry: RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   100 * time.Millisecond,
+			MaxDelay:    2 * time.Second,
+			Budget:      NewRetryBudget(0.1, 10),
+		},
+	}
+}
+
+// Transport wraps an http.RoundTripper with a circuit breaker, a bulkhead
+// and retries for one dependency. Network errors and 5xx answers count as
+// failures for the breaker.
+type Transport struct {
+	name     string
+	base     http.RoundTripper
+	breaker  *Breaker
+	bulkhead *Bulkhead
+	retry    RetryPolicy
+}
+
+func NewTransport(name string, base http.RoundTripper, options Options) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = DefaultOptions().MaxConcurrent
+	}
+	retry := options.Retry
+	if retry.OnRetry == nil {
+		retry.OnRetry = func(int, error) { metrics.RecordOutboundRetry(name) }
+	}
+	return &Transport{
+		name:     name,
+		base:     base,
+		breaker:  NewBreaker(name, options.Breaker),
+		bulkhead: NewBulkhead(name, options.MaxConcurrent, options.MaxWait),
+		retry:    retry,
+	}
+}
+
+func (t *Transport) Breaker() *Breaker {
+	return t.breaker
+}
+
+var errRetryableStatus = errors.New("retryable status")
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !replayable(req) {
+		return t.attempt(req)
+	}
+
+	var resp *http.Response
+	err := t.retry.Do(req.Context(), func(ctx context.Context) error {
+		attemptReq := req
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			resp = nil
+
+			attemptReq = req.Clone(ctx)
+			if req.Body != nil && req.Body != http.NoBody {
+				body, err := req.GetBody()
+				if err != nil {
+					return Permanent(err)
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		r, err := t.attempt(attemptReq)
+		if err != nil {
+			if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull) {
+				return Permanent(err)
+			}
+			return err
+		}
+		resp = r
+		if retryableStatus(r.StatusCode) {
+			return errRetryableStatus
+		}
+		return nil
+	})
+	if resp != nil {
+		return resp, nil
+	}
+	return nil, err
+}
+
+// attempt makes one call through the bulkhead and the breaker. The bulkhead
+// slot is held until the response body is closed.
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	release, err := t.bulkhead.Acquire(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.name, err)
+	}
+	done, err := t.breaker.Allow()
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("%s: %w", t.name, err)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		// A cancelled caller says nothing about the dependency's health.
+		done(errors.Is(err, context.Canceled))
+		return nil, err
+	}
+	done(resp.StatusCode < 500)
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// replayable reports whether the request may be sent more than once.
+func replayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,206 @@
+// Package server runs a binary's HTTP servers and background workers and
+// shuts them down in order on SIGINT or SIGTERM, so deploys do not cut off
+// in-flight requests.
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/oguzhan/e-commerce/pkg/config"
+	"go.uber.org/zap"
+)
+
+type Options struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// DrainPeriod is how long the servers keep serving after a stop signal
+	// while readiness already fails, so load balancers stop routing to
+	// this instance before it stops accepting connections.
+	DrainPeriod time.Duration
+	// ShutdownTimeout bounds everything after the drain: finishing
+	// in-flight requests, stopping workers and closing resources.
+	ShutdownTimeout time.Duration
+}
+
+// DefaultOptions returns the options used by binaries without a config file.
+func DefaultOptions() Options {
+	return FromConfig(config.Default())
+}
+
+func FromConfig(cfg *config.Config) Options {
+	return Options{
+		ReadTimeout:       cfg.ServerReadTimeout,
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		WriteTimeout:      cfg.ServerWriteTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
+		DrainPeriod:       cfg.ServerDrainPeriod,
+		ShutdownTimeout:   cfg.ServerShutdownTimeout,
+	}
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// Runner owns the lifecycle of a binary. Register servers, workers and
+// resources, then call Run. On shutdown it drains, stops the HTTP servers,
+// cancels and waits for the workers, then closes resources in the order
+// they were registered.
+type Runner struct {
+	options Options
+	logger  *zap.Logger
+
+	servers []*http.Server
+	names   []string
+	onDrain []func()
+	closers []closer
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+func New(options Options, logger *zap.Logger) *Runner {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{options: options, logger: logger, ctx: ctx, cancel: cancel}
+}
+
+// Serve adds an HTTP server for handler on addr.
+func (r *Runner) Serve(name, addr string, handler http.Handler) {
+	r.servers = append(r.servers, &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       r.options.ReadTimeout,
+		ReadHeaderTimeout: r.options.ReadHeaderTimeout,
+		WriteTimeout:      r.options.WriteTimeout,
+		IdleTimeout:       r.options.IdleTimeout,
+		ErrorLog:          zap.NewStdLog(r.logger.With(zap.String("server", name))),
+	})
+	r.names = append(r.names, name)
+}
+
+// Go runs a background worker until shutdown cancels its context. Shutdown
+// waits for it to return before closing resources it may still be using.
+func (r *Runner) Go(name string, worker func(ctx context.Context)) {
+	r.workers.Add(1)
+	go func() {
+		defer r.workers.Done()
+		worker(r.ctx)
+		if r.ctx.Err() == nil {
+			r.logger.Warn("worker stopped before shutdown", zap.String("worker", name))
+		}
+	}()
+}
+
+// OnDrain registers a function called as soon as shutdown starts, such as
+// health.Health.Drain.
+func (r *Runner) OnDrain(fn func()) {
+	r.onDrain = append(r.onDrain, fn)
+}
+
+// OnClose registers a resource to close once servers and workers have
+// stopped. Resources are closed in registration order, so register the
+// logger last.
+func (r *Runner) OnClose(name string, close func(ctx context.Context) error) {
+	r.closers = append(r.closers, closer{name: name, close: close})
+}
+
+// Run serves until SIGINT, SIGTERM, cancellation of ctx or a server
+// failure, then shuts down. It returns the server error, if any, joined
+// with any shutdown errors.
+func (r *Runner) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, len(r.servers))
+	for i, srv := range r.servers {
+		listener, err := net.Listen("tcp", srv.Addr)
+		if err != nil {
+			failed <- err
+			break
+		}
+		r.logger.Info("Starting server", zap.String("server", r.names[i]), zap.String("addr", listener.Addr().String()))
+		go func(srv *http.Server) {
+			if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				failed <- err
+			}
+		}(srv)
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		r.logger.Info("Shutdown signal received")
+	case serveErr = <-failed:
+		r.logger.Error("Server failed", zap.Error(serveErr))
+	}
+	stop()
+	return errors.Join(serveErr, r.shutdown(serveErr == nil))
+}
+
+func (r *Runner) shutdown(drain bool) error {
+	for _, fn := range r.onDrain {
+		fn()
+	}
+	if drain && r.options.DrainPeriod > 0 {
+		r.logger.Info("Draining", zap.Duration("period", r.options.DrainPeriod))
+		time.Sleep(r.options.DrainPeriod)
+	}
+
+	ctx := context.Background()
+	if r.options.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.options.ShutdownTimeout)
+		defer cancel()
+	}
+
+	var errs []error
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, srv := range r.servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				srv.Close()
+			}
+		}(srv)
+	}
+	wg.Wait()
+
+	r.cancel()
+	stopped := make(chan struct{})
+	go func() {
+		r.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		errs = append(errs, errors.New("workers did not stop before the shutdown deadline"))
+	}
+
+	for _, c := range r.closers {
+		if err := c.close(ctx); err != nil {
+			r.logger.Warn("Failed to close", zap.String("resource", c.name), zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestRunner_ShutsDownInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var steps []string
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, step)
+	}
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		record("request")
+		w.Write([]byte("done"))
+	})
+
+	addr := freeAddr(t)
+	runner := New(Options{DrainPeriod: 10 * time.Millisecond, ShutdownTimeout: time.Second}, nil)
+	runner.Serve("api", addr, handler)
+	runner.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		record("worker")
+	})
+	runner.OnDrain(func() { record("drain") })
+	runner.OnClose("database", func(context.Context) error {
+		record("database")
+		return nil
+	})
+	runner.OnClose("logger", func(context.Context) error {
+		record("logger")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+
+	var body string
+	var requestErr error
+	requested := make(chan struct{})
+	go func() {
+		defer close(requested)
+		var resp *http.Response
+		for i := 0; i < 50; i++ {
+			resp, requestErr = http.Get("http://" + addr)
+			if requestErr == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if requestErr != nil {
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body = string(data)
+	}()
+
+	<-started
+	cancel()
+	assert.NoError(t, <-done)
+	<-requested
+
+	assert.NoError(t, requestErr)
+	assert.Equal(t, "done", body)
+	assert.Equal(t, []string{"drain", "request", "worker", "database", "logger"}, steps)
+}
+
+func TestRunner_ReturnsListenErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	closed := false
+	runner := New(Options{DrainPeriod: time.Hour}, nil)
+	runner.Serve("api", listener.Addr().String(), http.NotFoundHandler())
+	runner.OnClose("database", func(context.Context) error {
+		closed = true
+		return nil
+	})
+
+	assert.Error(t, runner.Run(context.Background()))
+	assert.True(t, closed)
+}
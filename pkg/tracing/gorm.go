@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// GormPlugin starts a span around every gorm query, as a child of the span
+// in the statement context set with db.WithContext.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", startGormSpan("gorm.create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endGormSpan),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", startGormSpan("gorm.query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endGormSpan),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", startGormSpan("gorm.update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endGormSpan),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startGormSpan("gorm.delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endGormSpan),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", startGormSpan("gorm.row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endGormSpan),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startGormSpan("gorm.raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endGormSpan),
+	)
+}
+
+func startGormSpan(name string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := Start(db.Statement.Context, name)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endGormSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(*Span)
+	if db.Statement.Table != "" {
+		span.SetAttribute("db.table", db.Statement.Table)
+	}
+	span.SetAttribute("db.statement", db.Statement.SQL.String())
+	span.SetAttribute("db.rows_affected", db.Statement.RowsAffected)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+	}
+	span.End()
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+)
+
+// StartRequest continues the request ID and trace context sent with r, or
+// starts new ones, and starts a server span for handling it. The returned
+// context carries both and should replace the request context.
+func StartRequest(tracer *Tracer, r *http.Request, name string) (context.Context, *Span) {
+	ctx := r.Context()
+	requestID := r.Header.Get(RequestIDHeader)
+	if requestID == "" || len(requestID) > 128 {
+		requestID = NewRequestID()
+	}
+	ctx = ContextWithRequestID(ctx, requestID)
+	if parent, ok := ParseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+		ctx = ContextWithRemoteParent(ctx, parent)
+	}
+
+	ctx, span := tracer.Start(ctx, name, KindServer)
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.Path)
+	span.SetAttribute("request_id", requestID)
+	return ctx, span
+}
+
+// EndRequest records the response status on a span started with
+// StartRequest and ends it.
+func EndRequest(span *Span, status int, err error) {
+	span.SetAttribute("http.status_code", status)
+	if status >= http.StatusInternalServerError {
+		if err == nil {
+			err = httpError(http.StatusText(status))
+		}
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// Middleware traces plain net/http handlers, such as chi routers, the way
+// the gin middleware traces gin routes. Spans are named after the method
+// and path.
+func Middleware(tracer *Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := StartRequest(tracer, r, r.Method+" "+r.URL.Path)
+			w.Header().Set(RequestIDHeader, RequestID(ctx))
+			w.Header().Set(TraceparentHeader, span.SpanContext().Traceparent())
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+			EndRequest(span, recorder.status, nil)
+		})
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Transport wraps base so every outgoing request runs in a client span and
+// carries the request ID and traceparent headers.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Default().Start(req.Context(), "HTTP "+req.Method, KindClient)
+	defer span.End()
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Redacted())
+
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.RecordError(httpError(resp.Status))
+	}
+	return resp, nil
+}
+
+type httpError string
+
+func (e httpError) Error() string { return string(e) }
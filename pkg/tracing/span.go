@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindInternal Kind = "internal"
+	KindServer   Kind = "server"
+	KindClient   Kind = "client"
+)
+
+// SpanData is a finished span as handed to exporters.
+type SpanData struct {
+	Service      string                 `json:"service"`
+	Name         string                 `json:"name"`
+	Kind         Kind                   `json:"kind"`
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	Duration     time.Duration          `json:"duration_ns"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// Exporter receives every sampled span when it ends.
+type Exporter interface {
+	ExportSpan(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer starts spans for one service.
+type Tracer struct {
+	service  string
+	exporter Exporter
+}
+
+// NewTracer creates a tracer exporting to exporter. A nil exporter still
+// creates and propagates span IDs but records nothing.
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+func init() {
+	defaultTracer.Store(NewTracer("", nil))
+}
+
+// SetDefault sets the tracer used by Start and by the gorm, cache and HTTP
+// instrumentation.
+func SetDefault(tracer *Tracer) {
+	defaultTracer.Store(tracer)
+}
+
+// Default returns the tracer set with SetDefault.
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// Start starts an internal span with the default tracer.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return Default().Start(ctx, name, KindInternal)
+}
+
+// Start starts a span as a child of the span in ctx, or of the remote
+// parent, or as the root of a new trace. The span must be ended with End.
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &Span{
+		tracer: t,
+		data:   SpanData{Service: t.service, Name: name, Kind: kind, Start: time.Now()},
+	}
+	if parent.IsValid() {
+		span.sc = SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled}
+		span.data.ParentSpanID = parent.SpanID.String()
+	} else {
+		rand.Read(span.sc.TraceID[:])
+		span.sc.Sampled = true
+	}
+	rand.Read(span.sc.SpanID[:])
+	span.data.TraceID = span.sc.TraceID.String()
+	span.data.SpanID = span.sc.SpanID.String()
+	return ContextWithSpan(ctx, span), span
+}
+
+// Shutdown flushes and closes the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t.exporter == nil {
+		return nil
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+// Span is an operation being timed. Its methods are safe for concurrent
+// use and do nothing on a nil span.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute records a key/value pair on the span. Changes after End are
+// ignored.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+	s.data.Attributes[key] = value
+}
+
+// RecordError marks the span as failed. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Error = err.Error()
+	}
+}
+
+// End finishes the span and exports it. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	s.data.Duration = s.data.End.Sub(s.data.Start)
+	data := s.data
+	s.mu.Unlock()
+
+	if s.tracer.exporter != nil && s.sc.Sampled {
+		s.tracer.exporter.ExportSpan(data)
+	}
+}
@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *recorder) ExportSpan(span SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *recorder) Shutdown(context.Context) error { return nil }
+
+func (r *recorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, len(r.spans))
+	for i, span := range r.spans {
+		names[i] = span.Name
+	}
+	return names
+}
+
+func useRecorder(t *testing.T) *recorder {
+	rec := &recorder{}
+	previous := Default()
+	SetDefault(NewTracer("test", rec))
+	t.Cleanup(func() { SetDefault(previous) })
+	return rec
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	for _, invalid := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, ok := ParseTraceparent(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestStart_ChildSpansContinueTheTrace(t *testing.T) {
+	rec := useRecorder(t)
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, server := Default().Start(ContextWithRemoteParent(context.Background(), parent), "GET /orders/:id", KindServer)
+	_, child := Start(ctx, "work")
+	child.End()
+	server.End()
+	server.SetAttribute("ignored", true)
+
+	assert.Len(t, rec.spans, 2)
+	assert.Equal(t, parent.TraceID.String(), rec.spans[1].TraceID)
+	assert.Equal(t, parent.SpanID.String(), rec.spans[1].ParentSpanID)
+	assert.Equal(t, parent.TraceID.String(), rec.spans[0].TraceID)
+	assert.Equal(t, rec.spans[1].SpanID, rec.spans[0].ParentSpanID)
+	assert.Nil(t, rec.spans[1].Attributes["ignored"])
+}
+
+func TestTransport_InjectsHeaders(t *testing.T) {
+	rec := useRecorder(t)
+	var got http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	ctx, span := Start(ContextWithRequestID(context.Background(), "req-1"), "handler")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	assert.Equal(t, "req-1", got.Get(RequestIDHeader))
+	sent, ok := ParseTraceparent(got.Get(TraceparentHeader))
+	assert.True(t, ok)
+	assert.Equal(t, span.SpanContext().TraceID, sent.TraceID)
+	assert.Equal(t, []string{"HTTP GET", "handler"}, rec.names())
+	assert.Equal(t, sent.SpanID.String(), rec.spans[0].SpanID)
+	assert.Empty(t, req.Header.Get(TraceparentHeader))
+}
+
+func TestGormPlugin_RecordsQuerySpans(t *testing.T) {
+	rec := useRecorder(t)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Use(GormPlugin{}))
+
+	type widget struct {
+		ID   uint
+		Name string
+	}
+	assert.NoError(t, db.AutoMigrate(&widget{}))
+
+	ctx, span := Start(context.Background(), "handler")
+	assert.NoError(t, db.WithContext(ctx).Create(&widget{Name: "a"}).Error)
+	var found widget
+	assert.NoError(t, db.WithContext(ctx).First(&found).Error)
+	span.End()
+
+	var spans []SpanData
+	for _, s := range rec.spans {
+		if s.ParentSpanID == span.SpanContext().SpanID.String() {
+			spans = append(spans, s)
+		}
+	}
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "gorm.create", spans[0].Name)
+	assert.Equal(t, "gorm.query", spans[1].Name)
+	assert.Equal(t, "widgets", spans[1].Attributes["db.table"])
+	assert.Contains(t, spans[1].Attributes["db.statement"], "SELECT")
+}
+
+func TestWriterExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer("api", NewWriterExporter(&buf))
+	_, span := tracer.Start(context.Background(), "job", KindInternal)
+	span.SetAttribute("items", 3)
+	span.End()
+
+	var exported SpanData
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Equal(t, "api", exported.Service)
+	assert.Equal(t, "job", exported.Name)
+	assert.Equal(t, float64(3), exported.Attributes["items"])
+}